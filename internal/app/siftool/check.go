@@ -0,0 +1,73 @@
+// Copyright (c) 2023, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package siftool
+
+import (
+	"errors"
+	"fmt"
+	"text/tabwriter"
+
+	"github.com/sylabs/sif/v2/pkg/sif"
+)
+
+// CheckOCIBlobs checks the digest of each OCI root index and blob object in the SIF file at path.
+// A summary of each object is written to the configured output. If the digest of any object does
+// not match, an error is returned.
+func (a *App) CheckOCIBlobs(path string) error {
+	return withFileImage(path, false, func(f *sif.FileImage) error {
+		checkErr := f.CheckOCIBlobs()
+
+		// Unwrap digest mismatches. Any other error is returned as-is.
+		mismatches := make(map[uint32]*sif.OCIBlobDigestError)
+
+		if u, ok := checkErr.(interface{ Unwrap() []error }); ok {
+			for _, err := range u.Unwrap() {
+				var de *sif.OCIBlobDigestError
+				if errors.As(err, &de) {
+					mismatches[de.ID] = de
+				}
+			}
+		} else if checkErr != nil {
+			return checkErr
+		}
+
+		tw := tabwriter.NewWriter(a.opts.out, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "ID\tTYPE\tDIGEST\tRESULT")
+
+		var err error
+
+		f.WithDescriptors(func(d sif.Descriptor) bool {
+			if t := d.DataType(); t != sif.DataOCIRootIndex && t != sif.DataOCIBlob {
+				return false
+			}
+
+			h, herr := d.OCIBlobDigest()
+			if herr != nil {
+				err = herr
+				return true
+			}
+
+			result := "OK"
+			if de, ok := mismatches[d.ID()]; ok {
+				result = fmt.Sprintf("digest mismatch (got %v)", de.Actual)
+			}
+
+			fmt.Fprintf(tw, "%v\t%v\t%v\t%v\n", d.ID(), d.DataType(), h, result)
+
+			return false
+		})
+
+		if ferr := tw.Flush(); err == nil {
+			err = ferr
+		}
+
+		if err == nil {
+			err = checkErr
+		}
+
+		return err
+	})
+}
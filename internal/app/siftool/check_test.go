@@ -0,0 +1,111 @@
+// Copyright (c) 2023, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package siftool
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sebdah/goldie/v2"
+	"github.com/sylabs/sif/v2/pkg/sif"
+)
+
+// corruptObject flips the first byte of the data object with the specified ID in the SIF at path.
+func corruptObject(t *testing.T, path string, id uint32) {
+	t.Helper()
+
+	f, err := sif.LoadContainerFromPath(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	d, err := f.GetDescriptor(sif.WithID(id))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := f.UnloadContainer(); err != nil {
+		t.Fatal(err)
+	}
+
+	fp, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fp.Close()
+
+	b := make([]byte, 1)
+	if _, err := fp.ReadAt(b, d.Offset()); err != nil {
+		t.Fatal(err)
+	}
+
+	b[0] ^= 0xff
+
+	if _, err := fp.WriteAt(b, d.Offset()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestApp_CheckOCIBlobs(t *testing.T) {
+	corrupt := makeOCISIF(t)
+	corruptObject(t, corrupt, 2)
+
+	tests := []struct {
+		name    string
+		path    string
+		wantErr error
+	}{
+		{
+			name:    "NotExist",
+			path:    "not-exist.sif",
+			wantErr: os.ErrNotExist,
+		},
+		{
+			name: "OneObjectOCIRootIndex",
+			path: filepath.Join(corpus, "one-object-oci-root-index.sif"),
+		},
+		{
+			name: "OneObjectOCIBlob",
+			path: filepath.Join(corpus, "one-object-oci-blob.sif"),
+		},
+		{
+			name: "TwoGroups",
+			path: filepath.Join(corpus, "two-groups.sif"),
+		},
+		{
+			name: "OK",
+			path: makeOCISIF(t),
+		},
+		{
+			name:    "Corrupt",
+			path:    corrupt,
+			wantErr: &sif.OCIBlobDigestError{ID: 2},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var b bytes.Buffer
+
+			a, err := New(OptAppOutput(&b))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got, want := a.CheckOCIBlobs(tt.path), tt.wantErr; !errors.Is(got, want) {
+				t.Fatalf("got error %v, want %v", got, want)
+			}
+
+			if !errors.Is(tt.wantErr, os.ErrNotExist) {
+				g := goldie.New(t, goldie.WithTestNameForDir(true))
+				g.Assert(t, tt.name, b.Bytes())
+			}
+		})
+	}
+}
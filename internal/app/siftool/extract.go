@@ -0,0 +1,109 @@
+// Copyright (c) 2023, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package siftool
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/sylabs/sif/v2/pkg/sif"
+	"github.com/sylabs/sif/v2/pkg/squashfs"
+)
+
+// extractFile writes the regular file at name in fsys to dst, which must not already exist.
+func extractFile(fsys fs.FS, name, dst string, mode fs.FileMode) error {
+	r, err := fsys.Open(name)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	w, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, mode.Perm())
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(w, r); err != nil {
+		w.Close()
+		return err
+	}
+
+	return w.Close()
+}
+
+// extract writes the file or directory at src in fsys to dst. Directories are extracted
+// recursively. Files other than regular files, directories and symbolic links are skipped.
+func (a *App) extract(fsys *squashfs.FS, src, dst string) error {
+	return fs.WalkDir(fsys, src, func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		target := dst
+		if name != src {
+			rel := name
+			if src != "." {
+				rel = strings.TrimPrefix(name, src+"/")
+			}
+			target = filepath.Join(dst, filepath.FromSlash(rel))
+		}
+
+		switch t := d.Type(); {
+		case t.IsDir():
+			return os.MkdirAll(target, 0o755)
+
+		case t&fs.ModeSymlink != 0:
+			link, err := fsys.ReadLink(name)
+			if err != nil {
+				return err
+			}
+			return os.Symlink(link, target)
+
+		case t.IsRegular():
+			fi, err := d.Info()
+			if err != nil {
+				return err
+			}
+			return extractFile(fsys, name, target, fi.Mode())
+
+		default:
+			fmt.Fprintf(a.opts.err, "Skipping %v: unsupported file type %v\n", name, t)
+			return nil
+		}
+	})
+}
+
+// Extract extracts the file or directory at src within a SquashFS partition of the SIF at path
+// to dst. If id is zero, the primary system partition is used. Otherwise, the partition with the
+// specified ID is used.
+func (a *App) Extract(path string, id uint32, src, dst string) error {
+	return withFileImage(path, false, func(f *sif.FileImage) error {
+		fn := sif.WithPartitionType(sif.PartPrimSys)
+		if id != 0 {
+			fn = sif.WithID(id)
+		}
+
+		d, err := f.GetDescriptor(fn)
+		if err != nil {
+			return err
+		}
+
+		fsys, err := squashfs.FromDescriptor(d)
+		if err != nil {
+			return err
+		}
+
+		if src = strings.Trim(src, "/"); src == "" {
+			src = "."
+		}
+
+		return a.extract(fsys, src, dst)
+	})
+}
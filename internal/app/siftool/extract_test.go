@@ -0,0 +1,118 @@
+// Copyright (c) 2023, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package siftool
+
+import (
+	"bytes"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sylabs/sif/v2/pkg/sif"
+	"github.com/sylabs/sif/v2/pkg/squashfs"
+)
+
+func TestApp_Extract(t *testing.T) {
+	tests := []struct {
+		name     string
+		path     string
+		id       uint32
+		src      string
+		existing bool
+		wantFile string
+		wantErr  error
+	}{
+		{
+			name:    "NotExist",
+			path:    "not-exist.sif",
+			src:     "hello.txt",
+			wantErr: os.ErrNotExist,
+		},
+		{
+			name:    "NoPrimaryPartition",
+			path:    filepath.Join(corpus, "one-object-sbom.sif"),
+			src:     "hello.txt",
+			wantErr: sif.ErrObjectNotFound,
+		},
+		{
+			name:    "NotSquashFS",
+			path:    filepath.Join(corpus, "two-groups.sif"),
+			id:      3,
+			src:     "hello.txt",
+			wantErr: squashfs.ErrNotSquashFS,
+		},
+		{
+			name:    "FileNotExist",
+			path:    filepath.Join(corpus, "two-groups.sif"),
+			src:     "not-exist.txt",
+			wantErr: fs.ErrNotExist,
+		},
+		{
+			name:     "DestinationExists",
+			path:     filepath.Join(corpus, "two-groups.sif"),
+			src:      "hello.txt",
+			existing: true,
+			wantErr:  fs.ErrExist,
+		},
+		{
+			name: "File",
+			path: filepath.Join(corpus, "two-groups.sif"),
+			src:  "/hello.txt",
+		},
+		{
+			name: "FileWithID",
+			path: filepath.Join(corpus, "two-groups.sif"),
+			id:   2,
+			src:  "hello.txt",
+		},
+		{
+			name:     "Root",
+			path:     filepath.Join(corpus, "two-groups.sif"),
+			src:      "/",
+			wantFile: "hello.txt",
+		},
+		{
+			name: "Remote",
+			path: serveTestSIF(t, "two-groups.sif"),
+			src:  "hello.txt",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out, errOut bytes.Buffer
+
+			a, err := New(OptAppOutput(&out), OptAppError(&errOut))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			dst := filepath.Join(t.TempDir(), "dst")
+			if tt.existing {
+				if err := os.WriteFile(dst, nil, 0o600); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if got, want := a.Extract(tt.path, tt.id, tt.src, dst), tt.wantErr; !errors.Is(got, want) {
+				t.Fatalf("got error %v, want %v", got, want)
+			}
+
+			if tt.wantErr == nil {
+				b, err := os.ReadFile(filepath.Join(dst, tt.wantFile))
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				if got, want := string(b), "Hello from Sylabs!\n"; got != want {
+					t.Errorf("got contents %q, want %q", got, want)
+				}
+			}
+		})
+	}
+}
@@ -1,4 +1,4 @@
-// Copyright (c) 2021-2022, Sylabs Inc. All rights reserved.
+// Copyright (c) 2021-2023, Sylabs Inc. All rights reserved.
 // This software is licensed under a 3-clause BSD license. Please consult the
 // LICENSE file distributed with the sources of this project regarding your
 // rights to use or distribute this software.
@@ -6,19 +6,48 @@
 package siftool
 
 import (
+	"errors"
 	"os"
+	"strings"
 
 	"github.com/sylabs/sif/v2/pkg/sif"
+	"github.com/sylabs/sif/v2/pkg/sif/remote"
 )
 
-// withFileImage calls fn with a FileImage loaded from path.
-func withFileImage(path string, writable bool, fn func(*sif.FileImage) error) error {
+var errRemoteWritable = errors.New("remote image cannot be modified")
+
+// isRemote returns true if path is an HTTP(S) URL.
+func isRemote(path string) bool {
+	return strings.HasPrefix(path, "http://") || strings.HasPrefix(path, "https://")
+}
+
+// loadFileImage loads a FileImage from path. If path is an HTTP(S) URL, the image is loaded
+// read-only using HTTP range requests.
+func loadFileImage(path string, writable bool) (*sif.FileImage, error) {
+	if isRemote(path) {
+		if writable {
+			return nil, errRemoteWritable
+		}
+
+		r, err := remote.NewReaderAt(path)
+		if err != nil {
+			return nil, err
+		}
+
+		return sif.LoadContainerReader(r, r.Size())
+	}
+
 	flag := os.O_RDONLY
 	if writable {
 		flag = os.O_RDWR
 	}
 
-	f, err := sif.LoadContainerFromPath(path, sif.OptLoadWithFlag(flag))
+	return sif.LoadContainerFromPath(path, sif.OptLoadWithFlag(flag))
+}
+
+// withFileImage calls fn with a FileImage loaded from path.
+func withFileImage(path string, writable bool, fn func(*sif.FileImage) error) error {
+	f, err := loadFileImage(path, writable)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,73 @@
+// Copyright (c) 2023, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package siftool
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// serveTestSIF starts a server that serves the test image with the specified name, and returns
+// its URL.
+func serveTestSIF(t *testing.T, name string) string {
+	t.Helper()
+
+	s := httptest.NewServer(http.FileServer(http.Dir(corpus)))
+	t.Cleanup(s.Close)
+
+	return s.URL + "/" + name
+}
+
+func Test_loadFileImage(t *testing.T) {
+	tests := []struct {
+		name     string
+		path     string
+		writable bool
+		wantErr  error
+	}{
+		{
+			name:    "NotExist",
+			path:    "not-exist.sif",
+			wantErr: os.ErrNotExist,
+		},
+		{
+			name: "Local",
+			path: filepath.Join(corpus, "one-group.sif"),
+		},
+		{
+			name: "Remote",
+			path: serveTestSIF(t, "one-group.sif"),
+		},
+		{
+			name:     "RemoteWritable",
+			path:     serveTestSIF(t, "one-group.sif"),
+			writable: true,
+			wantErr:  errRemoteWritable,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := loadFileImage(tt.path, tt.writable)
+			if got, want := err, tt.wantErr; !errors.Is(got, want) {
+				t.Fatalf("got error %v, want %v", got, want)
+			}
+
+			if err == nil {
+				if got, want := f.DescriptorsTotal()-f.DescriptorsFree(), int64(2); got != want {
+					t.Errorf("got %v descriptors, want %v", got, want)
+				}
+
+				if err := f.UnloadContainer(); err != nil {
+					t.Error(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,62 @@
+// Copyright (c) 2023, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package siftool
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/sylabs/sif/v2/pkg/sif"
+)
+
+var errProblemsFound = errors.New("image contains structural problems")
+
+// writeProblems writes a table describing problems to the configured output.
+func (a *App) writeProblems(problems []sif.Problem) error {
+	tw := tabwriter.NewWriter(a.opts.out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "PROBLEM\tOBJECTS\tDESCRIPTION")
+
+	for _, p := range problems {
+		ids := make([]string, 0, len(p.IDs))
+		for _, id := range p.IDs {
+			ids = append(ids, strconv.FormatUint(uint64(id), 10))
+		}
+
+		objects := strings.Join(ids, ",")
+		if objects == "" {
+			objects = "-"
+		}
+
+		fmt.Fprintf(tw, "%v\t%v\t%v\n", p.Type, objects, p.Message)
+	}
+
+	return tw.Flush()
+}
+
+// Fsck checks the structure of the SIF file at path. Any problems found are written to the
+// configured output, and an error is returned.
+func (a *App) Fsck(path string) error {
+	return withFileImage(path, false, func(f *sif.FileImage) error {
+		problems, err := sif.Check(f)
+		if err != nil {
+			return err
+		}
+
+		if len(problems) == 0 {
+			fmt.Fprintln(a.opts.out, "No problems found.")
+			return nil
+		}
+
+		if err := a.writeProblems(problems); err != nil {
+			return err
+		}
+
+		return fmt.Errorf("%w: %v problem(s) found", errProblemsFound, len(problems))
+	})
+}
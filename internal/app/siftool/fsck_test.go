@@ -0,0 +1,86 @@
+// Copyright (c) 2023, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package siftool
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sebdah/goldie/v2"
+)
+
+// truncatedTestSIF returns the path to a copy of the test image with the specified name, with the
+// last n bytes removed.
+func truncatedTestSIF(t *testing.T, name string, n int) string {
+	t.Helper()
+
+	b, err := os.ReadFile(filepath.Join(corpus, name))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), name)
+
+	if err := os.WriteFile(path, b[:len(b)-n], 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+func TestApp_Fsck(t *testing.T) {
+	tests := []struct {
+		name    string
+		path    string
+		wantErr error
+	}{
+		{
+			name:    "NotExist",
+			path:    "not-exist.sif",
+			wantErr: os.ErrNotExist,
+		},
+		{
+			name: "Remote",
+			path: serveTestSIF(t, "two-groups.sif"),
+		},
+		{
+			name: "Empty",
+			path: filepath.Join(corpus, "empty.sif"),
+		},
+		{
+			name: "TwoGroups",
+			path: filepath.Join(corpus, "two-groups.sif"),
+		},
+		{
+			name:    "Truncated",
+			path:    truncatedTestSIF(t, "one-group.sif", 1),
+			wantErr: errProblemsFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var b bytes.Buffer
+
+			a, err := New(OptAppOutput(&b))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got, want := a.Fsck(tt.path), tt.wantErr; !errors.Is(got, want) {
+				t.Fatalf("got error %v, want %v", got, want)
+			}
+
+			if !errors.Is(tt.wantErr, os.ErrNotExist) {
+				g := goldie.New(t, goldie.WithTestNameForDir(true))
+				g.Assert(t, tt.name, b.Bytes())
+			}
+		})
+	}
+}
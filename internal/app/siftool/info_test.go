@@ -88,6 +88,10 @@ func TestApp_Header(t *testing.T) {
 			path:    "not-exist.sif",
 			wantErr: os.ErrNotExist,
 		},
+		{
+			name: "Remote",
+			path: serveTestSIF(t, "two-groups-signed-pgp.sif"),
+		},
 		{
 			name: "Empty",
 			path: filepath.Join(corpus, "empty.sif"),
@@ -198,6 +202,10 @@ func TestApp_List(t *testing.T) {
 			path:    "not-exist.sif",
 			wantErr: os.ErrNotExist,
 		},
+		{
+			name: "Remote",
+			path: serveTestSIF(t, "two-groups-signed-pgp.sif"),
+		},
 		{
 			name: "Empty",
 			path: filepath.Join(corpus, "empty.sif"),
@@ -308,6 +316,11 @@ func TestApp_Info(t *testing.T) {
 			path:    "not-exist.sif",
 			wantErr: os.ErrNotExist,
 		},
+		{
+			name: "Remote",
+			path: serveTestSIF(t, "two-groups-signed-pgp.sif"),
+			id:   2,
+		},
 		{
 			name: "Time",
 			path: filepath.Join(corpus, "one-object-time.sif"),
@@ -49,3 +49,17 @@ func (*App) Setprim(path string, id uint32) error {
 		return f.SetPrimPart(id)
 	})
 }
+
+// Compact reclaims unused space in the data section of the SIF file.
+func (*App) Compact(path string) error {
+	return withFileImage(path, true, func(f *sif.FileImage) error {
+		return f.Compact()
+	})
+}
+
+// ResizeDescriptors sets the descriptor capacity of the SIF file to n.
+func (*App) ResizeDescriptors(path string, n int64) error {
+	return withFileImage(path, true, func(f *sif.FileImage) error {
+		return f.SetDescriptorCapacity(n)
+	})
+}
@@ -168,3 +168,76 @@ func TestApp_Setprim(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+func TestApp_Compact(t *testing.T) {
+	a, err := New()
+	if err != nil {
+		t.Fatalf("failed to create app: %v", err)
+	}
+
+	tf, err := os.CreateTemp("", "sif-test-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(tf.Name())
+	tf.Close()
+
+	if err := a.New(tf.Name()); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, b := range [][]byte{{0xfa, 0xce}, {0xfe, 0xed}} {
+		if err := a.Add(tf.Name(), sif.DataGeneric, bytes.NewReader(b)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := a.Del(tf.Name(), 1); err != nil {
+		t.Fatal(err)
+	}
+
+	fi, err := os.Stat(tf.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	before := fi.Size()
+
+	if err := a.Compact(tf.Name()); err != nil {
+		t.Fatal(err)
+	}
+
+	if fi, err = os.Stat(tf.Name()); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := fi.Size(), before-2; got != want {
+		t.Errorf("got size %v, want %v", got, want)
+	}
+}
+
+func TestApp_ResizeDescriptors(t *testing.T) {
+	a, err := New()
+	if err != nil {
+		t.Fatalf("failed to create app: %v", err)
+	}
+
+	tf, err := os.CreateTemp("", "sif-test-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(tf.Name())
+	tf.Close()
+
+	if err := a.New(tf.Name()); err != nil {
+		t.Fatal(err)
+	}
+
+	err = a.Add(tf.Name(), sif.DataGeneric, bytes.NewReader([]byte{0xde, 0xad, 0xbe, 0xef}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := a.ResizeDescriptors(tf.Name(), 96); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,32 @@
+// Copyright (c) 2023, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package siftool
+
+import (
+	"context"
+
+	"github.com/sylabs/sif/v2/pkg/user"
+)
+
+// Mount mounts a partition of the SIF file at path into mountPath, according to opts.
+func (a *App) Mount(ctx context.Context, path, mountPath string, opts ...user.MountOpt) error {
+	opts = append([]user.MountOpt{
+		user.OptMountStdout(a.opts.out),
+		user.OptMountStderr(a.opts.err),
+	}, opts...)
+
+	return user.Mount(ctx, path, mountPath, opts...)
+}
+
+// Unmount unmounts the filesystem at mountPath, according to opts.
+func (a *App) Unmount(ctx context.Context, mountPath string, opts ...user.UnmountOpt) error {
+	opts = append([]user.UnmountOpt{
+		user.OptUnmountStdout(a.opts.out),
+		user.OptUnmountStderr(a.opts.err),
+	}, opts...)
+
+	return user.Unmount(ctx, mountPath, opts...)
+}
@@ -0,0 +1,18 @@
+// Copyright (c) 2023, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package siftool
+
+import (
+	"github.com/sylabs/sif/v2/pkg/sif"
+	"github.com/sylabs/sif/v2/pkg/sif/oci"
+)
+
+// OCIExport writes the OCI root index and blobs in the SIF at path to an OCI image layout at dst.
+func (a *App) OCIExport(path, dst string) error {
+	return withFileImage(path, false, func(f *sif.FileImage) error {
+		return oci.ExportLayout(f, dst)
+	})
+}
@@ -0,0 +1,104 @@
+// Copyright (c) 2023, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package siftool
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sylabs/sif/v2/pkg/sif"
+)
+
+// makeOCISIF returns the path to a new SIF containing a root index and a blob.
+func makeOCISIF(t *testing.T) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "oci.sif")
+
+	var dis []sif.DescriptorInput
+
+	for _, in := range []struct {
+		t    sif.DataType
+		name string
+	}{
+		{sif.DataOCIRootIndex, "index.json"},
+		{sif.DataOCIBlob, "oci-config.json"},
+	} {
+		b, err := os.ReadFile(filepath.Join("..", "..", "..", "test", "input", in.name))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		di, err := sif.NewDescriptorInput(in.t, bytes.NewReader(b))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		dis = append(dis, di)
+	}
+
+	f, err := sif.CreateContainerAtPath(path, sif.OptCreateWithDescriptors(dis...))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := f.UnloadContainer(); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+func TestApp_OCIExport(t *testing.T) {
+	tests := []struct {
+		name    string
+		path    string
+		wantErr error
+	}{
+		{
+			name:    "NotExist",
+			path:    "not-exist.sif",
+			wantErr: os.ErrNotExist,
+		},
+		{
+			name:    "NoRootIndex",
+			path:    filepath.Join(corpus, "one-object-oci-blob.sif"),
+			wantErr: sif.ErrObjectNotFound,
+		},
+		{
+			name: "OK",
+			path: makeOCISIF(t),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out, errOut bytes.Buffer
+
+			a, err := New(OptAppOutput(&out), OptAppError(&errOut))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			dst := filepath.Join(t.TempDir(), "layout")
+
+			if got, want := a.OCIExport(tt.path, dst), tt.wantErr; !errors.Is(got, want) {
+				t.Fatalf("got error %v, want %v", got, want)
+			}
+
+			if tt.wantErr == nil {
+				for _, name := range []string{"oci-layout", "index.json"} {
+					if _, err := os.Stat(filepath.Join(dst, name)); err != nil {
+						t.Error(err)
+					}
+				}
+			}
+		})
+	}
+}
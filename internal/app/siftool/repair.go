@@ -0,0 +1,47 @@
+// Copyright (c) 2023, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package siftool
+
+import (
+	"fmt"
+	"text/tabwriter"
+
+	"github.com/sylabs/sif/v2/pkg/sif"
+)
+
+// Repair repairs the global header and descriptors of the SIF file at path. If dryRun is true,
+// the fixes required are determined without modifying the image. A summary of each fix is written
+// to the configured output.
+func (a *App) Repair(path string, dryRun bool) error {
+	return withFileImage(path, !dryRun, func(f *sif.FileImage) error {
+		fixes, err := f.Repair(sif.OptRepairDryRun(dryRun))
+		if err != nil {
+			return err
+		}
+
+		if len(fixes) == 0 {
+			fmt.Fprintln(a.opts.out, "No repairs required.")
+			return nil
+		}
+
+		tw := tabwriter.NewWriter(a.opts.out, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "FIX\tDESCRIPTION")
+
+		for _, fix := range fixes {
+			fmt.Fprintf(tw, "%v\t%v\n", fix.Type, fix.Message)
+		}
+
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+
+		if dryRun {
+			fmt.Fprintln(a.opts.out, "Dry run: no changes made.")
+		}
+
+		return nil
+	})
+}
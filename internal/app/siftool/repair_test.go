@@ -0,0 +1,105 @@
+// Copyright (c) 2023, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package siftool
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sebdah/goldie/v2"
+)
+
+// paddedTestSIF returns the path to a copy of the test image with the specified name, with n zero
+// bytes appended.
+func paddedTestSIF(t *testing.T, name string, n int) string {
+	t.Helper()
+
+	b, err := os.ReadFile(filepath.Join(corpus, name))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), name)
+
+	if err := os.WriteFile(path, append(b, make([]byte, n)...), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+func TestApp_Repair(t *testing.T) {
+	tests := []struct {
+		name     string
+		path     string
+		dryRun   bool
+		wantSize int64
+		wantErr  error
+	}{
+		{
+			name:    "NotExist",
+			path:    "not-exist.sif",
+			wantErr: os.ErrNotExist,
+		},
+		{
+			name:   "RemoteDryRun",
+			path:   serveTestSIF(t, "two-groups.sif"),
+			dryRun: true,
+		},
+		{
+			name:     "OK",
+			path:     paddedTestSIF(t, "one-group.sif", 0),
+			wantSize: 40960,
+		},
+		{
+			name:     "TrailingDataDryRun",
+			path:     paddedTestSIF(t, "one-group.sif", 512),
+			dryRun:   true,
+			wantSize: 40960 + 512,
+		},
+		{
+			name:     "TrailingData",
+			path:     paddedTestSIF(t, "one-group.sif", 512),
+			wantSize: 40960,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var b bytes.Buffer
+
+			a, err := New(OptAppOutput(&b))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got, want := a.Repair(tt.path, tt.dryRun), tt.wantErr; !errors.Is(got, want) {
+				t.Fatalf("got error %v, want %v", got, want)
+			}
+
+			if tt.wantErr != nil {
+				return
+			}
+
+			if tt.wantSize != 0 {
+				fi, err := os.Stat(tt.path)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				if got, want := fi.Size(), tt.wantSize; got != want {
+					t.Errorf("got size %v, want %v", got, want)
+				}
+			}
+
+			g := goldie.New(t, goldie.WithTestNameForDir(true))
+			g.Assert(t, tt.name, b.Bytes())
+		})
+	}
+}
@@ -0,0 +1,23 @@
+// Copyright (c) 2023, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package siftool
+
+import (
+	"github.com/sylabs/sif/v2/pkg/integrity"
+	"github.com/sylabs/sif/v2/pkg/sif"
+)
+
+// Sign adds digital signature(s) to the SIF file at path, according to opts.
+func (*App) Sign(path string, opts ...integrity.SignerOpt) error {
+	return withFileImage(path, true, func(f *sif.FileImage) error {
+		s, err := integrity.NewSigner(f, opts...)
+		if err != nil {
+			return err
+		}
+
+		return s.Sign()
+	})
+}
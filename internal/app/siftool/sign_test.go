@@ -0,0 +1,143 @@
+// Copyright (c) 2023, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package siftool
+
+import (
+	"crypto"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sigstore/sigstore/pkg/cryptoutils"
+	"github.com/sigstore/sigstore/pkg/signature"
+	"github.com/sylabs/sif/v2/pkg/integrity"
+	"github.com/sylabs/sif/v2/pkg/sif"
+)
+
+var keys = filepath.Join("..", "..", "..", "test", "keys")
+
+// copyTestSIF copies the SIF at path to a temporary file, and returns the path of the copy.
+func copyTestSIF(t *testing.T, path string) string {
+	t.Helper()
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tf, err := os.CreateTemp("", "sif-test-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Remove(tf.Name()) })
+	defer tf.Close()
+
+	if _, err := tf.Write(b); err != nil {
+		t.Fatal(err)
+	}
+
+	return tf.Name()
+}
+
+// getTestSigner returns a Signer read from the PEM file with the specified name.
+func getTestSigner(t *testing.T, name string) signature.Signer { //nolint:ireturn
+	t.Helper()
+
+	s, err := signature.LoadSignerFromPEMFile(filepath.Join(keys, name), crypto.SHA256, cryptoutils.SkipPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return s
+}
+
+func TestApp_Sign(t *testing.T) {
+	a, err := New()
+	if err != nil {
+		t.Fatalf("failed to create app: %v", err)
+	}
+
+	s := getTestSigner(t, "ed25519-private.pem")
+
+	tests := []struct {
+		name       string
+		path       string
+		opts       []integrity.SignerOpt
+		verifyOpts []integrity.VerifierOpt
+	}{
+		{
+			name: "OneGroup",
+			path: filepath.Join(corpus, "one-group.sif"),
+			opts: []integrity.SignerOpt{
+				integrity.OptSignWithSigner(s),
+			},
+		},
+		{
+			name: "TwoGroups",
+			path: filepath.Join(corpus, "two-groups.sif"),
+			opts: []integrity.SignerOpt{
+				integrity.OptSignWithSigner(s),
+			},
+		},
+		{
+			name: "Group",
+			path: filepath.Join(corpus, "two-groups.sif"),
+			opts: []integrity.SignerOpt{
+				integrity.OptSignWithSigner(s),
+				integrity.OptSignGroup(2),
+			},
+			verifyOpts: []integrity.VerifierOpt{
+				integrity.OptVerifyGroup(2),
+			},
+		},
+		{
+			name: "Objects",
+			path: filepath.Join(corpus, "two-groups.sif"),
+			opts: []integrity.SignerOpt{
+				integrity.OptSignWithSigner(s),
+				integrity.OptSignObjects(1, 3),
+			},
+			verifyOpts: []integrity.VerifierOpt{
+				integrity.OptVerifyObject(1),
+				integrity.OptVerifyObject(3),
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := copyTestSIF(t, tt.path)
+
+			if err := a.Sign(path, tt.opts...); err != nil {
+				t.Fatal(err)
+			}
+
+			sv, err := s.PublicKey()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			v, err := signature.LoadVerifier(sv, crypto.SHA256)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = withFileImage(path, false, func(f *sif.FileImage) error {
+				opts := []integrity.VerifierOpt{integrity.OptVerifyWithVerifier(v)}
+				opts = append(opts, tt.verifyOpts...)
+
+				v, err := integrity.NewVerifier(f, opts...)
+				if err != nil {
+					return err
+				}
+
+				return v.Verify()
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
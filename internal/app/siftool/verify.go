@@ -0,0 +1,109 @@
+// Copyright (c) 2023, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package siftool
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/sigstore/sigstore/pkg/cryptoutils"
+	"github.com/sylabs/sif/v2/pkg/integrity"
+	"github.com/sylabs/sif/v2/pkg/sif"
+)
+
+// signerOf returns a human-readable representation of the entity or key(s) that produced the
+// signature described by r.
+func signerOf(r integrity.VerifyResult) string {
+	if e := r.Entity(); e != nil {
+		return fmt.Sprintf("%X", e.PrimaryKey.Fingerprint)
+	}
+
+	if keys := r.Keys(); len(keys) > 0 {
+		s := make([]string, 0, len(keys))
+		for _, k := range keys {
+			b, err := cryptoutils.MarshalPublicKeyToDER(k)
+			if err != nil {
+				s = append(s, "unknown")
+				continue
+			}
+			s = append(s, fmt.Sprintf("sha256:%x", sha256.Sum256(b)))
+		}
+		return strings.Join(s, ",")
+	}
+
+	// Fall back to the fingerprint recorded in the signature descriptor, if present.
+	if _, fp, err := r.Signature().SignatureMetadata(); err == nil && len(fp) > 0 {
+		return fmt.Sprintf("%X", fp)
+	}
+
+	return "-"
+}
+
+// writeVerifyResult writes a one line summary of r to w.
+func writeVerifyResult(w io.Writer, r integrity.VerifyResult) {
+	ids := make([]string, 0, len(r.Verified()))
+	for _, d := range r.Verified() {
+		ids = append(ids, strconv.FormatUint(uint64(d.ID()), 10))
+	}
+
+	objects := strings.Join(ids, ",")
+	if objects == "" {
+		objects = "-"
+	}
+
+	result := "OK"
+	if err := r.Error(); err != nil {
+		result = err.Error()
+	}
+
+	fmt.Fprintf(w, "%v\t%v\t%v\t%v\n", r.Signature().ID(), signerOf(r), objects, result)
+}
+
+// Verify verifies digital signature(s) in the SIF file at path, according to opts. A summary of
+// each signature is written to the configured output. If verification of any signature fails,
+// the first error encountered is returned.
+func (a *App) Verify(path string, opts ...integrity.VerifierOpt) error {
+	return withFileImage(path, false, func(f *sif.FileImage) error {
+		tw := tabwriter.NewWriter(a.opts.out, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "SIGNATURE\tSIGNER\tOBJECTS\tRESULT")
+
+		var verifyErr error
+
+		cb := func(r integrity.VerifyResult) bool {
+			writeVerifyResult(tw, r)
+
+			if err := r.Error(); err != nil && verifyErr == nil {
+				verifyErr = err
+			}
+
+			// Continue, so that all signatures are reported.
+			return true
+		}
+
+		opts = append(opts[:len(opts):len(opts)], integrity.OptVerifyCallback(cb))
+
+		v, err := integrity.NewVerifier(f, opts...)
+		if err != nil {
+			return err
+		}
+
+		err = v.Verify()
+
+		if ferr := tw.Flush(); err == nil {
+			err = ferr
+		}
+
+		if err == nil {
+			err = verifyErr
+		}
+
+		return err
+	})
+}
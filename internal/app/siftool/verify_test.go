@@ -0,0 +1,152 @@
+// Copyright (c) 2023, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package siftool
+
+import (
+	"bytes"
+	"crypto"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/sebdah/goldie/v2"
+	"github.com/sigstore/sigstore/pkg/signature"
+	"github.com/sylabs/sif/v2/pkg/integrity"
+)
+
+// getTestVerifier returns a Verifier read from the PEM file with the specified name.
+func getTestVerifier(t *testing.T, name string) signature.Verifier { //nolint:ireturn
+	t.Helper()
+
+	v, err := signature.LoadVerifierFromPEMFile(filepath.Join(keys, name), crypto.SHA256)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return v
+}
+
+// getTestKeyRing returns the fixed test PGP key ring.
+func getTestKeyRing(t *testing.T) openpgp.KeyRing { //nolint:ireturn
+	t.Helper()
+
+	f, err := os.Open(filepath.Join(keys, "private.asc"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	el, err := openpgp.ReadArmoredKeyRing(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return el
+}
+
+func TestApp_Verify(t *testing.T) {
+	oneGroupCorrupt := copyTestSIF(t, filepath.Join(corpus, "one-group-signed-dsse.sif"))
+	corruptObject(t, oneGroupCorrupt, 1)
+
+	twoGroupsCorrupt := copyTestSIF(t, filepath.Join(corpus, "two-groups-signed-dsse.sif"))
+	corruptObject(t, twoGroupsCorrupt, 1)
+
+	ed25519 := getTestVerifier(t, "ed25519-public.pem")
+	ecdsa := getTestVerifier(t, "ecdsa-public.pem")
+
+	tests := []struct {
+		name    string
+		path    string
+		opts    []integrity.VerifierOpt
+		wantErr error
+	}{
+		{
+			name:    "NotExist",
+			path:    "not-exist.sif",
+			wantErr: os.ErrNotExist,
+		},
+		{
+			name: "OneGroupDSSE",
+			path: filepath.Join(corpus, "one-group-signed-dsse.sif"),
+			opts: []integrity.VerifierOpt{
+				integrity.OptVerifyWithVerifier(ed25519),
+			},
+		},
+		{
+			name: "TwoGroupsDSSE",
+			path: filepath.Join(corpus, "two-groups-signed-dsse.sif"),
+			opts: []integrity.VerifierOpt{
+				integrity.OptVerifyWithVerifier(ed25519),
+			},
+		},
+		{
+			name: "TwoGroupsPGP",
+			path: filepath.Join(corpus, "two-groups-signed-pgp.sif"),
+			opts: []integrity.VerifierOpt{
+				integrity.OptVerifyWithKeyRing(getTestKeyRing(t)),
+			},
+		},
+		{
+			name: "Group",
+			path: filepath.Join(corpus, "two-groups-signed-dsse.sif"),
+			opts: []integrity.VerifierOpt{
+				integrity.OptVerifyWithVerifier(ed25519),
+				integrity.OptVerifyGroup(2),
+			},
+		},
+		{
+			name: "WrongKey",
+			path: filepath.Join(corpus, "one-group-signed-dsse.sif"),
+			opts: []integrity.VerifierOpt{
+				integrity.OptVerifyWithVerifier(ecdsa),
+			},
+			wantErr: &integrity.SignatureNotValidError{},
+		},
+		{
+			name: "OneGroupCorrupt",
+			path: oneGroupCorrupt,
+			opts: []integrity.VerifierOpt{
+				integrity.OptVerifyWithVerifier(ed25519),
+			},
+			wantErr: &integrity.ObjectIntegrityError{ID: 1},
+		},
+		{
+			name: "TwoGroupsCorrupt",
+			path: twoGroupsCorrupt,
+			opts: []integrity.VerifierOpt{
+				integrity.OptVerifyWithVerifier(ed25519),
+			},
+			wantErr: &integrity.ObjectIntegrityError{ID: 1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var b bytes.Buffer
+
+			a, err := New(OptAppOutput(&b))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = a.Verify(tt.path, tt.opts...)
+			if got, want := err, tt.wantErr; !errors.Is(got, want) {
+				t.Fatalf("got error %v, want %v", got, want)
+			}
+
+			if !errors.Is(tt.wantErr, os.ErrNotExist) {
+				g := goldie.New(t, goldie.WithTestNameForDir(true))
+				g.Assert(t, tt.name, b.Bytes())
+
+				if err != nil {
+					g.Assert(t, tt.name+"Error", []byte(err.Error()))
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,309 @@
+// Copyright (c) 2023, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+// Package encryption implements support for encrypted SquashFS partitions in SIF images.
+//
+// The contents of an encrypted partition are encrypted with a randomly generated partition key,
+// using AES-256 in XTS mode with 512 byte sectors and a plain 64-bit sector number as the tweak
+// ("aes-xts-plain64"), as used by dm-crypt in plain mode. The partition key is encrypted with an
+// RSA public key using RSA-OAEP, and stored in a PEM-encoded cryptographic message object that is
+// linked to the partition.
+package encryption
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"runtime"
+
+	"github.com/sylabs/sif/v2/pkg/sif"
+)
+
+const (
+	// keySize is the size of a partition key, in bytes. The key is split in two, with each half
+	// being used as an AES-256 key.
+	keySize = 64
+
+	// pemType is the type of the PEM block containing an encrypted partition key.
+	pemType = "ENCRYPTED PARTITION KEY"
+
+	// cipherName is the name of the cipher used to encrypt partitions, as understood by dm-crypt.
+	cipherName = "aes-xts-plain64"
+)
+
+var (
+	errNilFileImage          = errors.New("nil file image")
+	errNilKey                = errors.New("nil key")
+	errInvalidKeySize        = errors.New("invalid partition key size")
+	errNotEncryptedPartition = errors.New("data object is not an encrypted SquashFS partition")
+	errUnsupportedMessage    = errors.New("unsupported cryptographic message")
+	errNotReaderAt           = errors.New("data object reader does not implement io.ReaderAt")
+)
+
+// partitionOpts accumulates partition options.
+type partitionOpts struct {
+	pt      sif.PartType
+	arch    string
+	groupID uint32
+}
+
+// PartitionOpt are used to specify partition options.
+type PartitionOpt func(*partitionOpts) error
+
+// OptPartitionType specifies pt as the partition type.
+func OptPartitionType(pt sif.PartType) PartitionOpt {
+	return func(po *partitionOpts) error {
+		po.pt = pt
+		return nil
+	}
+}
+
+// OptPartitionArch specifies arch as the CPU architecture of the partition. The value of arch
+// should be the architecture as represented by the Go runtime.
+func OptPartitionArch(arch string) PartitionOpt {
+	return func(po *partitionOpts) error {
+		po.arch = arch
+		return nil
+	}
+}
+
+// OptPartitionGroupID specifies that the partition and its key should be placed in the object
+// group with the specified groupID.
+func OptPartitionGroupID(groupID uint32) PartitionOpt {
+	return func(po *partitionOpts) error {
+		if groupID == 0 {
+			return sif.ErrInvalidGroupID
+		}
+		po.groupID = groupID
+		return nil
+	}
+}
+
+// newKey returns a randomly generated partition key.
+func newKey() ([]byte, error) {
+	key := make([]byte, keySize)
+	if _, err := io.ReadFull(rand.Reader, key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// encryptKey encrypts key using pub, and returns the PEM-encoded result.
+func encryptKey(key []byte, pub *rsa.PublicKey) ([]byte, error) {
+	b, err := rsa.EncryptOAEP(sha256.New(), rand.Reader, pub, key, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return pem.EncodeToMemory(&pem.Block{
+		Type: pemType,
+		Headers: map[string]string{
+			"Cipher": cipherName,
+		},
+		Bytes: b,
+	}), nil
+}
+
+// decryptKey decodes the PEM-encoded message in b, and decrypts the partition key it contains
+// using priv.
+func decryptKey(b []byte, priv *rsa.PrivateKey) ([]byte, error) {
+	p, _ := pem.Decode(b)
+	if p == nil || p.Type != pemType {
+		return nil, fmt.Errorf("%w: PEM block %v not found", errUnsupportedMessage, pemType)
+	}
+
+	if c := p.Headers["Cipher"]; c != cipherName {
+		return nil, fmt.Errorf("%w: unsupported cipher %q", errUnsupportedMessage, c)
+	}
+
+	key, err := rsa.DecryptOAEP(sha256.New(), rand.Reader, priv, p.Bytes, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(key) != keySize {
+		return nil, errInvalidKeySize
+	}
+
+	return key, nil
+}
+
+// AddPartition encrypts the SquashFS filesystem read from r with a randomly generated partition
+// key, and adds it to f as a partition. The partition key is encrypted using pub, and added to f
+// as a cryptographic message object linked to the partition.
+//
+// By default, the partition type is set to sif.PartPrimSys, and the CPU architecture is set to
+// that of the Go runtime. To override this behavior, consider using OptPartitionType and/or
+// OptPartitionArch.
+//
+// By default, the partition and its key are placed in the default object group. To override this
+// behavior, consider using OptPartitionGroupID.
+func AddPartition(f *sif.FileImage, r io.Reader, pub *rsa.PublicKey, opts ...PartitionOpt) error {
+	if f == nil {
+		return fmt.Errorf("encryption: %w", errNilFileImage)
+	}
+
+	if pub == nil {
+		return fmt.Errorf("encryption: %w", errNilKey)
+	}
+
+	po := partitionOpts{
+		pt:      sif.PartPrimSys,
+		arch:    runtime.GOARCH,
+		groupID: sif.DefaultObjectGroup,
+	}
+
+	for _, opt := range opts {
+		if err := opt(&po); err != nil {
+			return fmt.Errorf("encryption: %w", err)
+		}
+	}
+
+	key, err := newKey()
+	if err != nil {
+		return fmt.Errorf("encryption: %w", err)
+	}
+
+	msg, err := encryptKey(key, pub)
+	if err != nil {
+		return fmt.Errorf("encryption: %w", err)
+	}
+
+	er, err := newEncryptReader(r, key)
+	if err != nil {
+		return fmt.Errorf("encryption: %w", err)
+	}
+
+	di, err := sif.NewDescriptorInput(sif.DataPartition, er,
+		sif.OptPartitionMetadata(sif.FsEncryptedSquashfs, po.pt, po.arch),
+		sif.OptGroupID(po.groupID),
+	)
+	if err != nil {
+		return fmt.Errorf("encryption: %w", err)
+	}
+
+	// The partition and its key are added in a single update, so that the key can be linked to the
+	// partition, and so that neither is added if the other cannot be.
+	err = f.Update(func(tx *sif.Tx) error {
+		d, err := tx.AddObject(di)
+		if err != nil {
+			return err
+		}
+
+		di, err := sif.NewDescriptorInput(sif.DataCryptoMessage, bytes.NewReader(msg),
+			sif.OptCryptoMessageMetadata(sif.FormatPEM, sif.MessageRSAOAEP),
+			sif.OptGroupID(po.groupID),
+			sif.OptLinkedID(d.ID()),
+		)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.AddObject(di)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("encryption: %w", err)
+	}
+
+	return nil
+}
+
+// checkPartition returns an error if d does not describe an encrypted SquashFS partition.
+func checkPartition(d sif.Descriptor) error {
+	if d.DataType() != sif.DataPartition {
+		return errNotEncryptedPartition
+	}
+
+	if fs, _, _, err := d.PartitionMetadata(); err != nil {
+		return err
+	} else if fs != sif.FsEncryptedSquashfs {
+		return errNotEncryptedPartition
+	}
+
+	return nil
+}
+
+// DecryptKey locates the cryptographic message object in f that is linked to the encrypted
+// partition described by d, and decrypts the partition key it contains using priv.
+func DecryptKey(f *sif.FileImage, d sif.Descriptor, priv *rsa.PrivateKey) ([]byte, error) {
+	if f == nil {
+		return nil, fmt.Errorf("encryption: %w", errNilFileImage)
+	}
+
+	if priv == nil {
+		return nil, fmt.Errorf("encryption: %w", errNilKey)
+	}
+
+	if err := checkPartition(d); err != nil {
+		return nil, fmt.Errorf("encryption: %w", err)
+	}
+
+	md, err := f.GetDescriptor(
+		sif.WithDataType(sif.DataCryptoMessage),
+		sif.WithLinkedID(d.ID()),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("encryption: failed to get partition key: %w", err)
+	}
+
+	ft, mt, err := md.CryptoMessageMetadata()
+	if err != nil {
+		return nil, fmt.Errorf("encryption: %w", err)
+	}
+
+	if ft != sif.FormatPEM || mt != sif.MessageRSAOAEP {
+		return nil, fmt.Errorf("encryption: %w: %v/%v", errUnsupportedMessage, ft, mt)
+	}
+
+	b, err := md.GetData()
+	if err != nil {
+		return nil, fmt.Errorf("encryption: %w", err)
+	}
+
+	key, err := decryptKey(b, priv)
+	if err != nil {
+		return nil, fmt.Errorf("encryption: failed to decrypt partition key: %w", err)
+	}
+
+	return key, nil
+}
+
+// NewReader returns a Reader that decrypts the contents of the encrypted partition described by
+// d using key, which is typically obtained using DecryptKey.
+func NewReader(d sif.Descriptor, key []byte) (*Reader, error) {
+	if err := checkPartition(d); err != nil {
+		return nil, fmt.Errorf("encryption: %w", err)
+	}
+
+	ra, ok := d.GetReader().(io.ReaderAt)
+	if !ok {
+		return nil, fmt.Errorf("encryption: %w", errNotReaderAt)
+	}
+
+	r, err := newReader(ra, d.Size(), key)
+	if err != nil {
+		return nil, fmt.Errorf("encryption: %w", err)
+	}
+
+	return r, nil
+}
+
+// Open decrypts the partition key of the encrypted partition described by d in f using priv, and
+// returns a Reader that decrypts the contents of the partition. The returned Reader is suitable
+// for use with squashfs.New.
+func Open(f *sif.FileImage, d sif.Descriptor, priv *rsa.PrivateKey) (*Reader, error) {
+	key, err := DecryptKey(f, d, priv)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewReader(d, key)
+}
@@ -0,0 +1,358 @@
+// Copyright (c) 2023, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package encryption
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sigstore/sigstore/pkg/cryptoutils"
+	"github.com/sylabs/sif/v2/pkg/sif"
+	"github.com/sylabs/sif/v2/pkg/squashfs"
+)
+
+var corpus = filepath.Join("..", "..", "test", "images")
+
+// loadRSAKey returns the RSA private key from the test key directory.
+func loadRSAKey(t *testing.T) *rsa.PrivateKey {
+	t.Helper()
+
+	b, err := os.ReadFile(filepath.Join("..", "..", "test", "keys", "rsa-private.pem"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	k, err := cryptoutils.UnmarshalPEMToPrivateKey(b, cryptoutils.SkipPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return k.(*rsa.PrivateKey) //nolint:forcetypeassert
+}
+
+// readSquashFS returns the contents of the test SquashFS filesystem.
+func readSquashFS(t *testing.T) []byte {
+	t.Helper()
+
+	b, err := os.ReadFile(filepath.Join("..", "..", "test", "input", "root.squashfs"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestAddPartition(t *testing.T) {
+	priv := loadRSAKey(t)
+
+	tests := []struct {
+		name      string
+		pub       *rsa.PublicKey
+		opts      []PartitionOpt
+		wantErr   error
+		wantPT    sif.PartType
+		wantArch  string
+		wantGroup uint32
+	}{
+		{
+			name:    "NilKey",
+			wantErr: errNilKey,
+		},
+		{
+			name:    "InvalidGroupID",
+			pub:     &priv.PublicKey,
+			opts:    []PartitionOpt{OptPartitionGroupID(0)},
+			wantErr: sif.ErrInvalidGroupID,
+		},
+		{
+			name:      "Defaults",
+			pub:       &priv.PublicKey,
+			wantPT:    sif.PartPrimSys,
+			wantGroup: sif.DefaultObjectGroup,
+		},
+		{
+			name: "Options",
+			pub:  &priv.PublicKey,
+			opts: []PartitionOpt{
+				OptPartitionType(sif.PartSystem),
+				OptPartitionArch("arm64"),
+				OptPartitionGroupID(2),
+			},
+			wantPT:    sif.PartSystem,
+			wantArch:  "arm64",
+			wantGroup: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := sif.CreateContainer(sif.NewBuffer(nil), sif.OptCreateDeterministic())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = AddPartition(f, bytes.NewReader(readSquashFS(t)), tt.pub, tt.opts...)
+			if got, want := err, tt.wantErr; !errors.Is(got, want) {
+				t.Fatalf("got error %v, want %v", got, want)
+			}
+
+			if err != nil {
+				return
+			}
+
+			d, err := f.GetDescriptor(sif.WithDataType(sif.DataPartition))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			fst, pt, arch, err := d.PartitionMetadata()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got, want := fst, sif.FsEncryptedSquashfs; got != want {
+				t.Errorf("got filesystem type %v, want %v", got, want)
+			}
+
+			if got, want := pt, tt.wantPT; got != want {
+				t.Errorf("got partition type %v, want %v", got, want)
+			}
+
+			if tt.wantArch != "" {
+				if got, want := arch, tt.wantArch; got != want {
+					t.Errorf("got arch %v, want %v", got, want)
+				}
+			}
+
+			if got, want := d.GroupID(), tt.wantGroup; got != want {
+				t.Errorf("got group ID %v, want %v", got, want)
+			}
+
+			md, err := f.GetDescriptor(sif.WithDataType(sif.DataCryptoMessage))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if id, isGroup := md.LinkedID(); id != d.ID() || isGroup {
+				t.Errorf("got linked ID %v (group %v), want %v", id, isGroup, d.ID())
+			}
+
+			if got, want := md.GroupID(), tt.wantGroup; got != want {
+				t.Errorf("got key group ID %v, want %v", got, want)
+			}
+
+			ft, mt, err := md.CryptoMessageMetadata()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if ft != sif.FormatPEM || mt != sif.MessageRSAOAEP {
+				t.Errorf("got message metadata %v/%v", ft, mt)
+			}
+
+			// The partition contents must not be stored in the clear.
+			b, err := d.GetData()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if bytes.Contains(b, []byte("hsqs")) {
+				t.Error("partition contains SquashFS magic")
+			}
+		})
+	}
+}
+
+func TestOpen(t *testing.T) {
+	priv := loadRSAKey(t)
+
+	other, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := sif.CreateContainer(sif.NewBuffer(nil), sif.OptCreateDeterministic())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Add an unencrypted partition, followed by an encrypted partition.
+	di, err := sif.NewDescriptorInput(sif.DataPartition, bytes.NewReader(readSquashFS(t)),
+		sif.OptPartitionMetadata(sif.FsSquash, sif.PartSystem, "386"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := f.AddObject(di); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := AddPartition(f, bytes.NewReader(readSquashFS(t)), &priv.PublicKey); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		id      uint32
+		priv    *rsa.PrivateKey
+		wantErr error
+	}{
+		{
+			name:    "NilKey",
+			id:      2,
+			wantErr: errNilKey,
+		},
+		{
+			name:    "NotEncrypted",
+			id:      1,
+			priv:    priv,
+			wantErr: errNotEncryptedPartition,
+		},
+		{
+			name:    "NotPartition",
+			id:      3,
+			priv:    priv,
+			wantErr: errNotEncryptedPartition,
+		},
+		{
+			name:    "WrongKey",
+			id:      2,
+			priv:    other,
+			wantErr: rsa.ErrDecryption,
+		},
+		{
+			name: "OK",
+			id:   2,
+			priv: priv,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, err := f.GetDescriptor(sif.WithID(tt.id))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			r, err := Open(f, d, tt.priv)
+			if got, want := err, tt.wantErr; !errors.Is(got, want) {
+				t.Fatalf("got error %v, want %v", got, want)
+			}
+
+			if err != nil {
+				return
+			}
+
+			fsys, err := squashfs.New(r)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			b, err := fs.ReadFile(fsys, "hello.txt")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got, want := string(b), "Hello from Sylabs!\n"; got != want {
+				t.Errorf("got contents %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestDecryptKey_NotEncrypted(t *testing.T) {
+	f, err := sif.LoadContainerFromPath(filepath.Join(corpus, "two-groups.sif"), sif.OptLoadWithFlag(os.O_RDONLY))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := f.UnloadContainer(); err != nil {
+			t.Error(err)
+		}
+	})
+
+	d, err := f.GetDescriptor(sif.WithID(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := DecryptKey(f, d, loadRSAKey(t)); !errors.Is(err, errNotEncryptedPartition) {
+		t.Errorf("got error %v, want %v", err, errNotEncryptedPartition)
+	}
+}
+
+func TestReader(t *testing.T) {
+	key := bytes.Repeat([]byte{0x5a}, keySize)
+
+	// Use a length that is not a multiple of the sector size, to exercise padding.
+	data := make([]byte, 3*sectorSize+100)
+	if _, err := io.ReadFull(rand.Reader, data); err != nil {
+		t.Fatal(err)
+	}
+
+	er, err := newEncryptReader(bytes.NewReader(data), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	enc, err := io.ReadAll(er)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := len(enc), 4*sectorSize; got != want {
+		t.Fatalf("got %v encrypted bytes, want %v", got, want)
+	}
+
+	r, err := newReader(bytes.NewReader(enc), int64(len(enc)), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Expected contents are the original data, followed by zero padding.
+	want := append(bytes.Clone(data), make([]byte, len(enc)-len(data))...)
+
+	tests := []struct {
+		name    string
+		off     int64
+		n       int
+		wantN   int
+		wantErr error
+	}{
+		{name: "All", off: 0, n: len(want), wantN: len(want)},
+		{name: "Aligned", off: sectorSize, n: sectorSize, wantN: sectorSize},
+		{name: "Unaligned", off: 100, n: 1000, wantN: 1000},
+		{name: "Tail", off: int64(len(want)) - 10, n: 20, wantN: 10, wantErr: io.EOF},
+		{name: "EOF", off: int64(len(want)), n: 1, wantN: 0, wantErr: io.EOF},
+		{name: "NegativeOffset", off: -1, n: 1, wantN: 0, wantErr: errNegativeOffset},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := make([]byte, tt.n)
+
+			n, err := r.ReadAt(b, tt.off)
+			if got, want := err, tt.wantErr; !errors.Is(got, want) {
+				t.Fatalf("got error %v, want %v", got, want)
+			}
+
+			if got, want := n, tt.wantN; got != want {
+				t.Fatalf("got %v bytes, want %v", got, want)
+			}
+
+			if n > 0 && !bytes.Equal(b[:n], want[tt.off:tt.off+int64(n)]) {
+				t.Error("decrypted contents do not match")
+			}
+		})
+	}
+}
@@ -0,0 +1,149 @@
+// Copyright (c) 2023, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package encryption
+
+import (
+	"crypto/aes"
+	"errors"
+	"io"
+
+	"golang.org/x/crypto/xts"
+)
+
+// sectorSize is the size of an encrypted sector, in bytes.
+const sectorSize = 512
+
+var (
+	errNegativeOffset       = errors.New("negative offset")
+	errInvalidPartitionSize = errors.New("partition size is not a multiple of the sector size")
+)
+
+// newCipher returns an XTS cipher using key.
+func newCipher(key []byte) (*xts.Cipher, error) {
+	if len(key) != keySize {
+		return nil, errInvalidKeySize
+	}
+	return xts.NewCipher(aes.NewCipher, key)
+}
+
+// encryptReader encrypts data read from an underlying reader. If the length of the underlying
+// data is not a multiple of sectorSize, it is padded with zeroes.
+type encryptReader struct {
+	r      io.Reader
+	c      *xts.Cipher
+	sector uint64 // Number of next sector.
+	buf    []byte // Encrypted data not yet read.
+	err    error  // Sticky error.
+}
+
+// newEncryptReader returns a reader that encrypts data read from r using key.
+func newEncryptReader(r io.Reader, key []byte) (*encryptReader, error) {
+	c, err := newCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return &encryptReader{r: r, c: c}, nil
+}
+
+// Read reads up to len(p) bytes of encrypted data into p. It implements the io.Reader interface.
+func (er *encryptReader) Read(p []byte) (int, error) {
+	if len(er.buf) == 0 {
+		if er.err != nil {
+			return 0, er.err
+		}
+
+		b := make([]byte, sectorSize)
+
+		_, err := io.ReadFull(er.r, b)
+		switch {
+		case errors.Is(err, io.EOF):
+			return 0, io.EOF
+		case errors.Is(err, io.ErrUnexpectedEOF):
+			// Final sector is zero padded, since the remainder of b is zero.
+			er.err = io.EOF
+		case err != nil:
+			er.err = err
+			return 0, err
+		}
+
+		er.c.Encrypt(b, b, er.sector)
+		er.sector++
+		er.buf = b
+	}
+
+	n := copy(p, er.buf)
+	er.buf = er.buf[n:]
+
+	return n, nil
+}
+
+// Reader decrypts the contents of an encrypted partition. It implements the io.ReaderAt
+// interface.
+type Reader struct {
+	r    io.ReaderAt
+	size int64
+	c    *xts.Cipher
+}
+
+// newReader returns a Reader that decrypts size bytes read from r using key.
+func newReader(r io.ReaderAt, size int64, key []byte) (*Reader, error) {
+	if size%sectorSize != 0 {
+		return nil, errInvalidPartitionSize
+	}
+
+	c, err := newCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return &Reader{r: r, size: size, c: c}, nil
+}
+
+// Size returns the size of the decrypted partition, in bytes.
+func (r *Reader) Size() int64 { return r.size }
+
+// ReadAt reads len(p) bytes of decrypted data into p starting at offset off. It implements the
+// io.ReaderAt interface.
+func (r *Reader) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, errNegativeOffset
+	}
+
+	if off >= r.size {
+		return 0, io.EOF
+	}
+
+	// Determine the range of sectors to read.
+	end := off + int64(len(p))
+	if end > r.size {
+		end = r.size
+	}
+	start := off - off%sectorSize
+	if rem := end % sectorSize; rem != 0 {
+		end += sectorSize - rem
+	}
+
+	b := make([]byte, end-start)
+
+	n, err := r.r.ReadAt(b, start)
+	if n < len(b) {
+		if err == nil || errors.Is(err, io.EOF) {
+			err = io.ErrUnexpectedEOF
+		}
+		return 0, err
+	}
+
+	for i := 0; i < len(b); i += sectorSize {
+		s := b[i : i+sectorSize]
+		r.c.Decrypt(s, s, uint64(start+int64(i))/sectorSize)
+	}
+
+	n = copy(p, b[off-start:])
+	if n < len(p) {
+		return n, io.EOF
+	}
+
+	return n, nil
+}
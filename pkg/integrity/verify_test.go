@@ -10,6 +10,7 @@ import (
 	"crypto"
 	"errors"
 	"io"
+	"os"
 	"path/filepath"
 	"reflect"
 	"strings"
@@ -1142,3 +1143,76 @@ func TestVerifier_Verify(t *testing.T) {
 		})
 	}
 }
+
+func TestVerifier_VerifyAfterCompact(t *testing.T) {
+	b, err := os.ReadFile(filepath.Join(corpus, "two-groups-signed-dsse.sif"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := sif.LoadContainer(sif.NewBuffer(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := f.UnloadContainer(); err != nil {
+			t.Error(err)
+		}
+	})
+
+	before, err := f.GetDescriptor(sif.WithID(3))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Delete the first group and its signature without compaction, leaving a gap prior to the
+	// data object in the second group.
+	for _, id := range []uint32{1, 2, 4} {
+		if err := f.DeleteObject(id, sif.OptDeleteDeterministic()); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := f.Compact(sif.OptCompactDeterministic()); err != nil {
+		t.Fatal(err)
+	}
+
+	after, err := f.GetDescriptor(sif.WithID(3))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := after.Offset(), before.Offset(); got >= want {
+		t.Fatalf("got offset %v, want less than %v", got, want)
+	}
+
+	tests := []struct {
+		name string
+		opt  VerifierOpt
+	}{
+		{
+			name: "Group",
+			opt:  OptVerifyGroup(2),
+		},
+		{
+			name: "Object",
+			opt:  OptVerifyObject(3),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v, err := NewVerifier(f,
+				tt.opt,
+				OptVerifyWithVerifier(getTestVerifier(t, "ed25519-public.pem", crypto.Hash(0))),
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if err := v.Verify(); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
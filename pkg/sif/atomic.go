@@ -0,0 +1,212 @@
+// Copyright (c) 2023, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package sif
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+var errAtomicDiscarded = errors.New("modifications discarded due to earlier error")
+
+// atomicFile is the backing storage of an image loaded with OptLoadAtomic. Modifications are made
+// to a temporary copy of the image, which atomically replaces the original image on Close.
+//
+// If an error is encountered while modifying the temporary copy, the image may be inconsistent,
+// so the temporary copy is discarded on Close, leaving the original image unmodified. Further
+// attempts to modify the temporary copy fail, so that subsequent modifications are not reported
+// as successful. An error may be cleared if the modification that caused it is rolled back.
+//
+// The original image is held open until Close, so that any lock on it is retained until the
+// temporary copy has replaced it.
+type atomicFile struct {
+	fp    *os.File // Temporary copy.
+	orig  *os.File // Original image.
+	path  string   // Path of original image.
+	dirty bool     // Temporary copy has been modified.
+	err   error    // First error encountered while modifying temporary copy.
+}
+
+// openAtomic creates a temporary copy of the file at path, in the same directory. If path is a
+// symbolic link, the copy is made of the file it refers to. If lock is true, an exclusive lock is
+// acquired on the original file, as described by lockFile.
+func openAtomic(ctx context.Context, path string, lock bool) (*atomicFile, error) {
+	path, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var src *os.File
+	if lock {
+		src, err = openLocked(ctx, path, os.O_RDONLY, 0, true)
+	} else {
+		src, err = os.Open(path)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	fi, err := src.Stat()
+	if err != nil {
+		src.Close()
+		return nil, err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		src.Close()
+		return nil, err
+	}
+
+	if err := func() error {
+		if err := tmp.Chmod(fi.Mode().Perm()); err != nil {
+			return err
+		}
+
+		if _, err := io.Copy(tmp, src); err != nil {
+			return err
+		}
+
+		_, err := tmp.Seek(0, io.SeekStart)
+		return err
+	}(); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		src.Close()
+		return nil, err
+	}
+
+	return &atomicFile{fp: tmp, orig: src, path: path}, nil
+}
+
+// record notes that the temporary copy has been modified, and records err if it is the first
+// error encountered.
+func (a *atomicFile) record(err error) {
+	a.dirty = true
+
+	if err != nil && a.err == nil {
+		a.err = err
+	}
+}
+
+// fail records that a modification of f failed with err, which may have left the backing storage
+// of f inconsistent. If f was loaded with OptLoadAtomic, modifications are discarded when f is
+// unloaded. The caller must hold f.mu.
+func (f *FileImage) fail(err error) error {
+	if a, ok := f.rw.(*atomicFile); ok {
+		a.record(err)
+	}
+	return err
+}
+
+// failure returns the first error recorded by fail, or by the backing storage of f, if f was
+// loaded with OptLoadAtomic. The caller must hold f.mu.
+func (f *FileImage) failure() error {
+	if a, ok := f.rw.(*atomicFile); ok {
+		return a.err
+	}
+	return nil
+}
+
+// setFailure sets the error recorded for the backing storage of f to err, which must have been
+// returned by failure. This is used to clear errors encountered by a modification that has been
+// rolled back. The caller must hold f.mu.
+func (f *FileImage) setFailure(err error) {
+	if a, ok := f.rw.(*atomicFile); ok {
+		a.err = err
+	}
+}
+
+// ReadAt reads len(p) bytes from the temporary copy starting at offset off.
+func (a *atomicFile) ReadAt(p []byte, off int64) (int, error) {
+	return a.fp.ReadAt(p, off)
+}
+
+// Seek sets the offset for the next Write on the temporary copy.
+func (a *atomicFile) Seek(offset int64, whence int) (int64, error) {
+	return a.fp.Seek(offset, whence)
+}
+
+// Write writes p to the temporary copy. If an error was previously encountered, the temporary copy
+// is not modified, and an error is returned.
+func (a *atomicFile) Write(p []byte) (int, error) {
+	if a.err != nil {
+		return 0, fmt.Errorf("%w: %v", errAtomicDiscarded, a.err)
+	}
+
+	n, err := a.fp.Write(p)
+	a.record(err)
+	return n, err
+}
+
+// Truncate changes the size of the temporary copy. If an error was previously encountered, the
+// temporary copy is not modified, and an error is returned.
+func (a *atomicFile) Truncate(size int64) error {
+	if a.err != nil {
+		return fmt.Errorf("%w: %v", errAtomicDiscarded, a.err)
+	}
+
+	err := a.fp.Truncate(size)
+	a.record(err)
+	return err
+}
+
+// Close commits modifications by replacing the original image with the temporary copy. If the
+// temporary copy was not modified, or an error was encountered while modifying it, the temporary
+// copy is removed and the original image is left unmodified.
+func (a *atomicFile) Close() error {
+	defer a.orig.Close()
+
+	if !a.dirty || a.err != nil {
+		a.fp.Close()
+
+		if err := os.Remove(a.fp.Name()); err != nil {
+			return err
+		}
+
+		if a.err != nil {
+			return fmt.Errorf("%w: %v", errAtomicDiscarded, a.err)
+		}
+
+		return nil
+	}
+
+	if err := a.commit(); err != nil {
+		os.Remove(a.fp.Name())
+		return err
+	}
+
+	return nil
+}
+
+// commit flushes the temporary copy to stable storage, and renames it over the original image.
+func (a *atomicFile) commit() error {
+	if err := a.fp.Sync(); err != nil {
+		a.fp.Close()
+		return err
+	}
+
+	if err := a.fp.Close(); err != nil {
+		return err
+	}
+
+	if err := os.Rename(a.fp.Name(), a.path); err != nil {
+		return err
+	}
+
+	// Flush the directory entry, so that the rename persists.
+	d, err := os.Open(filepath.Dir(a.path))
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+
+	return d.Sync()
+}
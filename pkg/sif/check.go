@@ -0,0 +1,295 @@
+// Copyright (c) 2023, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package sif
+
+import (
+	"fmt"
+	"io"
+	"sort"
+)
+
+// ProblemType describes a class of structural problem found in an image.
+type ProblemType int
+
+// List of supported problem types.
+const (
+	ProblemDescriptorsFree  ProblemType = iota + 1 // Free descriptor count incorrect
+	ProblemUnusedDescriptor                        // Unused descriptor not zeroed
+	ProblemDuplicateID                             // Object ID used by multiple descriptors
+	ProblemObjectBounds                            // Object extends beyond end of file
+	ProblemObjectOverlap                           // Object overlaps another object or descriptors
+	ProblemMissingLink                             // Linked object or group not found
+	ProblemMultiplePrimary                         // Multiple primary system partitions
+	ProblemHeaderArch                              // Header arch inconsistent with primary partition
+	ProblemDataSize                                // Data section inconsistent with objects or file size
+	ProblemTrailingData                            // Data follows the end of the data section
+)
+
+// String returns a human-readable representation of t.
+func (t ProblemType) String() string {
+	switch t {
+	case ProblemDescriptorsFree:
+		return "Descriptors Free"
+	case ProblemUnusedDescriptor:
+		return "Unused Descriptor"
+	case ProblemDuplicateID:
+		return "Duplicate ID"
+	case ProblemObjectBounds:
+		return "Object Bounds"
+	case ProblemObjectOverlap:
+		return "Object Overlap"
+	case ProblemMissingLink:
+		return "Missing Link"
+	case ProblemMultiplePrimary:
+		return "Multiple Primary"
+	case ProblemHeaderArch:
+		return "Header Arch"
+	case ProblemDataSize:
+		return "Data Size"
+	case ProblemTrailingData:
+		return "Trailing Data"
+	}
+	return "Unknown"
+}
+
+// Problem describes a structural problem found in an image.
+type Problem struct {
+	Type    ProblemType // Type of problem.
+	IDs     []uint32    // IDs of the data objects involved, if applicable.
+	Message string      // Human-readable description.
+}
+
+// String returns a human-readable representation of p.
+func (p Problem) String() string {
+	return fmt.Sprintf("%v: %v", p.Type, p.Message)
+}
+
+// checker accumulates problems found in an image.
+type checker struct {
+	f        *FileImage
+	size     int64 // Size of backing storage.
+	problems []Problem
+}
+
+// add records a problem of type t, involving the data objects with the specified IDs.
+func (c *checker) add(t ProblemType, ids []uint32, format string, a ...any) {
+	c.problems = append(c.problems, Problem{
+		Type:    t,
+		IDs:     ids,
+		Message: fmt.Sprintf(format, a...),
+	})
+}
+
+// checkDescriptors checks the free descriptor count, that unused descriptors are zeroed, and that
+// object IDs are unique.
+func (c *checker) checkDescriptors() {
+	var used int64
+
+	ids := make(map[uint32]int)
+
+	for i, rd := range c.f.rds {
+		if !rd.Used {
+			if rd != (rawDescriptor{}) {
+				c.add(ProblemUnusedDescriptor, nil, "descriptor %v is unused but not zeroed", i)
+			}
+			continue
+		}
+
+		used++
+
+		if ids[rd.ID]++; ids[rd.ID] == 2 {
+			c.add(ProblemDuplicateID, []uint32{rd.ID}, "object ID %v used by multiple descriptors", rd.ID)
+		}
+	}
+
+	if want := int64(len(c.f.rds)) - used; c.f.h.DescriptorsFree != want {
+		c.add(ProblemDescriptorsFree, nil, "header records %v free descriptors, found %v",
+			c.f.h.DescriptorsFree, want)
+	}
+}
+
+// checkDataSection checks that the data section lies within the file, and that no data follows
+// it.
+func (c *checker) checkDataSection() {
+	end := c.f.h.DataOffset + c.f.h.DataSize
+
+	// The file may legitimately end before the data section if the image is empty.
+	if c.f.h.DataSize > 0 && end > c.size {
+		c.add(ProblemDataSize, nil, "data section (offset %v, size %v) extends beyond end of file (size %v)",
+			c.f.h.DataOffset, c.f.h.DataSize, c.size)
+	} else if c.size > end && c.size > c.f.h.DataOffset {
+		c.add(ProblemTrailingData, nil, "%v byte(s) follow end of data section (offset %v)",
+			c.size-end, end)
+	}
+}
+
+// checkObjects checks that data objects lie within the data section of the file, and do not
+// overlap.
+func (c *checker) checkObjects() {
+	var rds []rawDescriptor
+
+	end := c.f.h.DataOffset + c.f.h.DataSize
+
+	for _, rd := range c.f.rds {
+		if !rd.Used {
+			continue
+		}
+
+		if rd.Offset < 0 || rd.Size < 0 || rd.Offset+rd.Size > c.size {
+			c.add(ProblemObjectBounds, []uint32{rd.ID},
+				"object %v (offset %v, size %v) extends beyond end of file (size %v)",
+				rd.ID, rd.Offset, rd.Size, c.size)
+			continue
+		}
+
+		if rd.Size == 0 {
+			continue
+		}
+
+		if rd.Offset < c.f.h.DataOffset {
+			c.add(ProblemObjectOverlap, []uint32{rd.ID},
+				"object %v (offset %v) overlaps header or descriptors (data offset %v)",
+				rd.ID, rd.Offset, c.f.h.DataOffset)
+		}
+
+		if rd.Offset+rd.Size > end {
+			c.add(ProblemDataSize, []uint32{rd.ID},
+				"object %v (offset %v, size %v) extends beyond end of data section (offset %v)",
+				rd.ID, rd.Offset, rd.Size, end)
+		}
+
+		rds = append(rds, rd)
+	}
+
+	sort.SliceStable(rds, func(i, j int) bool { return rds[i].Offset < rds[j].Offset })
+
+	for i := range rds {
+		for _, next := range rds[i+1:] {
+			if next.Offset >= rds[i].Offset+rds[i].Size {
+				break
+			}
+
+			c.add(ProblemObjectOverlap, []uint32{rds[i].ID, next.ID},
+				"object %v (offset %v, size %v) overlaps object %v (offset %v, size %v)",
+				rds[i].ID, rds[i].Offset, rds[i].Size, next.ID, next.Offset, next.Size)
+		}
+	}
+}
+
+// checkLinks checks that linked objects and groups exist.
+func (c *checker) checkLinks() {
+	ids := make(map[uint32]bool)
+	groups := make(map[uint32]bool)
+
+	for _, rd := range c.f.rds {
+		if rd.Used {
+			ids[rd.ID] = true
+			groups[rd.GroupID&^descrGroupMask] = true
+		}
+	}
+
+	for _, rd := range c.f.rds {
+		if !rd.Used || rd.LinkedID == 0 {
+			continue
+		}
+
+		id := rd.LinkedID &^ descrGroupMask
+		isGroup := rd.LinkedID&descrGroupMask == descrGroupMask
+
+		if isGroup && (id == 0 || !groups[id]) {
+			c.add(ProblemMissingLink, []uint32{rd.ID}, "object %v linked to missing group %v", rd.ID, id)
+		} else if !isGroup && !ids[id] {
+			c.add(ProblemMissingLink, []uint32{rd.ID}, "object %v linked to missing object %v", rd.ID, id)
+		}
+	}
+}
+
+// checkPrimary checks that there is at most one primary system partition, and that the header
+// architecture is consistent with it.
+func (c *checker) checkPrimary() {
+	var primary []rawDescriptor
+
+	for _, rd := range c.f.rds {
+		if rd.Used && rd.isPartitionOfType(PartPrimSys) {
+			primary = append(primary, rd)
+		}
+	}
+
+	switch len(primary) {
+	case 0:
+		if c.f.h.Arch != hdrArchUnknown {
+			c.add(ProblemHeaderArch, nil, "header arch is %v, but image has no primary partition",
+				c.f.h.Arch.GoArch())
+		}
+
+	case 1:
+		var p partition
+		if err := primary[0].getExtra(binaryUnmarshaler{&p}); err != nil {
+			return
+		}
+
+		if c.f.h.Arch != p.Arch {
+			c.add(ProblemHeaderArch, []uint32{primary[0].ID},
+				"header arch is %v, but primary partition %v arch is %v",
+				c.f.h.Arch.GoArch(), primary[0].ID, p.Arch.GoArch())
+		}
+
+	default:
+		ids := make([]uint32, 0, len(primary))
+		for _, rd := range primary {
+			ids = append(ids, rd.ID)
+		}
+
+		c.add(ProblemMultiplePrimary, ids, "image contains %v primary system partitions", len(ids))
+	}
+}
+
+// size returns the size of the backing storage of f. The caller must hold f.mu for writing.
+func (f *FileImage) size() (int64, error) {
+	if s, ok := f.rw.(readOnlyStorage); ok {
+		return s.Size(), nil
+	}
+	return f.rw.Seek(0, io.SeekEnd)
+}
+
+// Check validates the structure of f, and returns a list of the problems found. The following
+// checks are performed:
+//
+//   - The free descriptor count recorded in the global header is consistent with the number of
+//     unused descriptors.
+//   - Unused descriptors are zeroed.
+//   - Each object ID is used by a single descriptor.
+//   - The data section recorded in the global header lies within the file, and is not followed by
+//     further data.
+//   - Data objects lie within the data section of the file, and do not overlap.
+//   - Linked objects and groups exist.
+//   - The image contains at most one primary system partition, and the architecture recorded in
+//     the global header matches it.
+//
+// If the image is structurally valid, an empty list is returned.
+func Check(f *FileImage) ([]Problem, error) {
+	// Determining the size of the backing storage may require seeking, so prevent concurrent access.
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	size, err := f.size()
+	if err != nil {
+		return nil, fmt.Errorf("%w", err)
+	}
+
+	c := checker{
+		f:    f,
+		size: size,
+	}
+
+	c.checkDescriptors()
+	c.checkDataSection()
+	c.checkObjects()
+	c.checkLinks()
+	c.checkPrimary()
+
+	return c.problems, nil
+}
@@ -0,0 +1,295 @@
+// Copyright (c) 2023, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package sif
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestCheck(t *testing.T) {
+	// Image containing a primary partition, a system partition and a linked object, followed by
+	// an unused descriptor.
+	createOpts := func() []CreateOpt {
+		return []CreateOpt{
+			OptCreateDeterministic(),
+			OptCreateWithDescriptorCapacity(4),
+			OptCreateWithDescriptors(
+				getDescriptorInput(t, DataPartition, []byte{0xfa, 0xce},
+					OptPartitionMetadata(FsSquash, PartPrimSys, "386"),
+				),
+				getDescriptorInput(t, DataPartition, []byte{0xfe, 0xed},
+					OptPartitionMetadata(FsExt3, PartSystem, "386"),
+				),
+				getDescriptorInput(t, DataGeneric, []byte{0xde, 0xad},
+					OptLinkedID(1),
+				),
+			),
+		}
+	}
+
+	tests := []struct {
+		name       string
+		createOpts []CreateOpt
+		modify     func(t *testing.T, f *FileImage)
+		want       []Problem
+	}{
+		{
+			name: "Empty",
+			createOpts: []CreateOpt{
+				OptCreateDeterministic(),
+			},
+		},
+		{
+			name:       "OK",
+			createOpts: createOpts(),
+		},
+		{
+			name:       "DescriptorsFree",
+			createOpts: createOpts(),
+			modify: func(t *testing.T, f *FileImage) {
+				f.h.DescriptorsFree = 0
+			},
+			want: []Problem{
+				{Type: ProblemDescriptorsFree},
+			},
+		},
+		{
+			name:       "UnusedDescriptor",
+			createOpts: createOpts(),
+			modify: func(t *testing.T, f *FileImage) {
+				f.rds[3].Size = 2
+			},
+			want: []Problem{
+				{Type: ProblemUnusedDescriptor},
+			},
+		},
+		{
+			name:       "DuplicateID",
+			createOpts: createOpts(),
+			modify: func(t *testing.T, f *FileImage) {
+				f.rds[1].ID = 1
+			},
+			want: []Problem{
+				{Type: ProblemDuplicateID, IDs: []uint32{1}},
+			},
+		},
+		{
+			name:       "ObjectBounds",
+			createOpts: createOpts(),
+			modify: func(t *testing.T, f *FileImage) {
+				f.rds[2].Size = 1 << 20
+			},
+			want: []Problem{
+				{Type: ProblemObjectBounds, IDs: []uint32{3}},
+			},
+		},
+		{
+			name:       "ObjectOverlap",
+			createOpts: createOpts(),
+			modify: func(t *testing.T, f *FileImage) {
+				f.rds[2].Offset = f.rds[1].Offset + 1
+			},
+			want: []Problem{
+				{Type: ProblemObjectOverlap, IDs: []uint32{2, 3}},
+			},
+		},
+		{
+			name:       "ObjectOverlapDescriptors",
+			createOpts: createOpts(),
+			modify: func(t *testing.T, f *FileImage) {
+				f.rds[2].Offset = f.h.DescriptorsOffset
+			},
+			want: []Problem{
+				{Type: ProblemObjectOverlap, IDs: []uint32{3}},
+			},
+		},
+		{
+			name:       "DeleteNoCompact",
+			createOpts: createOpts(),
+			modify: func(t *testing.T, f *FileImage) {
+				if err := f.DeleteObject(3); err != nil {
+					t.Fatal(err)
+				}
+			},
+		},
+		{
+			name:       "DataSizeBeyondEOF",
+			createOpts: createOpts(),
+			modify: func(t *testing.T, f *FileImage) {
+				f.h.DataSize += 1000
+			},
+			want: []Problem{
+				{Type: ProblemDataSize},
+			},
+		},
+		{
+			name:       "ObjectBeyondDataSection",
+			createOpts: createOpts(),
+			modify: func(t *testing.T, f *FileImage) {
+				f.h.DataSize -= 2
+			},
+			want: []Problem{
+				{Type: ProblemTrailingData},
+				{Type: ProblemDataSize, IDs: []uint32{3}},
+			},
+		},
+		{
+			name:       "TrailingData",
+			createOpts: createOpts(),
+			modify: func(t *testing.T, f *FileImage) {
+				if _, err := f.rw.Seek(0, io.SeekEnd); err != nil {
+					t.Fatal(err)
+				}
+
+				if _, err := f.rw.Write([]byte{0xde, 0xad, 0xbe, 0xef}); err != nil {
+					t.Fatal(err)
+				}
+			},
+			want: []Problem{
+				{Type: ProblemTrailingData},
+			},
+		},
+		{
+			name:       "MissingLinkedObject",
+			createOpts: createOpts(),
+			modify: func(t *testing.T, f *FileImage) {
+				f.rds[2].LinkedID = 5
+			},
+			want: []Problem{
+				{Type: ProblemMissingLink, IDs: []uint32{3}},
+			},
+		},
+		{
+			name:       "MissingLinkedGroup",
+			createOpts: createOpts(),
+			modify: func(t *testing.T, f *FileImage) {
+				f.rds[2].LinkedID = 5 | descrGroupMask
+			},
+			want: []Problem{
+				{Type: ProblemMissingLink, IDs: []uint32{3}},
+			},
+		},
+		{
+			name:       "MultiplePrimary",
+			createOpts: createOpts(),
+			modify: func(t *testing.T, f *FileImage) {
+				p := partition{Fstype: FsExt3, Parttype: PartPrimSys, Arch: hdrArch386}
+				if err := f.rds[1].setExtra(p); err != nil {
+					t.Fatal(err)
+				}
+			},
+			want: []Problem{
+				{Type: ProblemMultiplePrimary, IDs: []uint32{1, 2}},
+			},
+		},
+		{
+			name:       "HeaderArch",
+			createOpts: createOpts(),
+			modify: func(t *testing.T, f *FileImage) {
+				f.h.Arch = hdrArchARM64
+			},
+			want: []Problem{
+				{Type: ProblemHeaderArch, IDs: []uint32{1}},
+			},
+		},
+		{
+			name: "HeaderArchNoPrimary",
+			createOpts: []CreateOpt{
+				OptCreateDeterministic(),
+			},
+			modify: func(t *testing.T, f *FileImage) {
+				f.h.Arch = hdrArchAMD64
+			},
+			want: []Problem{
+				{Type: ProblemHeaderArch},
+			},
+		},
+		{
+			name:       "Multiple",
+			createOpts: createOpts(),
+			modify: func(t *testing.T, f *FileImage) {
+				f.h.DescriptorsFree = 2
+				f.rds[2].LinkedID = 5
+				f.h.Arch = hdrArchUnknown
+			},
+			want: []Problem{
+				{Type: ProblemDescriptorsFree},
+				{Type: ProblemMissingLink, IDs: []uint32{3}},
+				{Type: ProblemHeaderArch, IDs: []uint32{1}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var b Buffer
+
+			f, err := CreateContainer(&b, tt.createOpts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Cleanup(func() {
+				if err := f.UnloadContainer(); err != nil {
+					t.Error(err)
+				}
+			})
+
+			if tt.modify != nil {
+				tt.modify(t, f)
+			}
+
+			problems, err := Check(f)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// Messages are not compared.
+			for i := range problems {
+				problems[i].Message = ""
+			}
+
+			if got, want := problems, tt.want; !reflect.DeepEqual(got, want) {
+				t.Errorf("got problems %+v, want %+v", got, want)
+			}
+		})
+	}
+}
+
+func TestCheck_Corpus(t *testing.T) {
+	paths, err := filepath.Glob(filepath.Join(corpus, "*.sif"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, path := range paths {
+		path := path
+
+		t.Run(filepath.Base(path), func(t *testing.T) {
+			f, err := LoadContainerFromPath(path, OptLoadWithFlag(os.O_RDONLY))
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Cleanup(func() {
+				if err := f.UnloadContainer(); err != nil {
+					t.Error(err)
+				}
+			})
+
+			problems, err := Check(f)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(problems) > 0 {
+				t.Errorf("got problems %v, want none", problems)
+			}
+		})
+	}
+}
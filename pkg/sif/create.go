@@ -8,12 +8,14 @@
 package sif
 
 import (
+	"context"
 	"encoding"
 	"encoding/binary"
 	"errors"
 	"fmt"
 	"io"
 	"os"
+	"sort"
 	"time"
 
 	"github.com/google/uuid"
@@ -45,6 +47,10 @@ func writeDataObjectAt(ws io.WriteSeeker, offsetUnaligned int64, di DescriptorIn
 		return err
 	}
 
+	if size := di.opts.size; size >= 0 && n != size {
+		return fmt.Errorf("%w: got %v bytes, want %v", errObjectSizeMismatch, n, size)
+	}
+
 	if err := di.fillDescriptor(t, d); err != nil {
 		return err
 	}
@@ -59,20 +65,21 @@ func writeDataObjectAt(ws io.WriteSeeker, offsetUnaligned int64, di DescriptorIn
 var (
 	errInsufficientCapacity = errors.New("insufficient descriptor capacity to add data object(s) to image")
 	errPrimaryPartition     = errors.New("image already contains a primary partition")
+	errObjectSizeMismatch   = errors.New("data object size mismatch")
 )
 
-// writeDataObject writes the data object described by di to f, using time t, recording details in
-// the descriptor at index i.
-func (f *FileImage) writeDataObject(i int, di DescriptorInput, t time.Time) error {
+// reserveDescriptor prepares the descriptor at index i to record details of the data object
+// described by di, and returns a pointer to it.
+func (f *FileImage) reserveDescriptor(i int, di DescriptorInput) (*rawDescriptor, error) {
 	if i >= len(f.rds) {
-		return errInsufficientCapacity
+		return nil, errInsufficientCapacity
 	}
 
 	// If this is a primary partition, verify there isn't another primary partition, and update the
 	// architecture in the global header.
 	if p, ok := di.opts.md.(partition); ok && p.Parttype == PartPrimSys {
-		if ds, err := f.GetDescriptors(WithPartitionType(PartPrimSys)); err == nil && len(ds) > 0 {
-			return errPrimaryPartition
+		if err := f.withDescriptors(WithPartitionType(PartPrimSys), abortOnMatch); errors.Is(err, errAbort) {
+			return nil, errPrimaryPartition
 		}
 
 		f.h.Arch = p.Arch
@@ -81,10 +88,22 @@ func (f *FileImage) writeDataObject(i int, di DescriptorInput, t time.Time) erro
 	d := &f.rds[i]
 	d.ID = uint32(i) + 1
 
-	if err := writeDataObjectAt(f.rw, f.h.DataOffset+f.h.DataSize, di, t, d); err != nil {
+	return d, nil
+}
+
+// writeDataObject writes the data object described by di to f, using time t, recording details in
+// the descriptor at index i.
+func (f *FileImage) writeDataObject(i int, di DescriptorInput, t time.Time) error {
+	d, err := f.reserveDescriptor(i, di)
+	if err != nil {
 		return err
 	}
 
+	if err := writeDataObjectAt(f.rw, f.h.DataOffset+f.h.DataSize, di, t, d); err != nil {
+		// Data may have been partially written, for example if di could not be read in full.
+		return f.fail(err)
+	}
+
 	// Update minimum object ID map.
 	if minID, ok := f.minIDs[d.GroupID]; !ok || d.ID < minID {
 		f.minIDs[d.GroupID] = d.ID
@@ -123,6 +142,8 @@ type createOpts struct {
 	dis                []DescriptorInput
 	t                  time.Time
 	closeOnUnload      bool
+	lock               bool
+	lockCtx            context.Context //nolint:containedctx
 }
 
 // CreateOpt are used to specify container creation options.
@@ -198,8 +219,57 @@ func OptCreateWithCloseOnUnload(b bool) CreateOpt {
 	}
 }
 
+// OptCreateWithLock specifies whether an exclusive advisory lock should be acquired on the
+// container file. The lock is released by UnloadContainer.
+//
+// By default, a lock is acquired, and an error wrapping ErrLocked is returned immediately if a
+// conflicting lock is held by another process. To wait for the lock, consider using
+// OptCreateWithLockContext.
+//
+// This option is only applicable to CreateContainerAtPath. Locking is not supported on all
+// platforms; where unsupported, no lock is acquired.
+func OptCreateWithLock(b bool) CreateOpt {
+	return func(co *createOpts) error {
+		co.lock = b
+		return nil
+	}
+}
+
+// OptCreateWithLockContext specifies that if a conflicting lock is held on the container file,
+// the lock should be waited for until it is acquired, or ctx is done. See OptCreateWithLock for
+// details.
+func OptCreateWithLockContext(ctx context.Context) CreateOpt {
+	return func(co *createOpts) error {
+		co.lock = true
+		co.lockCtx = ctx
+		return nil
+	}
+}
+
 // createContainer creates a new SIF container file in rw, according to opts.
 func createContainer(rw ReadWriter, co createOpts) (*FileImage, error) {
+	f := newFileImage(rw, co)
+
+	for i, di := range co.dis {
+		if err := f.writeDataObject(i, di, co.t); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := f.writeDescriptors(); err != nil {
+		return nil, err
+	}
+
+	if err := f.writeHeader(); err != nil {
+		return nil, err
+	}
+
+	return f, nil
+}
+
+// newFileImage returns an image containing a global header and empty descriptors, according to
+// co. No data is written to rw.
+func newFileImage(rw ReadWriter, co createOpts) *FileImage {
 	rds := make([]rawDescriptor, co.descriptorCapacity)
 	rdsSize := int64(binary.Size(rds))
 
@@ -218,28 +288,38 @@ func createContainer(rw ReadWriter, co createOpts) (*FileImage, error) {
 		DataOffset:        co.descriptorsOffset + rdsSize,
 	}
 
-	f := &FileImage{
+	return &FileImage{
 		rw:     rw,
 		h:      h,
 		rds:    rds,
 		minIDs: make(map[uint32]uint32),
 	}
+}
 
-	for i, di := range co.dis {
-		if err := f.writeDataObject(i, di, co.t); err != nil {
-			return nil, err
-		}
+// getCreateOpts returns container creation options, populated with default values and then
+// modified according to opts.
+func getCreateOpts(opts ...CreateOpt) (createOpts, error) {
+	id, err := uuid.NewRandom()
+	if err != nil {
+		return createOpts{}, err
 	}
 
-	if err := f.writeDescriptors(); err != nil {
-		return nil, err
+	co := createOpts{
+		id:                 id,
+		descriptorsOffset:  4096,
+		descriptorCapacity: 48,
+		t:                  time.Now(),
+		closeOnUnload:      true,
+		lock:               true,
 	}
 
-	if err := f.writeHeader(); err != nil {
-		return nil, err
+	for _, opt := range opts {
+		if err := opt(&co); err != nil {
+			return createOpts{}, err
+		}
 	}
 
-	return f, nil
+	return co, nil
 }
 
 // CreateContainer creates a new SIF container in rw, according to opts. One or more data objects
@@ -260,23 +340,9 @@ func createContainer(rw ReadWriter, co createOpts) (*FileImage, error) {
 //
 // A launch script can optionally be set using OptCreateWithLaunchScript.
 func CreateContainer(rw ReadWriter, opts ...CreateOpt) (*FileImage, error) {
-	id, err := uuid.NewRandom()
+	co, err := getCreateOpts(opts...)
 	if err != nil {
-		return nil, err
-	}
-
-	co := createOpts{
-		id:                 id,
-		descriptorsOffset:  4096,
-		descriptorCapacity: 48,
-		t:                  time.Now(),
-		closeOnUnload:      true,
-	}
-
-	for _, opt := range opts {
-		if err := opt(&co); err != nil {
-			return nil, fmt.Errorf("%w", err)
-		}
+		return nil, fmt.Errorf("%w", err)
 	}
 
 	f, err := createContainer(rw, co)
@@ -304,18 +370,41 @@ func CreateContainer(rw ReadWriter, opts ...CreateOpt) (*FileImage, error) {
 // OptCreateWithDescriptorCapacity.
 //
 // A launch script can optionally be set using OptCreateWithLaunchScript.
+//
+// By default, an exclusive advisory lock is acquired on the file, so that concurrent modification
+// by other processes is prevented. To change this behavior, consider using OptCreateWithLock or
+// OptCreateWithLockContext.
 func CreateContainerAtPath(path string, opts ...CreateOpt) (*FileImage, error) {
-	fp, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0o755)
+	co, err := getCreateOpts(opts...)
+	if err != nil {
+		return nil, fmt.Errorf("%w", err)
+	}
+
+	// The file is truncated once opened (and locked, if applicable), so that the contents of a
+	// file locked by another process are not discarded.
+	flag := os.O_RDWR | os.O_CREATE
+
+	var fp *os.File
+	if co.lock {
+		fp, err = openLocked(co.lockCtx, path, flag, 0o755, true)
+	} else {
+		fp, err = os.OpenFile(path, flag, 0o755)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("%w", err)
 	}
 
-	f, err := CreateContainer(fp, opts...)
+	f, err := func() (*FileImage, error) {
+		if err := fp.Truncate(0); err != nil {
+			return nil, err
+		}
+		return createContainer(fp, co)
+	}()
 	if err != nil {
 		fp.Close()
 		os.Remove(fp.Name())
 
-		return nil, err
+		return nil, fmt.Errorf("%w", err)
 	}
 
 	f.closeOnUnload = true
@@ -352,6 +441,16 @@ func OptAddWithTime(t time.Time) AddOpt {
 // By default, the image modification time is set to the current time for non-deterministic images,
 // and unset otherwise. To override this, consider using OptAddDeterministic or OptAddWithTime.
 func (f *FileImage) AddObject(di DescriptorInput, opts ...AddOpt) error {
+	f.wmu.Lock()
+	defer f.wmu.Unlock()
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.readOnly {
+		return ErrReadOnly
+	}
+
 	ao := addOpts{}
 
 	if !f.isDeterministic() {
@@ -390,19 +489,6 @@ func (f *FileImage) AddObject(di DescriptorInput, opts ...AddOpt) error {
 	return nil
 }
 
-// isLast return true if the data object associated with d is the last in f.
-func (f *FileImage) isLast(d *rawDescriptor) bool {
-	isLast := true
-
-	end := d.Offset + d.Size
-	f.WithDescriptors(func(d Descriptor) bool {
-		isLast = d.Offset()+d.Size() <= end
-		return !isLast
-	})
-
-	return isLast
-}
-
 // zeroReader is an io.Reader that returns a stream of zero-bytes.
 type zeroReader struct{}
 
@@ -423,11 +509,105 @@ func (f *FileImage) zero(d *rawDescriptor) error {
 	return err
 }
 
-// truncateAt truncates f at the start of the padded data object described by d.
-func (f *FileImage) truncateAt(d *rawDescriptor) error {
-	start := d.Offset + d.Size - d.SizeWithPadding
+// objectAlignment returns the alignment of the data object described by d. The alignment
+// requirement of a data object is not recorded in its descriptor, so the largest power of two that
+// divides the object offset is used. This is guaranteed to satisfy the original requirement, but
+// may exceed it. For example, an object that requires 4096 byte alignment but happens to be
+// located at offset 8192 is treated as requiring 8192 byte alignment, and any padding in excess of
+// the original requirement cannot be reclaimed by compaction.
+func objectAlignment(d *rawDescriptor) int {
+	if d.Offset <= 0 {
+		return 0
+	}
+	return int(d.Offset & -d.Offset)
+}
+
+// moveData moves n bytes of data located at offset src to offset dst in f. The source and
+// destination regions may overlap. If an error is encountered, the data may have been partially
+// moved, so the failure is recorded as described by fail.
+func (f *FileImage) moveData(dst, src, n int64) error {
+	if err := f.copyData(dst, src, n); err != nil {
+		return f.fail(err)
+	}
+	return nil
+}
+
+// copyData copies n bytes of data located at offset src to offset dst in f. The source and
+// destination regions may overlap.
+func (f *FileImage) copyData(dst, src, n int64) error {
+	if dst <= src {
+		if _, err := f.rw.Seek(dst, io.SeekStart); err != nil {
+			return err
+		}
+
+		_, err := io.CopyN(f.rw, io.NewSectionReader(f.rw, src, n), n)
+		return err
+	}
+
+	// Moving towards the end of the file, so copy chunks starting from the end of the region to
+	// avoid overwriting data that has not yet been copied.
+	b := make([]byte, 32*1024)
+
+	for n > 0 {
+		l := int64(len(b))
+		if n < l {
+			l = n
+		}
+		n -= l
+
+		if _, err := io.ReadFull(io.NewSectionReader(f.rw, src+n, l), b[:l]); err != nil {
+			return err
+		}
+
+		if _, err := f.rw.Seek(dst+n, io.SeekStart); err != nil {
+			return err
+		}
+
+		if _, err := f.rw.Write(b[:l]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// compactFrom moves the data objects located at or beyond offset start towards the start of the
+// data section, such that no space remains between them other than that required to satisfy
+// object alignment. The data section is then truncated following the last data object.
+func (f *FileImage) compactFrom(start int64) error {
+	var rds []*rawDescriptor
+	for i := range f.rds {
+		if rd := &f.rds[i]; rd.Used && rd.Offset >= start {
+			rds = append(rds, rd)
+		}
+	}
+
+	sort.Slice(rds, func(i, j int) bool { return rds[i].Offset < rds[j].Offset })
+
+	end := start
+
+	for _, rd := range rds {
+		offset := nextAligned(end, objectAlignment(rd))
+
+		if offset != rd.Offset {
+			if err := f.moveData(offset, rd.Offset, rd.Size); err != nil {
+				return err
+			}
+		}
+
+		rd.Offset = offset
+		rd.SizeWithPadding = offset - end + rd.Size
+
+		end = offset + rd.Size
+	}
 
-	return f.rw.Truncate(start)
+	if err := f.rw.Truncate(end); err != nil {
+		return err
+	}
+
+	f.h.DataSize = end - f.h.DataOffset
+
+	return nil
 }
 
 // deleteOpts accumulates object deletion options.
@@ -473,17 +653,26 @@ func OptDeleteWithTime(t time.Time) DeleteOpt {
 	}
 }
 
-var errCompactNotImplemented = errors.New("compact not implemented for non-last object")
-
 // DeleteObject deletes the data object with id, according to opts.
 //
 // To zero the data region of the deleted object, use OptDeleteZero. To compact the file following
-// object deletion, use OptDeleteCompact.
+// object deletion, use OptDeleteCompact. When compacting, data objects located after the deleted
+// object are moved towards the start of the data section, and the file is truncated accordingly.
 //
 // By default, the image modification time is set to the current time for non-deterministic images,
 // and unset otherwise. To override this, consider using OptDeleteDeterministic or
 // OptDeleteWithTime.
 func (f *FileImage) DeleteObject(id uint32, opts ...DeleteOpt) error {
+	f.wmu.Lock()
+	defer f.wmu.Unlock()
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.readOnly {
+		return ErrReadOnly
+	}
+
 	do := deleteOpts{}
 
 	if !f.isDeterministic() {
@@ -501,24 +690,12 @@ func (f *FileImage) DeleteObject(id uint32, opts ...DeleteOpt) error {
 		return fmt.Errorf("%w", err)
 	}
 
-	if do.compact && !f.isLast(d) {
-		return fmt.Errorf("%w", errCompactNotImplemented)
-	}
-
 	if do.zero {
 		if err := f.zero(d); err != nil {
 			return fmt.Errorf("%w", err)
 		}
 	}
 
-	if do.compact {
-		if err := f.truncateAt(d); err != nil {
-			return fmt.Errorf("%w", err)
-		}
-
-		f.h.DataSize -= d.SizeWithPadding
-	}
-
 	f.h.DescriptorsFree++
 	f.h.ModifiedAt = do.t.Unix()
 
@@ -529,13 +706,100 @@ func (f *FileImage) DeleteObject(id uint32, opts ...DeleteOpt) error {
 		f.h.Arch = hdrArchUnknown
 	}
 
+	// Note start of padded data object, prior to resetting descriptor.
+	start := d.Offset + d.Size - d.SizeWithPadding
+
 	// Reset rawDescripter with empty struct
 	*d = rawDescriptor{}
 
+	if do.compact {
+		if err := f.compactFrom(start); err != nil {
+			return fmt.Errorf("%w", err)
+		}
+	}
+
+	if err := f.writeDescriptors(); err != nil {
+		return fmt.Errorf("%w", err)
+	}
+
+	if err := f.writeHeader(); err != nil {
+		return fmt.Errorf("%w", err)
+	}
+
+	return nil
+}
+
+// compactOpts accumulates image compaction options.
+type compactOpts struct {
+	t time.Time
+}
+
+// CompactOpt are used to specify image compaction options.
+type CompactOpt func(*compactOpts) error
+
+// OptCompactDeterministic sets header/descriptor fields to values that support deterministic
+// modification of images.
+func OptCompactDeterministic() CompactOpt {
+	return func(co *compactOpts) error {
+		co.t = time.Time{}
+		return nil
+	}
+}
+
+// OptCompactWithTime specifies t as the image modification time.
+func OptCompactWithTime(t time.Time) CompactOpt {
+	return func(co *compactOpts) error {
+		co.t = t
+		return nil
+	}
+}
+
+// Compact rewrites the data section of f to reclaim unused space, such as that left behind by
+// objects deleted without compaction. Data objects are moved towards the start of the data
+// section as far as their alignment permits, and the file is truncated following the last data
+// object. Object IDs, group IDs and links are preserved, so existing digital signatures remain
+// valid.
+//
+// The alignment requirement of a data object is not recorded in the image, so it is inferred from
+// the current object offset as the largest power of two that divides it. This never violates the
+// original requirement, but may be stricter than it, in which case some padding is retained.
+//
+// By default, the image modification time is set to the current time for non-deterministic images,
+// and unset otherwise. To override this, consider using OptCompactDeterministic or
+// OptCompactWithTime.
+func (f *FileImage) Compact(opts ...CompactOpt) error {
+	f.wmu.Lock()
+	defer f.wmu.Unlock()
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.readOnly {
+		return ErrReadOnly
+	}
+
+	co := compactOpts{}
+
+	if !f.isDeterministic() {
+		co.t = time.Now()
+	}
+
+	for _, opt := range opts {
+		if err := opt(&co); err != nil {
+			return fmt.Errorf("%w", err)
+		}
+	}
+
+	if err := f.compactFrom(f.h.DataOffset); err != nil {
+		return fmt.Errorf("%w", err)
+	}
+
 	if err := f.writeDescriptors(); err != nil {
 		return fmt.Errorf("%w", err)
 	}
 
+	f.h.ModifiedAt = co.t.Unix()
+
 	if err := f.writeHeader(); err != nil {
 		return fmt.Errorf("%w", err)
 	}
@@ -579,6 +843,16 @@ var (
 // non-deterministic images, and unset otherwise. To override this, consider using
 // OptSetDeterministic or OptSetWithTime.
 func (f *FileImage) SetPrimPart(id uint32, opts ...SetOpt) error {
+	f.wmu.Lock()
+	defer f.wmu.Unlock()
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.readOnly {
+		return ErrReadOnly
+	}
+
 	so := setOpts{}
 
 	if !f.isDeterministic() {
@@ -661,6 +935,16 @@ func (f *FileImage) SetPrimPart(id uint32, opts ...SetOpt) error {
 // non-deterministic images, and unset otherwise. To override this, consider using
 // OptSetDeterministic or OptSetWithTime.
 func (f *FileImage) SetMetadata(id uint32, md encoding.BinaryMarshaler, opts ...SetOpt) error {
+	f.wmu.Lock()
+	defer f.wmu.Unlock()
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.readOnly {
+		return ErrReadOnly
+	}
+
 	so := setOpts{}
 
 	if !f.isDeterministic() {
@@ -696,3 +980,104 @@ func (f *FileImage) SetMetadata(id uint32, md encoding.BinaryMarshaler, opts ...
 
 	return nil
 }
+
+var errDescriptorCapacity = errors.New("descriptor capacity insufficient for data objects in image")
+
+// SetDescriptorCapacity sets the capacity of the descriptor section of f to n descriptors,
+// according to opts. If the enlarged descriptor section would overlap the data section, the data
+// section is relocated towards the end of the file. The capacity cannot be reduced such that an
+// in-use descriptor would be discarded.
+//
+// By default, the image modification time is set to the current time for non-deterministic images,
+// and unset otherwise. To override this, consider using OptSetDeterministic or OptSetWithTime.
+func (f *FileImage) SetDescriptorCapacity(n int64, opts ...SetOpt) error {
+	f.wmu.Lock()
+	defer f.wmu.Unlock()
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.readOnly {
+		return ErrReadOnly
+	}
+
+	so := setOpts{}
+
+	if !f.isDeterministic() {
+		so.t = time.Now()
+	}
+
+	for _, opt := range opts {
+		if err := opt(&so); err != nil {
+			return fmt.Errorf("%w", err)
+		}
+	}
+
+	if n < 0 {
+		return fmt.Errorf("%w", errDescriptorCapacity)
+	}
+
+	for i := n; i < int64(len(f.rds)); i++ {
+		if f.rds[i].Used {
+			return fmt.Errorf("%w", errDescriptorCapacity)
+		}
+	}
+
+	rds := make([]rawDescriptor, n)
+	copy(rds, f.rds)
+
+	rdsSize := int64(binary.Size(rds))
+	end := f.h.DescriptorsOffset + rdsSize
+
+	// If the descriptor section would overlap the data section, relocate the data section. The
+	// data section is moved by a multiple of the largest object alignment, so that the alignment
+	// of each object is preserved.
+	if end > f.h.DataOffset {
+		alignment := 0
+		for i := range rds {
+			if a := objectAlignment(&rds[i]); rds[i].Used && a > alignment {
+				alignment = a
+			}
+		}
+
+		delta := nextAligned(end-f.h.DataOffset, alignment)
+
+		if err := f.moveData(f.h.DataOffset+delta, f.h.DataOffset, f.h.DataSize); err != nil {
+			return fmt.Errorf("%w", err)
+		}
+
+		for i := range rds {
+			if rds[i].Used {
+				rds[i].Offset += delta
+			}
+		}
+
+		f.h.DataOffset += delta
+	}
+
+	// Zero any space between the descriptor and data sections.
+	if _, err := f.rw.Seek(end, io.SeekStart); err != nil {
+		return fmt.Errorf("%w", err)
+	}
+
+	if _, err := io.CopyN(f.rw, zeroReader{}, f.h.DataOffset-end); err != nil {
+		return fmt.Errorf("%w", err)
+	}
+
+	f.rds = rds
+	f.h.DescriptorsFree += n - f.h.DescriptorsTotal
+	f.h.DescriptorsTotal = n
+	f.h.DescriptorsSize = rdsSize
+
+	if err := f.writeDescriptors(); err != nil {
+		return fmt.Errorf("%w", err)
+	}
+
+	f.h.ModifiedAt = so.t.Unix()
+
+	if err := f.writeHeader(); err != nil {
+		return fmt.Errorf("%w", err)
+	}
+
+	return nil
+}
@@ -56,6 +56,18 @@ func TestCreateContainer(t *testing.T) {
 			},
 			wantErr: errInsufficientCapacity,
 		},
+		{
+			name: "ErrObjectSizeMismatch",
+			opts: []CreateOpt{
+				OptCreateDeterministic(),
+				OptCreateWithDescriptors(
+					getDescriptorInput(t, DataGeneric, []byte{0xfa, 0xce},
+						OptObjectSize(3),
+					),
+				),
+			},
+			wantErr: errObjectSizeMismatch,
+		},
 		{
 			name: "Empty",
 			opts: []CreateOpt{
@@ -484,6 +496,55 @@ func TestDeleteObject(t *testing.T) {
 				OptDeleteCompact(true),
 			},
 		},
+		{
+			name: "CompactNotLast",
+			createOpts: []CreateOpt{
+				OptCreateDeterministic(),
+				OptCreateWithDescriptors(
+					getDescriptorInput(t, DataGeneric, []byte{0xfa, 0xce}),
+					getDescriptorInput(t, DataGeneric, []byte{0xfe, 0xed}),
+					getDescriptorInput(t, DataGeneric, []byte{0xde, 0xad, 0xbe, 0xef}),
+				),
+			},
+			id: 1,
+			opts: []DeleteOpt{
+				OptDeleteCompact(true),
+			},
+		},
+		{
+			name: "CompactNotLastAligned",
+			createOpts: []CreateOpt{
+				OptCreateDeterministic(),
+				OptCreateWithDescriptors(
+					getDescriptorInput(t, DataGeneric, []byte{0xfa, 0xce}),
+					getDescriptorInput(t, DataGeneric, []byte{0xfe, 0xed},
+						OptObjectAlignment(1024),
+					),
+					getDescriptorInput(t, DataPartition, []byte{0xde, 0xad, 0xbe, 0xef},
+						OptPartitionMetadata(FsSquash, PartPrimSys, "386"),
+					),
+				),
+			},
+			id: 2,
+			opts: []DeleteOpt{
+				OptDeleteCompact(true),
+			},
+		},
+		{
+			name: "ZeroCompactNotLast",
+			createOpts: []CreateOpt{
+				OptCreateDeterministic(),
+				OptCreateWithDescriptors(
+					getDescriptorInput(t, DataGeneric, []byte{0xfa, 0xce}),
+					getDescriptorInput(t, DataGeneric, []byte{0xfe, 0xed}),
+				),
+			},
+			id: 1,
+			opts: []DeleteOpt{
+				OptDeleteZero(true),
+				OptDeleteCompact(true),
+			},
+		},
 		{
 			name: "Deterministic",
 			createOpts: []CreateOpt{
@@ -611,6 +672,104 @@ func TestDeleteObjectAndAddObject(t *testing.T) {
 	}
 }
 
+func TestCompact(t *testing.T) {
+	tests := []struct {
+		name       string
+		createOpts []CreateOpt
+		deleteIDs  []uint32
+		opts       []CompactOpt
+	}{
+		{
+			name: "Empty",
+			createOpts: []CreateOpt{
+				OptCreateDeterministic(),
+			},
+		},
+		{
+			name: "NoGaps",
+			createOpts: []CreateOpt{
+				OptCreateDeterministic(),
+				OptCreateWithDescriptors(
+					getDescriptorInput(t, DataGeneric, []byte{0xfa, 0xce}),
+					getDescriptorInput(t, DataGeneric, []byte{0xfe, 0xed}),
+				),
+			},
+		},
+		{
+			name: "Gaps",
+			createOpts: []CreateOpt{
+				OptCreateDeterministic(),
+				OptCreateWithDescriptors(
+					getDescriptorInput(t, DataGeneric, []byte{0xfa, 0xce}),
+					getDescriptorInput(t, DataGeneric, []byte{0xfe, 0xed}),
+					getDescriptorInput(t, DataGeneric, []byte{0xde, 0xad}),
+					getDescriptorInput(t, DataGeneric, []byte{0xbe, 0xef}),
+				),
+			},
+			deleteIDs: []uint32{1, 3},
+		},
+		{
+			name: "GapsAligned",
+			createOpts: []CreateOpt{
+				OptCreateDeterministic(),
+				OptCreateWithDescriptors(
+					getDescriptorInput(t, DataGeneric, []byte{0xfa, 0xce}),
+					getDescriptorInput(t, DataPartition, []byte{0xfe, 0xed},
+						OptPartitionMetadata(FsSquash, PartPrimSys, "386"),
+					),
+					getDescriptorInput(t, DataGeneric, []byte{0xde, 0xad},
+						OptObjectAlignment(8192),
+					),
+					getDescriptorInput(t, DataGeneric, []byte{0xbe, 0xef}),
+				),
+			},
+			deleteIDs: []uint32{1, 2},
+		},
+		{
+			name: "WithTime",
+			createOpts: []CreateOpt{
+				OptCreateDeterministic(),
+				OptCreateWithDescriptors(
+					getDescriptorInput(t, DataGeneric, []byte{0xfa, 0xce}),
+					getDescriptorInput(t, DataGeneric, []byte{0xfe, 0xed}),
+				),
+			},
+			deleteIDs: []uint32{1},
+			opts: []CompactOpt{
+				OptCompactWithTime(time.Unix(946702800, 0)),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var b Buffer
+
+			f, err := CreateContainer(&b, tt.createOpts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for _, id := range tt.deleteIDs {
+				if err := f.DeleteObject(id); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if err := f.Compact(tt.opts...); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := f.UnloadContainer(); err != nil {
+				t.Error(err)
+			}
+
+			g := goldie.New(t, goldie.WithTestNameForDir(true))
+			g.Assert(t, tt.name, b.Bytes())
+		})
+	}
+}
+
 func TestSetPrimPart(t *testing.T) {
 	tests := []struct {
 		name       string
@@ -793,3 +952,148 @@ func TestSetMetadata(t *testing.T) {
 		})
 	}
 }
+
+func TestSetDescriptorCapacity(t *testing.T) {
+	tests := []struct {
+		name       string
+		createOpts []CreateOpt
+		n          int64
+		opts       []SetOpt
+		wantErr    error
+	}{
+		{
+			name: "ErrDescriptorCapacity",
+			createOpts: []CreateOpt{
+				OptCreateDeterministic(),
+				OptCreateWithDescriptors(
+					getDescriptorInput(t, DataGeneric, []byte{0xfa, 0xce}),
+					getDescriptorInput(t, DataGeneric, []byte{0xfe, 0xed}),
+				),
+			},
+			n:       1,
+			wantErr: errDescriptorCapacity,
+		},
+		{
+			name: "Deterministic",
+			createOpts: []CreateOpt{
+				OptCreateWithID("de170c43-36ab-44a8-bca9-1ea1a070a274"),
+				OptCreateWithDescriptorCapacity(1),
+				OptCreateWithTime(time.Unix(946702800, 0)),
+			},
+			n: 2,
+			opts: []SetOpt{
+				OptSetDeterministic(),
+			},
+		},
+		{
+			name: "WithTime",
+			createOpts: []CreateOpt{
+				OptCreateDeterministic(),
+				OptCreateWithDescriptorCapacity(1),
+			},
+			n: 2,
+			opts: []SetOpt{
+				OptSetWithTime(time.Unix(946702800, 0)),
+			},
+		},
+		{
+			name: "GrowEmpty",
+			createOpts: []CreateOpt{
+				OptCreateDeterministic(),
+				OptCreateWithDescriptorCapacity(1),
+			},
+			n: 4,
+		},
+		{
+			name: "Grow",
+			createOpts: []CreateOpt{
+				OptCreateDeterministic(),
+				OptCreateWithDescriptorCapacity(2),
+				OptCreateWithDescriptors(
+					getDescriptorInput(t, DataGeneric, []byte{0xfa, 0xce}),
+					getDescriptorInput(t, DataPartition, []byte{0xfe, 0xed},
+						OptPartitionMetadata(FsSquash, PartPrimSys, "386"),
+					),
+				),
+			},
+			n: 16,
+		},
+		{
+			name: "Shrink",
+			createOpts: []CreateOpt{
+				OptCreateDeterministic(),
+				OptCreateWithDescriptors(
+					getDescriptorInput(t, DataGeneric, []byte{0xfa, 0xce}),
+				),
+			},
+			n: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var b Buffer
+
+			f, err := CreateContainer(&b, tt.createOpts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got, want := f.SetDescriptorCapacity(tt.n, tt.opts...), tt.wantErr; !errors.Is(got, want) {
+				t.Errorf("got error %v, want %v", got, want)
+			}
+
+			if err := f.UnloadContainer(); err != nil {
+				t.Error(err)
+			}
+
+			g := goldie.New(t, goldie.WithTestNameForDir(true))
+			g.Assert(t, tt.name, b.Bytes())
+		})
+	}
+}
+
+func TestSetDescriptorCapacityAndAddObject(t *testing.T) {
+	var b Buffer
+
+	f, err := CreateContainer(&b,
+		OptCreateDeterministic(),
+		OptCreateWithDescriptorCapacity(1),
+		OptCreateWithDescriptors(
+			getDescriptorInput(t, DataGeneric, []byte("abc")),
+		),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	di := getDescriptorInput(t, DataGeneric, []byte("def"))
+
+	if got, want := f.AddObject(di), errInsufficientCapacity; !errors.Is(got, want) {
+		t.Fatalf("got error %v, want %v", got, want)
+	}
+
+	if err := f.SetDescriptorCapacity(2); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := f.AddObject(getDescriptorInput(t, DataGeneric, []byte("def"))); err != nil {
+		t.Fatal(err)
+	}
+
+	for i, want := range []string{"abc", "def"} {
+		d, err := f.GetDescriptor(WithID(uint32(i + 1)))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		b, err := d.GetData()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if got := string(b); got != want {
+			t.Errorf("got data %q, want %q", got, want)
+		}
+	}
+}
@@ -22,6 +22,7 @@ type descriptorOpts struct {
 	name      string
 	md        encoding.BinaryMarshaler
 	t         time.Time
+	size      int64
 }
 
 // DescriptorInputOpt are used to specify data object options.
@@ -77,6 +78,18 @@ func OptObjectAlignment(n int) DescriptorInputOpt {
 	}
 }
 
+// OptObjectSize specifies n as the size of the data object, in bytes. If the data object does not
+// contain exactly n bytes, an error is returned when it is written.
+func OptObjectSize(n int64) DescriptorInputOpt {
+	return func(_ DataType, opts *descriptorOpts) error {
+		if n < 0 {
+			return errNegativeSize
+		}
+		opts.size = n
+		return nil
+	}
+}
+
 // OptObjectName specifies name as the data object name.
 func OptObjectName(name string) DescriptorInputOpt {
 	return func(_ DataType, opts *descriptorOpts) error {
@@ -101,6 +114,8 @@ func OptMetadata(md encoding.BinaryMarshaler) DescriptorInputOpt {
 	}
 }
 
+var errNegativeSize = errors.New("negative size")
+
 type unexpectedDataTypeError struct {
 	got  DataType
 	want []DataType
@@ -280,12 +295,17 @@ const DefaultObjectGroup = 1
 //
 // By default, no name is set for data object. To set a name, use OptObjectName.
 //
+// By default, the size of the data object is determined by reading r until EOF. If the size is
+// known in advance, consider specifying it using OptObjectSize. This is recommended when using
+// WriteContainer, which must otherwise buffer the data object to determine its size.
+//
 // When creating a new image, data object creation/modification times are set to the image creation
 // time. When modifying an existing image, the data object creation/modification time is set to the
 // image modification time. To override this behavior, consider using OptObjectTime.
 func NewDescriptorInput(t DataType, r io.Reader, opts ...DescriptorInputOpt) (DescriptorInput, error) {
 	dopts := descriptorOpts{
 		groupID: DefaultObjectGroup,
+		size:    -1,
 	}
 
 	if t == DataPartition {
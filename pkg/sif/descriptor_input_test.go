@@ -104,6 +104,21 @@ func TestNewDescriptorInput(t *testing.T) {
 				OptObjectAlignment(8),
 			},
 		},
+		{
+			name: "OptObjectSize",
+			t:    DataGeneric,
+			opts: []DescriptorInputOpt{
+				OptObjectSize(2),
+			},
+		},
+		{
+			name: "OptObjectSizeNegative",
+			t:    DataGeneric,
+			opts: []DescriptorInputOpt{
+				OptObjectSize(-1),
+			},
+			wantErr: errNegativeSize,
+		},
 		{
 			name: "OptObjectName",
 			t:    DataGeneric,
@@ -1,4 +1,4 @@
-// Copyright (c) 2018-2021, Sylabs Inc. All rights reserved.
+// Copyright (c) 2018-2023, Sylabs Inc. All rights reserved.
 // Copyright (c) 2017, SingularityWare, LLC. All rights reserved.
 // Copyright (c) 2017, Yannick Cote <yhcote@gmail.com> All rights reserved.
 // This software is licensed under a 3-clause BSD license. Please consult the
@@ -8,6 +8,7 @@
 package sif
 
 import (
+	"context"
 	"encoding/binary"
 	"errors"
 	"fmt"
@@ -82,6 +83,9 @@ func loadContainer(rw ReadWriter) (*FileImage, error) {
 type loadOpts struct {
 	flag          int
 	closeOnUnload bool
+	atomic        bool
+	lock          bool
+	lockCtx       context.Context //nolint:containedctx
 }
 
 // LoadOpt are used to specify container loading options.
@@ -104,16 +108,70 @@ func OptLoadWithCloseOnUnload(b bool) LoadOpt {
 	}
 }
 
+// OptLoadAtomic specifies whether modifications to the container file should be made atomically.
+// If set, modifications are made to a temporary copy of the file, which replaces the original file
+// when UnloadContainer is called. If the process is interrupted, or an error is encountered while
+// modifying the image, the original file is left unmodified. In the latter case, subsequent
+// modifications return an error, and UnloadContainer returns an error. Errors encountered by a
+// modification that is rolled back, such as a failed FileImage.Update, are not retained.
+//
+// This option is only applicable to LoadContainerFromPath, and is ignored if the file is opened
+// read-only. Note that the temporary copy requires additional storage equal to the size of the
+// file.
+//
+// The temporary copy is created in the same directory as the file, with a name of the form
+// ".<name>.<random>". If the process is terminated before UnloadContainer is called, the temporary
+// copy is not removed, and may be deleted once no process is modifying the image.
+func OptLoadAtomic(b bool) LoadOpt {
+	return func(lo *loadOpts) error {
+		lo.atomic = b
+		return nil
+	}
+}
+
+// OptLoadWithLock specifies whether an advisory lock should be acquired on the container file. A
+// shared lock is acquired if the file is opened read-only, and an exclusive lock otherwise. The
+// lock is released by UnloadContainer.
+//
+// By default, a lock is acquired, and an error wrapping ErrLocked is returned immediately if a
+// conflicting lock is held by another process. To wait for the lock, consider using
+// OptLoadWithLockContext.
+//
+// This option is only applicable to LoadContainerFromPath. Locking is not supported on all
+// platforms; where unsupported, no lock is acquired.
+func OptLoadWithLock(b bool) LoadOpt {
+	return func(lo *loadOpts) error {
+		lo.lock = b
+		return nil
+	}
+}
+
+// OptLoadWithLockContext specifies that if a conflicting lock is held on the container file, the
+// lock should be waited for until it is acquired, or ctx is done. See OptLoadWithLock for details.
+func OptLoadWithLockContext(ctx context.Context) LoadOpt {
+	return func(lo *loadOpts) error {
+		lo.lock = true
+		lo.lockCtx = ctx
+		return nil
+	}
+}
+
 // LoadContainerFromPath loads a new SIF container from path, according to opts.
 //
 // On success, a FileImage is returned. The caller must call UnloadContainer to ensure resources
 // are released.
 //
 // By default, the file is opened for read and write access. To change this behavior, consider
-// using OptLoadWithFlag.
+// using OptLoadWithFlag. To make modifications to the file atomically, consider using
+// OptLoadAtomic.
+//
+// By default, an advisory lock is acquired on the file, so that concurrent modification by other
+// processes is prevented. To change this behavior, consider using OptLoadWithLock or
+// OptLoadWithLockContext.
 func LoadContainerFromPath(path string, opts ...LoadOpt) (*FileImage, error) {
 	lo := loadOpts{
 		flag: os.O_RDWR,
+		lock: true,
 	}
 
 	for _, opt := range opts {
@@ -122,9 +180,34 @@ func LoadContainerFromPath(path string, opts ...LoadOpt) (*FileImage, error) {
 		}
 	}
 
-	fp, err := os.OpenFile(path, lo.flag, 0)
-	if err != nil {
-		return nil, fmt.Errorf("%w", err)
+	var fp interface {
+		ReadWriter
+		io.Closer
+	}
+
+	writable := lo.flag&(os.O_WRONLY|os.O_RDWR) != 0
+
+	switch {
+	case lo.atomic && writable:
+		a, err := openAtomic(lo.lockCtx, path, lo.lock)
+		if err != nil {
+			return nil, fmt.Errorf("%w", err)
+		}
+		fp = a
+
+	case lo.lock:
+		f, err := openLocked(lo.lockCtx, path, lo.flag, 0, writable)
+		if err != nil {
+			return nil, fmt.Errorf("%w", err)
+		}
+		fp = f
+
+	default:
+		f, err := os.OpenFile(path, lo.flag, 0)
+		if err != nil {
+			return nil, fmt.Errorf("%w", err)
+		}
+		fp = f
 	}
 
 	f, err := loadContainer(fp)
@@ -163,8 +246,67 @@ func LoadContainer(rw ReadWriter, opts ...LoadOpt) (*FileImage, error) {
 	return f, nil
 }
 
+// ErrReadOnly is the error returned when attempting to modify an image that was loaded without
+// write capability.
+var ErrReadOnly = errors.New("image is read-only")
+
+// readOnlyStorage provides a read-only ReadWriter backed by an io.ReaderAt. Attempts to write,
+// seek, or truncate return ErrReadOnly.
+type readOnlyStorage struct {
+	*io.SectionReader
+	r io.ReaderAt
+}
+
+func (readOnlyStorage) Write([]byte) (int, error) { return 0, ErrReadOnly }
+
+func (readOnlyStorage) Seek(int64, int) (int64, error) { return 0, ErrReadOnly }
+
+func (readOnlyStorage) Truncate(int64) error { return ErrReadOnly }
+
+// Close closes the underlying io.ReaderAt, if it implements the io.Closer interface.
+func (s readOnlyStorage) Close() error {
+	if c, ok := s.r.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+// LoadContainerReader loads a new SIF container from the first size bytes of r, according to
+// opts. The returned FileImage may be used to inspect the image, but methods that modify the
+// image return ErrReadOnly.
+//
+// On success, a FileImage is returned. The caller must call UnloadContainer to ensure resources
+// are released. By default, UnloadContainer will close r if it implements the io.Closer
+// interface. To change this behavior, consider using OptLoadWithCloseOnUnload.
+func LoadContainerReader(r io.ReaderAt, size int64, opts ...LoadOpt) (*FileImage, error) {
+	lo := loadOpts{
+		closeOnUnload: true,
+	}
+
+	for _, opt := range opts {
+		if err := opt(&lo); err != nil {
+			return nil, fmt.Errorf("%w", err)
+		}
+	}
+
+	f, err := loadContainer(readOnlyStorage{io.NewSectionReader(r, 0, size), r})
+	if err != nil {
+		return nil, fmt.Errorf("%w", err)
+	}
+
+	f.readOnly = true
+	f.closeOnUnload = lo.closeOnUnload
+	return f, nil
+}
+
 // UnloadContainer unloads f, releasing associated resources.
 func (f *FileImage) UnloadContainer() error {
+	f.wmu.Lock()
+	defer f.wmu.Unlock()
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
 	if c, ok := f.rw.(io.Closer); ok && f.closeOnUnload {
 		if err := c.Close(); err != nil {
 			return fmt.Errorf("%w", err)
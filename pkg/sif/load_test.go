@@ -1,4 +1,4 @@
-// Copyright (c) 2018-2021, Sylabs Inc. All rights reserved.
+// Copyright (c) 2018-2023, Sylabs Inc. All rights reserved.
 // This software is licensed under a 3-clause BSD license. Please consult the
 // LICENSE file distributed with the sources of this project regarding your
 // rights to use or distribute this software.
@@ -6,9 +6,13 @@
 package sif
 
 import (
+	"bytes"
+	"errors"
+	"io"
 	"os"
 	"path/filepath"
 	"testing"
+	"testing/iotest"
 )
 
 func TestLoadContainerFromPath(t *testing.T) {
@@ -48,6 +52,218 @@ func TestLoadContainerFromPath(t *testing.T) {
 	}
 }
 
+func TestLoadContainerFromPath_Atomic(t *testing.T) {
+	b, err := os.ReadFile(filepath.Join(corpus, "one-group.sif"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		flag     int
+		fn       func(t *testing.T, f *FileImage)
+		modified bool
+		wantErr  error
+	}{
+		{
+			name: "Unmodified",
+			flag: os.O_RDWR,
+		},
+		{
+			name: "AddObject",
+			flag: os.O_RDWR,
+			fn: func(t *testing.T, f *FileImage) {
+				if err := f.AddObject(
+					getDescriptorInput(t, DataGeneric, []byte{0xfa, 0xce}),
+					OptAddDeterministic(),
+				); err != nil {
+					t.Fatal(err)
+				}
+			},
+			modified: true,
+		},
+		{
+			name: "DeleteObject",
+			flag: os.O_RDWR,
+			fn: func(t *testing.T, f *FileImage) {
+				if err := f.DeleteObject(1, OptDeleteCompact(true), OptDeleteDeterministic()); err != nil {
+					t.Fatal(err)
+				}
+			},
+			modified: true,
+		},
+		{
+			name: "WriteError",
+			flag: os.O_RDWR,
+			fn: func(t *testing.T, f *FileImage) {
+				if err := f.SetMetadata(1, newOCIBlobDigest(), OptSetDeterministic()); err != nil {
+					t.Fatal(err)
+				}
+
+				a, ok := f.rw.(*atomicFile)
+				if !ok {
+					t.Fatal("unexpected storage for atomic image")
+				}
+
+				// Simulate a failure part way through a modification.
+				a.record(errors.New("write error"))
+			},
+			wantErr: errAtomicDiscarded,
+		},
+		{
+			name: "AddObjectReadError",
+			flag: os.O_RDWR,
+			fn: func(t *testing.T, f *FileImage) {
+				errRead := errors.New("read error")
+
+				// The data object is partially written before the read error is encountered.
+				di, err := NewDescriptorInput(DataGeneric, io.MultiReader(
+					bytes.NewReader([]byte{0xfa, 0xce}),
+					iotest.ErrReader(errRead),
+				))
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				if err := f.AddObject(di, OptAddDeterministic()); !errors.Is(err, errRead) {
+					t.Fatalf("got error %v, want %v", err, errRead)
+				}
+			},
+			wantErr: errAtomicDiscarded,
+		},
+		{
+			name: "AddObjectAfterError",
+			flag: os.O_RDWR,
+			fn: func(t *testing.T, f *FileImage) {
+				errRead := errors.New("read error")
+
+				di, err := NewDescriptorInput(DataGeneric, iotest.ErrReader(errRead))
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				if err := f.AddObject(di, OptAddDeterministic()); !errors.Is(err, errRead) {
+					t.Fatalf("got error %v, want %v", err, errRead)
+				}
+
+				// Subsequent modifications must not be reported as successful.
+				if err := f.AddObject(
+					getDescriptorInput(t, DataGeneric, []byte{0xfa, 0xce}),
+					OptAddDeterministic(),
+				); !errors.Is(err, errAtomicDiscarded) {
+					t.Fatalf("got error %v, want %v", err, errAtomicDiscarded)
+				}
+			},
+			wantErr: errAtomicDiscarded,
+		},
+		{
+			name: "UpdateRolledBack",
+			flag: os.O_RDWR,
+			fn: func(t *testing.T, f *FileImage) {
+				errRead := errors.New("read error")
+
+				di, err := NewDescriptorInput(DataGeneric, io.MultiReader(
+					bytes.NewReader([]byte{0xfa, 0xce}),
+					iotest.ErrReader(errRead),
+				))
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				if err := f.Update(func(tx *Tx) error {
+					_, err := tx.AddObject(di)
+					return err
+				}, OptUpdateDeterministic()); !errors.Is(err, errRead) {
+					t.Fatalf("got error %v, want %v", err, errRead)
+				}
+
+				// The failed update was rolled back, so subsequent modifications must be retained.
+				if err := f.AddObject(
+					getDescriptorInput(t, DataGeneric, []byte{0xfe, 0xed}),
+					OptAddDeterministic(),
+				); err != nil {
+					t.Fatal(err)
+				}
+			},
+			modified: true,
+		},
+		{
+			name: "ReadOnly",
+			flag: os.O_RDONLY,
+			fn: func(t *testing.T, f *FileImage) {
+				if _, ok := f.rw.(*atomicFile); ok {
+					t.Error("unexpected atomic storage for read-only image")
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "image.sif")
+
+			if err := os.WriteFile(path, b, 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			// Apply the same modifications to an in-memory copy of the image, for comparison.
+			want := NewBuffer(bytes.Clone(b))
+
+			if tt.modified {
+				f, err := LoadContainer(want)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				tt.fn(t, f)
+			}
+
+			f, err := LoadContainerFromPath(path, OptLoadWithFlag(tt.flag), OptLoadAtomic(true))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if tt.fn != nil {
+				tt.fn(t, f)
+			}
+
+			// The original image must not be modified prior to unload.
+			got, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !bytes.Equal(got, b) {
+				t.Error("image modified prior to unload")
+			}
+
+			if got, want := f.UnloadContainer(), tt.wantErr; !errors.Is(got, want) {
+				t.Errorf("got error %v, want %v", got, want)
+			}
+
+			if got, err = os.ReadFile(path); err != nil {
+				t.Fatal(err)
+			}
+
+			if !bytes.Equal(got, want.Bytes()) {
+				t.Error("unexpected image contents following unload")
+			}
+
+			// The temporary copy must be removed.
+			des, err := os.ReadDir(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got, want := len(des), 1; got != want {
+				t.Errorf("got %v directory entries, want %v", got, want)
+			}
+		})
+	}
+}
+
 func TestLoadContainer(t *testing.T) {
 	tests := []struct {
 		name string
@@ -87,6 +303,126 @@ func TestLoadContainer(t *testing.T) {
 	}
 }
 
+func TestLoadContainerReader(t *testing.T) {
+	b, err := os.ReadFile(filepath.Join(corpus, "one-group.sif"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		size    int64
+		opts    []LoadOpt
+		wantErr bool
+	}{
+		{
+			name: "NoOpts",
+			size: int64(len(b)),
+		},
+		{
+			name: "NoCloseOnUnload",
+			size: int64(len(b)),
+			opts: []LoadOpt{OptLoadWithCloseOnUnload(false)},
+		},
+		{
+			name:    "Truncated",
+			size:    4096,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := LoadContainerReader(bytes.NewReader(b), tt.size, tt.opts...)
+			if got, want := err != nil, tt.wantErr; got != want {
+				t.Fatalf("got error %v, wantErr %v", err, want)
+			}
+
+			if err == nil {
+				if got, want := f.DescriptorsTotal(), int64(48); got != want {
+					t.Errorf("got %v descriptors, want %v", got, want)
+				}
+
+				if err := f.UnloadContainer(); err != nil {
+					t.Errorf("failed to unload container: %v", err)
+				}
+			}
+		})
+	}
+}
+
+func TestLoadContainerReaderReadOnly(t *testing.T) {
+	b, err := os.ReadFile(filepath.Join(corpus, "one-group.sif"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := LoadContainerReader(bytes.NewReader(b), int64(len(b)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := f.UnloadContainer(); err != nil {
+			t.Error(err)
+		}
+	})
+
+	d, err := f.GetDescriptor(WithID(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := d.GetData(); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		fn   func() error
+	}{
+		{
+			name: "AddObject",
+			fn: func() error {
+				return f.AddObject(getDescriptorInput(t, DataGeneric, []byte{0xfa, 0xce}))
+			},
+		},
+		{
+			name: "DeleteObject",
+			fn:   func() error { return f.DeleteObject(1) },
+		},
+		{
+			name: "Compact",
+			fn:   func() error { return f.Compact() },
+		},
+		{
+			name: "SetPrimPart",
+			fn:   func() error { return f.SetPrimPart(1) },
+		},
+		{
+			name: "SetMetadata",
+			fn:   func() error { return f.SetMetadata(1, newOCIBlobDigest()) },
+		},
+		{
+			name: "SetDescriptorCapacity",
+			fn:   func() error { return f.SetDescriptorCapacity(64) },
+		},
+		{
+			name: "Update",
+			fn:   func() error { return f.Update(func(*Tx) error { return nil }) },
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			if got, want := tt.fn(), ErrReadOnly; !errors.Is(got, want) {
+				t.Errorf("got error %v, want %v", got, want)
+			}
+		})
+	}
+}
+
 func TestLoadContainerFpMock(t *testing.T) {
 	// This test is using mockSifReadWriter to verify that the code
 	// is not making assumptions regading the behavior of the
@@ -0,0 +1,91 @@
+// Copyright (c) 2023, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package sif
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+// ErrLocked is the error returned when an image cannot be locked, because a conflicting lock is
+// held by another process.
+var ErrLocked = errors.New("image is locked")
+
+// lockPollInterval is the interval between attempts to acquire a lock when waiting.
+const lockPollInterval = 100 * time.Millisecond
+
+// lockFile acquires an advisory lock on fp. If exclusive is true, an exclusive lock is acquired,
+// otherwise a shared lock is acquired.
+//
+// If the lock is held by another process and ctx is nil, ErrLocked is returned immediately.
+// Otherwise, lockFile waits until the lock is acquired or ctx is done.
+func lockFile(ctx context.Context, fp *os.File, exclusive bool) error {
+	for {
+		err := tryLockFile(fp, exclusive)
+		if ctx == nil || !errors.Is(err, ErrLocked) {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%w: %w", ErrLocked, ctx.Err())
+		case <-time.After(lockPollInterval):
+		}
+	}
+}
+
+// isFileAtPath returns true if path refers to the same file as fp.
+func isFileAtPath(fp *os.File, path string) (bool, error) {
+	fi, err := fp.Stat()
+	if err != nil {
+		return false, err
+	}
+
+	pi, err := os.Stat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	return os.SameFile(fi, pi), nil
+}
+
+// openLocked opens the file at path using flag and perm, and acquires an advisory lock on it. If
+// exclusive is true, an exclusive lock is acquired, otherwise a shared lock is acquired. See
+// lockFile for details of how ctx is used.
+//
+// The lock is released when the returned file is closed.
+func openLocked(ctx context.Context, path string, flag int, perm os.FileMode, exclusive bool) (*os.File, error) { //nolint:lll
+	for {
+		fp, err := os.OpenFile(path, flag, perm)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := lockFile(ctx, fp, exclusive); err != nil {
+			fp.Close()
+			return nil, err
+		}
+
+		// While waiting for the lock, the file may have been replaced (for example, by an atomic
+		// modification) or removed. If so, the lock is of no use, so try again.
+		ok, err := isFileAtPath(fp, path)
+		if err != nil {
+			fp.Close()
+			return nil, err
+		}
+
+		if ok {
+			return fp, nil
+		}
+
+		fp.Close()
+	}
+}
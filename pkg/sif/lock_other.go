@@ -0,0 +1,15 @@
+// Copyright (c) 2023, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+//go:build !(darwin || dragonfly || freebsd || linux || netbsd || openbsd)
+
+package sif
+
+import "os"
+
+// tryLockFile is a no-op on platforms that do not support flock(2).
+func tryLockFile(*os.File, bool) error {
+	return nil
+}
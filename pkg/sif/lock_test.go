@@ -0,0 +1,233 @@
+// Copyright (c) 2023, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+
+package sif
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// copyTestImage copies the named image from the corpus to a temporary directory, and returns its
+// path.
+func copyTestImage(t *testing.T, name string) string {
+	t.Helper()
+
+	b, err := os.ReadFile(filepath.Join(corpus, name))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), name)
+
+	if err := os.WriteFile(path, b, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+func TestLoadContainerFromPath_Lock(t *testing.T) {
+	tests := []struct {
+		name     string
+		heldFlag int
+		flag     int
+		opts     []LoadOpt
+		wantErr  error
+	}{
+		{
+			name:     "SharedShared",
+			heldFlag: os.O_RDONLY,
+			flag:     os.O_RDONLY,
+		},
+		{
+			name:     "SharedExclusive",
+			heldFlag: os.O_RDONLY,
+			flag:     os.O_RDWR,
+			wantErr:  ErrLocked,
+		},
+		{
+			name:     "ExclusiveShared",
+			heldFlag: os.O_RDWR,
+			flag:     os.O_RDONLY,
+			wantErr:  ErrLocked,
+		},
+		{
+			name:     "ExclusiveExclusive",
+			heldFlag: os.O_RDWR,
+			flag:     os.O_RDWR,
+			wantErr:  ErrLocked,
+		},
+		{
+			name:     "ExclusiveAtomic",
+			heldFlag: os.O_RDWR,
+			flag:     os.O_RDWR,
+			opts:     []LoadOpt{OptLoadAtomic(true)},
+			wantErr:  ErrLocked,
+		},
+		{
+			name:     "NoLock",
+			heldFlag: os.O_RDWR,
+			flag:     os.O_RDWR,
+			opts:     []LoadOpt{OptLoadWithLock(false)},
+		},
+		{
+			name:     "ContextDeadline",
+			heldFlag: os.O_RDWR,
+			flag:     os.O_RDWR,
+			opts: []LoadOpt{
+				OptLoadWithLockContext(func() context.Context {
+					ctx, cancel := context.WithTimeout(context.Background(), 2*lockPollInterval)
+					t.Cleanup(cancel)
+					return ctx
+				}()),
+			},
+			wantErr: context.DeadlineExceeded,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			path := copyTestImage(t, "one-group.sif")
+
+			held, err := LoadContainerFromPath(path, OptLoadWithFlag(tt.heldFlag))
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Cleanup(func() {
+				if err := held.UnloadContainer(); err != nil {
+					t.Error(err)
+				}
+			})
+
+			f, err := LoadContainerFromPath(path, append(tt.opts, OptLoadWithFlag(tt.flag))...)
+			if got, want := err, tt.wantErr; !errors.Is(got, want) {
+				t.Fatalf("got error %v, want %v", got, want)
+			}
+
+			if err == nil {
+				if err := f.UnloadContainer(); err != nil {
+					t.Error(err)
+				}
+			}
+		})
+	}
+}
+
+func TestLoadContainerFromPath_LockWait(t *testing.T) {
+	path := copyTestImage(t, "one-group.sif")
+
+	held, err := LoadContainerFromPath(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	time.AfterFunc(2*lockPollInterval, func() {
+		if err := held.UnloadContainer(); err != nil {
+			t.Error(err)
+		}
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	f, err := LoadContainerFromPath(path, OptLoadWithLockContext(ctx))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := f.UnloadContainer(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestLoadContainerFromPath_LockWaitAtomic(t *testing.T) {
+	path := copyTestImage(t, "one-group.sif")
+
+	held, err := LoadContainerFromPath(path, OptLoadAtomic(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := held.AddObject(getDescriptorInput(t, DataGeneric, []byte{0xfa, 0xce})); err != nil {
+		t.Fatal(err)
+	}
+
+	// Once the lock is released, the original file has been replaced. The waiter must load the
+	// replacement, rather than the original.
+	time.AfterFunc(2*lockPollInterval, func() {
+		if err := held.UnloadContainer(); err != nil {
+			t.Error(err)
+		}
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	f, err := LoadContainerFromPath(path, OptLoadWithLockContext(ctx), OptLoadWithFlag(os.O_RDONLY))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := f.UnloadContainer(); err != nil {
+			t.Error(err)
+		}
+	})
+
+	if got, want := f.DescriptorsFree(), held.DescriptorsFree(); got != want {
+		t.Errorf("got %v free descriptors, want %v", got, want)
+	}
+}
+
+func TestCreateContainerAtPath_Lock(t *testing.T) {
+	path := copyTestImage(t, "one-group.sif")
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	held, err := LoadContainerFromPath(path, OptLoadWithFlag(os.O_RDONLY))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := held.UnloadContainer(); err != nil {
+			t.Error(err)
+		}
+	})
+
+	if _, err := CreateContainerAtPath(path); !errors.Is(err, ErrLocked) {
+		t.Fatalf("got error %v, want %v", err, ErrLocked)
+	}
+
+	// The locked file must not be truncated, or removed.
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(got, b) {
+		t.Error("locked file modified")
+	}
+
+	f, err := CreateContainerAtPath(path, OptCreateWithLock(false))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := f.UnloadContainer(); err != nil {
+		t.Error(err)
+	}
+}
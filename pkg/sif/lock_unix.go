@@ -0,0 +1,37 @@
+// Copyright (c) 2023, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+
+package sif
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+// tryLockFile attempts to acquire an advisory lock on fp using flock(2), without blocking. If
+// exclusive is true, an exclusive lock is requested, otherwise a shared lock is requested. If a
+// conflicting lock is held, ErrLocked is returned.
+func tryLockFile(fp *os.File, exclusive bool) error {
+	how := syscall.LOCK_SH
+	if exclusive {
+		how = syscall.LOCK_EX
+	}
+
+	for {
+		err := syscall.Flock(int(fp.Fd()), how|syscall.LOCK_NB)
+		if errors.Is(err, syscall.EINTR) {
+			continue
+		}
+
+		if errors.Is(err, syscall.EWOULDBLOCK) {
+			return ErrLocked
+		}
+
+		return err
+	}
+}
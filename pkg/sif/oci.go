@@ -0,0 +1,370 @@
+// Copyright (c) 2023, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package sif
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+)
+
+// OCIBlobDigestError records a mismatch between the digest recorded in the descriptor of an OCI
+// root index or blob object, and the digest of its data.
+type OCIBlobDigestError struct {
+	ID       uint32  // Data object ID.
+	Expected v1.Hash // Digest recorded in descriptor.
+	Actual   v1.Hash // Digest of data object.
+}
+
+func (e *OCIBlobDigestError) Error() string {
+	if e.ID == 0 {
+		return "OCI blob digest mismatch"
+	}
+	return fmt.Sprintf("OCI blob digest mismatch: object %v: got %v, want %v",
+		e.ID, e.Actual, e.Expected)
+}
+
+// Is compares e against target. If target is a OCIBlobDigestError and matches e or target has a
+// zero value ID, true is returned.
+func (e *OCIBlobDigestError) Is(target error) bool {
+	t, ok := target.(*OCIBlobDigestError)
+	if !ok {
+		return false
+	}
+	return e.ID == t.ID || t.ID == 0
+}
+
+// checkOCIBlob computes the digest of the data object described by d, and compares it against the
+// digest recorded in d.
+func checkOCIBlob(d Descriptor) error {
+	want, err := d.OCIBlobDigest()
+	if err != nil {
+		return err
+	}
+
+	h, err := v1.Hasher(want.Algorithm)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(h, d.GetReader()); err != nil {
+		return err
+	}
+
+	got := v1.Hash{Algorithm: want.Algorithm, Hex: fmt.Sprintf("%x", h.Sum(nil))}
+	if got != want {
+		return &OCIBlobDigestError{ID: d.ID(), Expected: want, Actual: got}
+	}
+
+	return nil
+}
+
+// CheckOCIBlobs computes the digest of each OCI root index and blob object in f, and compares it
+// against the digest recorded in the object descriptor when the object was written.
+//
+// If the digest of one or more objects does not match, an error is returned that wraps an
+// OCIBlobDigestError for each such object. These may be examined using errors.As, or by unwrapping
+// the returned error.
+func (f *FileImage) CheckOCIBlobs() error {
+	// Hash objects from a snapshot of the descriptors, so that modification of f is not blocked
+	// while object data is read.
+	ds, err := f.snapshotDescriptors()
+	if errors.Is(err, ErrNoObjects) {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("%w", err)
+	}
+
+	var errs []error
+
+	for _, d := range ds {
+		if d.DataType() != DataOCIRootIndex && d.DataType() != DataOCIBlob {
+			continue
+		}
+
+		err := checkOCIBlob(d)
+
+		var de *OCIBlobDigestError
+		if errors.As(err, &de) {
+			errs = append(errs, err)
+			continue
+		}
+
+		if err != nil {
+			return fmt.Errorf("%w", err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// ociRootIndexOpts accumulates OCI root index replacement options.
+type ociRootIndexOpts struct {
+	compact bool
+	t       time.Time
+}
+
+// OCIRootIndexOpt are used to specify OCI root index replacement options.
+type OCIRootIndexOpt func(*ociRootIndexOpts) error
+
+// OptOCIRootIndexCompact specifies whether the image should be compacted following the deletion
+// of unreachable blobs.
+func OptOCIRootIndexCompact(b bool) OCIRootIndexOpt {
+	return func(oo *ociRootIndexOpts) error {
+		oo.compact = b
+		return nil
+	}
+}
+
+// OptOCIRootIndexDeterministic sets header/descriptor fields to values that support
+// deterministic modification of images.
+func OptOCIRootIndexDeterministic() OCIRootIndexOpt {
+	return func(oo *ociRootIndexOpts) error {
+		oo.t = time.Time{}
+		return nil
+	}
+}
+
+// OptOCIRootIndexWithTime specifies t as the image modification time.
+func OptOCIRootIndexWithTime(t time.Time) OCIRootIndexOpt {
+	return func(oo *ociRootIndexOpts) error {
+		oo.t = t
+		return nil
+	}
+}
+
+// getOCIBlob returns a pointer to the descriptor of the OCI blob with digest h.
+func (f *FileImage) getOCIBlob(h v1.Hash) (*rawDescriptor, error) {
+	d, err := f.getDescriptor(WithDataType(DataOCIBlob), WithOCIBlobDigest(h))
+	if err != nil {
+		return nil, fmt.Errorf("blob %v: %w", h, err)
+	}
+	return d, nil
+}
+
+// reachableOCIBlobs returns the digests of the OCI blobs reachable from im. Nested indexes and
+// image manifests referenced by im are read from f and walked recursively. Blobs that are not
+// index or image manifests, such as configs and layers, are not required to be present in f.
+func (f *FileImage) reachableOCIBlobs(im *v1.IndexManifest) (map[v1.Hash]bool, error) {
+	reachable := make(map[v1.Hash]bool)
+
+	var walk func(descs []v1.Descriptor) error
+
+	walk = func(descs []v1.Descriptor) error {
+		for _, desc := range descs {
+			if reachable[desc.Digest] {
+				continue
+			}
+			reachable[desc.Digest] = true
+
+			if !desc.MediaType.IsIndex() && !desc.MediaType.IsImage() {
+				continue
+			}
+
+			rd, err := f.getOCIBlob(desc.Digest)
+			if err != nil {
+				return err
+			}
+
+			r := io.NewSectionReader(f.rw, rd.Offset, rd.Size)
+
+			if desc.MediaType.IsIndex() {
+				im, err := v1.ParseIndexManifest(r)
+				if err != nil {
+					return fmt.Errorf("blob %v: %w", desc.Digest, err)
+				}
+
+				if err := walk(im.Manifests); err != nil {
+					return err
+				}
+
+				continue
+			}
+
+			m, err := v1.ParseManifest(r)
+			if err != nil {
+				return fmt.Errorf("blob %v: %w", desc.Digest, err)
+			}
+
+			if err := walk(append([]v1.Descriptor{m.Config}, m.Layers...)); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	if err := walk(im.Manifests); err != nil {
+		return nil, err
+	}
+
+	return reachable, nil
+}
+
+// ReplaceOCIRootIndex replaces the OCI root index object in f with one containing the index
+// manifest read from r, according to opts. If f does not contain a root index, one is added.
+//
+// OCI blob objects that are not reachable from the new root index are deleted. Reachability is
+// determined by walking the nested indexes and image manifests referenced by the new root index,
+// which must be present in f as blob objects. Any new blobs should therefore be added to f before
+// the root index is replaced.
+//
+// If the new root index cannot be written, f is left unmodified. Otherwise, by default, the image
+// is compacted following the deletion of unreachable blobs. To disable this, use
+// OptOCIRootIndexCompact.
+//
+// By default, the image modification time is set to the current time for non-deterministic images,
+// and unset otherwise. To override this, consider using OptOCIRootIndexDeterministic or
+// OptOCIRootIndexWithTime.
+func (f *FileImage) ReplaceOCIRootIndex(r io.Reader, opts ...OCIRootIndexOpt) error {
+	f.wmu.Lock()
+	defer f.wmu.Unlock()
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.readOnly {
+		return ErrReadOnly
+	}
+
+	oo := ociRootIndexOpts{
+		compact: true,
+	}
+
+	if !f.isDeterministic() {
+		oo.t = time.Now()
+	}
+
+	for _, opt := range opts {
+		if err := opt(&oo); err != nil {
+			return fmt.Errorf("%w", err)
+		}
+	}
+
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return fmt.Errorf("%w", err)
+	}
+
+	im, err := v1.ParseIndexManifest(bytes.NewReader(b))
+	if err != nil {
+		return fmt.Errorf("%w", err)
+	}
+
+	// Determine which blobs are reachable before modifying the image, so that the image is left
+	// unmodified if the new root index cannot be walked.
+	reachable, err := f.reachableOCIBlobs(im)
+	if err != nil {
+		return fmt.Errorf("%w", err)
+	}
+
+	di, err := NewDescriptorInput(DataOCIRootIndex, bytes.NewReader(b))
+	if err != nil {
+		return fmt.Errorf("%w", err)
+	}
+
+	// Collect the existing root index (if any), and unreachable blobs.
+	var rds []*rawDescriptor
+
+	if d, err := f.getDescriptor(WithDataType(DataOCIRootIndex)); err == nil {
+		rds = append(rds, d)
+	} else if !errors.Is(err, ErrObjectNotFound) {
+		return fmt.Errorf("%w", err)
+	}
+
+	for i := range f.rds {
+		rd := &f.rds[i]
+
+		if !rd.Used || rd.DataType != DataOCIBlob {
+			continue
+		}
+
+		d := f.descriptorFromRaw(rd)
+
+		h, err := d.OCIBlobDigest()
+		if err != nil {
+			return fmt.Errorf("%w", err)
+		}
+
+		if !reachable[h] {
+			rds = append(rds, rd)
+		}
+	}
+
+	// A descriptor is only freed if a root index is being replaced, so check capacity prior to
+	// deleting anything.
+	if len(rds) == 0 && f.h.DescriptorsFree == 0 {
+		return fmt.Errorf("%w", errInsufficientCapacity)
+	}
+
+	// Record state, so that the image can be rolled back if the new root index cannot be written.
+	state, err := f.saveState()
+	if err != nil {
+		return fmt.Errorf("%w", err)
+	}
+
+	// Delete objects, noting the start of the earliest padded data object.
+	start := f.h.DataOffset + f.h.DataSize
+
+	for _, rd := range rds {
+		if s := rd.Offset + rd.Size - rd.SizeWithPadding; s < start {
+			start = s
+		}
+
+		*rd = rawDescriptor{}
+		f.h.DescriptorsFree++
+	}
+
+	// Find an unused descriptor.
+	i := 0
+	for _, rd := range f.rds {
+		if !rd.Used {
+			break
+		}
+		i++
+	}
+
+	// The new root index is written following the existing data objects, so the data of deleted
+	// objects remains intact until the descriptors are written.
+	if err := f.writeDataObject(i, di, oo.t); err != nil {
+		if rerr := f.restoreState(state); rerr != nil {
+			return fmt.Errorf("%w", errors.Join(err, rerr))
+		}
+
+		return fmt.Errorf("%w", err)
+	}
+
+	if err := f.writeDescriptors(); err != nil {
+		return fmt.Errorf("%w", err)
+	}
+
+	f.h.ModifiedAt = oo.t.Unix()
+
+	if err := f.writeHeader(); err != nil {
+		return fmt.Errorf("%w", err)
+	}
+
+	// Data objects are only moved once the root index has been replaced.
+	if oo.compact {
+		if err := f.compactFrom(start); err != nil {
+			return fmt.Errorf("%w", err)
+		}
+
+		if err := f.writeDescriptors(); err != nil {
+			return fmt.Errorf("%w", err)
+		}
+
+		if err := f.writeHeader(); err != nil {
+			return fmt.Errorf("%w", err)
+		}
+	}
+
+	return nil
+}
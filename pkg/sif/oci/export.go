@@ -0,0 +1,124 @@
+// Copyright (c) 2023, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package oci
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/sylabs/sif/v2/pkg/sif"
+)
+
+// createFile writes the contents of r to a new file at path. If an error occurs, the file is
+// removed.
+func createFile(path string, r io.Reader) error {
+	w, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644) //nolint:gosec
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(w, r); err != nil {
+		w.Close()
+		os.Remove(path)
+		return err
+	}
+
+	if err := w.Close(); err != nil {
+		os.Remove(path)
+		return err
+	}
+
+	return nil
+}
+
+// exportObject writes the root index or blob object described by d to a new file at path. The
+// data is verified against the digest in the descriptor metadata as it is written.
+func exportObject(d sif.Descriptor, path string) error {
+	h, err := d.OCIBlobDigest()
+	if err != nil {
+		return err
+	}
+
+	vr, err := newVerifyReader(io.NopCloser(d.GetReader()), h, d.Size())
+	if err != nil {
+		return err
+	}
+
+	return createFile(path, vr)
+}
+
+// ExportLayout writes the root index and all blob objects in f to an OCI image layout at path.
+// The data of each object is verified against the digest in its descriptor metadata as it is
+// written. Blob objects are written regardless of whether they are referenced by the root index.
+//
+// The directory at path is created if it does not exist. Existing files within the directory are
+// not overwritten; if a file to be written is already present, an error is returned.
+func ExportLayout(f *sif.FileImage, path string) error {
+	if f == nil {
+		return fmt.Errorf("oci: %w", errNilFileImage)
+	}
+
+	root, err := f.GetDescriptor(sif.WithDataType(sif.DataOCIRootIndex))
+	if err != nil {
+		return fmt.Errorf("oci: failed to get root index: %w", err)
+	}
+
+	ds, err := f.GetDescriptors(sif.WithDataType(sif.DataOCIBlob))
+	if err != nil {
+		return fmt.Errorf("oci: %w", err)
+	}
+
+	if err := os.MkdirAll(path, 0o755); err != nil {
+		return fmt.Errorf("oci: %w", err)
+	}
+
+	written := make(map[v1.Hash]bool)
+
+	for _, d := range ds {
+		h, err := d.OCIBlobDigest()
+		if err != nil {
+			return fmt.Errorf("oci: %w", err)
+		}
+
+		if written[h] {
+			continue
+		}
+
+		p := blobPath(path, h)
+
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			return fmt.Errorf("oci: %w", err)
+		}
+
+		if err := exportObject(d, p); err != nil {
+			return fmt.Errorf("oci: failed to export blob %v: %w", h, err)
+		}
+
+		written[h] = true
+	}
+
+	// Write the index and layout marker last, so that the layout is only valid if all blobs were
+	// written successfully.
+	if err := exportObject(root, filepath.Join(path, indexFile)); err != nil {
+		return fmt.Errorf("oci: failed to export root index: %w", err)
+	}
+
+	b, err := json.Marshal(layoutMarker{ImageLayoutVersion: layoutVersion})
+	if err != nil {
+		return fmt.Errorf("oci: %w", err)
+	}
+
+	if err := createFile(filepath.Join(path, layoutFile), bytes.NewReader(b)); err != nil {
+		return fmt.Errorf("oci: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,157 @@
+// Copyright (c) 2023, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package oci
+
+import (
+	"bytes"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sylabs/sif/v2/pkg/sif"
+)
+
+func TestExportLayout(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(t *testing.T, f *sif.FileImage, b *sif.Buffer, tl *testLayout, path string)
+		wantErr error
+	}{
+		{
+			name: "OK",
+		},
+		{
+			name: "DuplicateBlob",
+			modify: func(t *testing.T, f *sif.FileImage, _ *sif.Buffer, tl *testLayout, _ string) {
+				b, err := os.ReadFile(blobPath(tl.path, tl.blobs[0]))
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				di, err := sif.NewDescriptorInput(sif.DataOCIBlob, bytes.NewReader(b))
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				if err := f.AddObject(di); err != nil {
+					t.Fatal(err)
+				}
+			},
+		},
+		{
+			name: "NoRootIndex",
+			modify: func(t *testing.T, f *sif.FileImage, _ *sif.Buffer, _ *testLayout, _ string) {
+				d, err := f.GetDescriptor(sif.WithDataType(sif.DataOCIRootIndex))
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				if err := f.DeleteObject(d.ID()); err != nil {
+					t.Fatal(err)
+				}
+			},
+			wantErr: sif.ErrObjectNotFound,
+		},
+		{
+			name: "CorruptBlob",
+			modify: func(t *testing.T, f *sif.FileImage, b *sif.Buffer, tl *testLayout, _ string) {
+				d, err := f.GetDescriptor(sif.WithOCIBlobDigest(tl.blobs[1]))
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				b.Bytes()[d.Offset()] ^= 0xff
+			},
+			wantErr: errDigestMismatch,
+		},
+		{
+			name: "IndexExists",
+			modify: func(t *testing.T, _ *sif.FileImage, _ *sif.Buffer, _ *testLayout, path string) {
+				if err := os.WriteFile(filepath.Join(path, indexFile), []byte("{}"), 0o600); err != nil {
+					t.Fatal(err)
+				}
+			},
+			wantErr: fs.ErrExist,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tl := writeTestLayout(t)
+
+			b := sif.NewBuffer(nil)
+
+			f, err := sif.CreateContainer(b, sif.OptCreateDeterministic())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if err := WriteLayout(f, tl.path); err != nil {
+				t.Fatal(err)
+			}
+
+			path := t.TempDir()
+
+			if tt.modify != nil {
+				tt.modify(t, f, b, tl, path)
+			}
+
+			err = ExportLayout(f, path)
+			if got, want := err, tt.wantErr; !errors.Is(got, want) {
+				t.Fatalf("got error %v, want %v", got, want)
+			}
+
+			if err != nil {
+				return
+			}
+
+			// The exported layout must be identical to the original.
+			for _, name := range []string{layoutFile, indexFile} {
+				got, err := os.ReadFile(filepath.Join(path, name))
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				want, err := os.ReadFile(filepath.Join(tl.path, name))
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				if !bytes.Equal(got, want) {
+					t.Errorf("%v: got %s, want %s", name, got, want)
+				}
+			}
+
+			for _, h := range tl.blobs {
+				got, err := os.ReadFile(blobPath(path, h))
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				want, err := os.ReadFile(blobPath(tl.path, h))
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				if !bytes.Equal(got, want) {
+					t.Errorf("blob %v: contents differ", h)
+				}
+			}
+
+			if _, err := LayoutImageIndex(path); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestExportLayout_NilFileImage(t *testing.T) {
+	if err := ExportLayout(nil, t.TempDir()); !errors.Is(err, errNilFileImage) {
+		t.Errorf("got error %v, want %v", err, errNilFileImage)
+	}
+}
@@ -0,0 +1,201 @@
+// Copyright (c) 2023, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package oci
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+)
+
+// image is an image, backed by a set of blobs. It implements the v1.Image interface.
+type image struct {
+	open      blobOpener
+	raw       []byte
+	mediaType types.MediaType
+	m         *v1.Manifest
+}
+
+// newImage returns an image with raw manifest raw and media type mt. The config and layers it
+// references are read using open.
+func newImage(open blobOpener, raw []byte, mt types.MediaType) (*image, error) {
+	m, err := v1.ParseManifest(bytes.NewReader(raw))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse manifest: %w", err)
+	}
+
+	if m.MediaType != "" {
+		mt = m.MediaType
+	}
+
+	return &image{
+		open:      open,
+		raw:       raw,
+		mediaType: mt,
+		m:         m,
+	}, nil
+}
+
+// Layers returns the layers of the image, base layer first.
+func (img *image) Layers() ([]v1.Layer, error) {
+	ls := make([]v1.Layer, 0, len(img.m.Layers))
+	for i := range img.m.Layers {
+		ls = append(ls, &layer{img: img, i: i})
+	}
+	return ls, nil
+}
+
+// MediaType returns the media type of the image manifest.
+func (img *image) MediaType() (types.MediaType, error) { return img.mediaType, nil }
+
+// Size returns the size of the image manifest.
+func (img *image) Size() (int64, error) { return int64(len(img.raw)), nil }
+
+// ConfigName returns the digest of the image config.
+func (img *image) ConfigName() (v1.Hash, error) { return img.m.Config.Digest, nil }
+
+// ConfigFile returns the image config.
+func (img *image) ConfigFile() (*v1.ConfigFile, error) {
+	b, err := img.RawConfigFile()
+	if err != nil {
+		return nil, err
+	}
+	return v1.ParseConfigFile(bytes.NewReader(b))
+}
+
+// RawConfigFile returns the serialized bytes of the image config.
+func (img *image) RawConfigFile() ([]byte, error) { return readBlob(img.open, img.m.Config) }
+
+// Digest returns the sha256 digest of the image manifest.
+func (img *image) Digest() (v1.Hash, error) {
+	h, _, err := v1.SHA256(bytes.NewReader(img.raw))
+	return h, err
+}
+
+// Manifest returns the image manifest.
+func (img *image) Manifest() (*v1.Manifest, error) { return img.m.DeepCopy(), nil }
+
+// RawManifest returns the serialized bytes of the image manifest.
+func (img *image) RawManifest() ([]byte, error) { return bytes.Clone(img.raw), nil }
+
+// LayerByDigest returns the layer of the image with (compressed) digest h.
+func (img *image) LayerByDigest(h v1.Hash) (v1.Layer, error) {
+	for i, desc := range img.m.Layers {
+		if desc.Digest == h {
+			return &layer{img: img, i: i}, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %v", errDescriptorNotFound, h)
+}
+
+// LayerByDiffID returns the layer of the image with (uncompressed) digest h.
+func (img *image) LayerByDiffID(h v1.Hash) (v1.Layer, error) {
+	cf, err := img.ConfigFile()
+	if err != nil {
+		return nil, err
+	}
+
+	for i, diffID := range cf.RootFS.DiffIDs {
+		if diffID == h && i < len(img.m.Layers) {
+			return &layer{img: img, i: i}, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %v", errDescriptorNotFound, h)
+}
+
+// layer is a layer of an image, backed by a blob. It implements the v1.Layer interface.
+type layer struct {
+	img *image
+	i   int // Index of layer within image manifest.
+}
+
+// desc returns the descriptor of the layer.
+func (l *layer) desc() v1.Descriptor { return l.img.m.Layers[l.i] }
+
+// Digest returns the digest of the compressed layer.
+func (l *layer) Digest() (v1.Hash, error) { return l.desc().Digest, nil }
+
+// DiffID returns the digest of the uncompressed layer.
+func (l *layer) DiffID() (v1.Hash, error) {
+	cf, err := l.img.ConfigFile()
+	if err != nil {
+		return v1.Hash{}, err
+	}
+
+	if l.i >= len(cf.RootFS.DiffIDs) {
+		return v1.Hash{}, fmt.Errorf("%w: diff ID for layer %v", errDescriptorNotFound, l.i)
+	}
+	return cf.RootFS.DiffIDs[l.i], nil
+}
+
+// Compressed returns a reader for the compressed layer contents. The digest and size of the
+// contents are verified as they are read.
+func (l *layer) Compressed() (io.ReadCloser, error) {
+	desc := l.desc()
+
+	rc, err := l.img.open(desc.Digest)
+	if err != nil {
+		return nil, err
+	}
+
+	vr, err := newVerifyReader(rc, desc.Digest, desc.Size)
+	if err != nil {
+		rc.Close()
+		return nil, err
+	}
+
+	return vr, nil
+}
+
+// gzipReadCloser decompresses a gzip stream, closing both the decompressor and the underlying
+// reader on Close.
+type gzipReadCloser struct {
+	*gzip.Reader
+	rc io.ReadCloser
+}
+
+func (g *gzipReadCloser) Close() error {
+	err := g.Reader.Close()
+	if cerr := g.rc.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// Uncompressed returns a reader for the uncompressed layer contents.
+func (l *layer) Uncompressed() (io.ReadCloser, error) {
+	switch mt := l.desc().MediaType; mt {
+	case types.OCIUncompressedLayer, types.OCIUncompressedRestrictedLayer, types.DockerUncompressedLayer:
+		return l.Compressed()
+
+	case types.OCILayer, types.OCIRestrictedLayer, types.DockerLayer, types.DockerForeignLayer:
+		rc, err := l.Compressed()
+		if err != nil {
+			return nil, err
+		}
+
+		zr, err := gzip.NewReader(rc)
+		if err != nil {
+			rc.Close()
+			return nil, err
+		}
+
+		return &gzipReadCloser{zr, rc}, nil
+
+	default:
+		return nil, fmt.Errorf("%w: %v", errUnsupportedMediaType, mt)
+	}
+}
+
+// Size returns the size of the compressed layer.
+func (l *layer) Size() (int64, error) { return l.desc().Size, nil }
+
+// MediaType returns the media type of the layer.
+func (l *layer) MediaType() (types.MediaType, error) { return l.desc().MediaType, nil }
@@ -0,0 +1,98 @@
+// Copyright (c) 2023, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package oci
+
+import (
+	"bytes"
+	"fmt"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+)
+
+// index is an image index, backed by a set of blobs. It implements the v1.ImageIndex interface.
+type index struct {
+	open      blobOpener
+	raw       []byte
+	mediaType types.MediaType
+	im        *v1.IndexManifest
+}
+
+// newIndex returns an image index with raw manifest raw and media type mt. The manifests it
+// references are read using open.
+func newIndex(open blobOpener, raw []byte, mt types.MediaType) (*index, error) {
+	im, err := v1.ParseIndexManifest(bytes.NewReader(raw))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse index manifest: %w", err)
+	}
+
+	if im.MediaType != "" {
+		mt = im.MediaType
+	}
+
+	return &index{
+		open:      open,
+		raw:       raw,
+		mediaType: mt,
+		im:        im,
+	}, nil
+}
+
+// MediaType returns the media type of the index manifest.
+func (ii *index) MediaType() (types.MediaType, error) { return ii.mediaType, nil }
+
+// Digest returns the sha256 digest of the index manifest.
+func (ii *index) Digest() (v1.Hash, error) {
+	h, _, err := v1.SHA256(bytes.NewReader(ii.raw))
+	return h, err
+}
+
+// Size returns the size of the index manifest.
+func (ii *index) Size() (int64, error) { return int64(len(ii.raw)), nil }
+
+// IndexManifest returns the index manifest.
+func (ii *index) IndexManifest() (*v1.IndexManifest, error) { return ii.im.DeepCopy(), nil }
+
+// RawManifest returns the serialized bytes of the index manifest.
+func (ii *index) RawManifest() ([]byte, error) { return bytes.Clone(ii.raw), nil }
+
+// Image returns the image referenced by the index with digest h.
+func (ii *index) Image(h v1.Hash) (v1.Image, error) {
+	desc, err := findDescriptor(ii.im.Manifests, h)
+	if err != nil {
+		return nil, err
+	}
+
+	if !desc.MediaType.IsImage() {
+		return nil, fmt.Errorf("%w: %v", errUnsupportedMediaType, desc.MediaType)
+	}
+
+	raw, err := readBlob(ii.open, desc)
+	if err != nil {
+		return nil, err
+	}
+
+	return newImage(ii.open, raw, desc.MediaType)
+}
+
+// ImageIndex returns the image index referenced by the index with digest h.
+func (ii *index) ImageIndex(h v1.Hash) (v1.ImageIndex, error) {
+	desc, err := findDescriptor(ii.im.Manifests, h)
+	if err != nil {
+		return nil, err
+	}
+
+	if !desc.MediaType.IsIndex() {
+		return nil, fmt.Errorf("%w: %v", errUnsupportedMediaType, desc.MediaType)
+	}
+
+	raw, err := readBlob(ii.open, desc)
+	if err != nil {
+		return nil, err
+	}
+
+	return newIndex(ii.open, raw, desc.MediaType)
+}
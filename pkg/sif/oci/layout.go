@@ -0,0 +1,66 @@
+// Copyright (c) 2023, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package oci
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+)
+
+const (
+	layoutFile    = "oci-layout"
+	layoutVersion = "1.0.0"
+	indexFile     = "index.json"
+	blobsDir      = "blobs"
+)
+
+var errInvalidLayout = errors.New("invalid OCI image layout")
+
+// layoutMarker is the contents of the oci-layout file in an OCI image layout.
+type layoutMarker struct {
+	ImageLayoutVersion string `json:"imageLayoutVersion"`
+}
+
+// blobPath returns the path of the blob with digest h in the OCI image layout at path.
+func blobPath(path string, h v1.Hash) string {
+	return filepath.Join(path, blobsDir, h.Algorithm, h.Hex)
+}
+
+// LayoutImageIndex returns a v1.ImageIndex for the OCI image layout at path. The digest and
+// size of each blob are verified as it is read.
+func LayoutImageIndex(path string) (v1.ImageIndex, error) {
+	b, err := os.ReadFile(filepath.Join(path, layoutFile))
+	if err != nil {
+		return nil, err
+	}
+
+	var lm layoutMarker
+	if err := json.Unmarshal(b, &lm); err != nil {
+		return nil, fmt.Errorf("%w: %v", errInvalidLayout, err)
+	}
+
+	if lm.ImageLayoutVersion != layoutVersion {
+		return nil, fmt.Errorf("%w: unsupported version %q", errInvalidLayout, lm.ImageLayoutVersion)
+	}
+
+	raw, err := os.ReadFile(filepath.Join(path, indexFile))
+	if err != nil {
+		return nil, err
+	}
+
+	open := func(h v1.Hash) (io.ReadCloser, error) {
+		return os.Open(blobPath(path, h))
+	}
+
+	return newIndex(open, raw, types.OCIImageIndex)
+}
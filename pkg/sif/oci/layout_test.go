@@ -0,0 +1,205 @@
+// Copyright (c) 2023, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package oci
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+)
+
+func TestLayoutImageIndex(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(t *testing.T, tl *testLayout)
+		wantErr error
+	}{
+		{
+			name: "OK",
+		},
+		{
+			name: "NoMarker",
+			modify: func(t *testing.T, tl *testLayout) {
+				if err := os.Remove(filepath.Join(tl.path, layoutFile)); err != nil {
+					t.Fatal(err)
+				}
+			},
+			wantErr: os.ErrNotExist,
+		},
+		{
+			name: "UnsupportedVersion",
+			modify: func(t *testing.T, tl *testLayout) {
+				b := []byte(`{"imageLayoutVersion":"2.0.0"}`)
+				if err := os.WriteFile(filepath.Join(tl.path, layoutFile), b, 0o600); err != nil {
+					t.Fatal(err)
+				}
+			},
+			wantErr: errInvalidLayout,
+		},
+		{
+			name: "NoIndex",
+			modify: func(t *testing.T, tl *testLayout) {
+				if err := os.Remove(filepath.Join(tl.path, indexFile)); err != nil {
+					t.Fatal(err)
+				}
+			},
+			wantErr: os.ErrNotExist,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tl := writeTestLayout(t)
+
+			if tt.modify != nil {
+				tt.modify(t, tl)
+			}
+
+			ii, err := LayoutImageIndex(tl.path)
+			if got, want := err, tt.wantErr; !errors.Is(got, want) {
+				t.Fatalf("got error %v, want %v", got, want)
+			}
+
+			if err != nil {
+				return
+			}
+
+			raw, err := ii.RawManifest()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !bytes.Equal(raw, tl.root) {
+				t.Errorf("got raw manifest %s, want %s", raw, tl.root)
+			}
+
+			im, err := ii.IndexManifest()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got, want := len(im.Manifests), 2; got != want {
+				t.Fatalf("got %v manifests, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestLayoutImageIndex_Image(t *testing.T) {
+	tl := writeTestLayout(t)
+
+	ii, err := LayoutImageIndex(tl.path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	nested, err := ii.ImageIndex(tl.index.Digest)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if h, err := nested.Digest(); err != nil {
+		t.Fatal(err)
+	} else if got, want := h, tl.index.Digest; got != want {
+		t.Errorf("got index digest %v, want %v", got, want)
+	}
+
+	for _, desc := range tl.images {
+		img, err := nested.Image(desc.Digest)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if h, err := img.Digest(); err != nil {
+			t.Fatal(err)
+		} else if got, want := h, desc.Digest; got != want {
+			t.Errorf("got image digest %v, want %v", got, want)
+		}
+
+		cf, err := img.ConfigFile()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if got, want := cf.Architecture, desc.Platform.Architecture; got != want {
+			t.Errorf("got architecture %v, want %v", got, want)
+		}
+
+		ls, err := img.Layers()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for i, l := range ls {
+			diffID, err := l.DiffID()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got, want := diffID, cf.RootFS.DiffIDs[i]; got != want {
+				t.Errorf("got diff ID %v, want %v", got, want)
+			}
+
+			if _, err := img.LayerByDiffID(diffID); err != nil {
+				t.Error(err)
+			}
+
+			h, err := l.Digest()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			rc, err := l.Uncompressed()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			b, err := io.ReadAll(rc)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if err := rc.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			if got, want := b, tl.layers[h]; !bytes.Equal(got, want) {
+				t.Errorf("got layer contents %q, want %q", got, want)
+			}
+		}
+	}
+
+	if _, err := nested.Image(v1.Hash{Algorithm: "sha256", Hex: "00"}); !errors.Is(err, errDescriptorNotFound) {
+		t.Errorf("got error %v, want %v", err, errDescriptorNotFound)
+	}
+
+	if _, err := ii.Image(tl.index.Digest); !errors.Is(err, errUnsupportedMediaType) {
+		t.Errorf("got error %v, want %v", err, errUnsupportedMediaType)
+	}
+}
+
+func TestLayoutImageIndex_CorruptBlob(t *testing.T) {
+	tl := writeTestLayout(t)
+
+	// Corrupt the nested index.
+	if err := os.WriteFile(blobPath(tl.path, tl.index.Digest), []byte("{}"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	ii, err := LayoutImageIndex(tl.path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := ii.ImageIndex(tl.index.Digest); !errors.Is(err, errSizeMismatch) {
+		t.Errorf("got error %v, want %v", err, errSizeMismatch)
+	}
+}
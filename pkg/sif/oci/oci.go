@@ -0,0 +1,104 @@
+// Copyright (c) 2023, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+// Package oci implements support for OCI images stored in SIF images, as a root index
+// (sif.DataOCIRootIndex) and a set of content-addressable blobs (sif.DataOCIBlob).
+package oci
+
+import (
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+)
+
+var (
+	errDigestMismatch       = errors.New("digest mismatch")
+	errSizeMismatch         = errors.New("size mismatch")
+	errUnsupportedMediaType = errors.New("unsupported media type")
+	errDescriptorNotFound   = errors.New("descriptor not found")
+)
+
+// blobOpener opens the blob with the specified digest.
+type blobOpener func(h v1.Hash) (io.ReadCloser, error)
+
+// verifyReader verifies the digest, and optionally the size, of data read from an underlying
+// reader. If a mismatch is detected, an error is returned in place of io.EOF.
+type verifyReader struct {
+	rc   io.ReadCloser
+	h    hash.Hash
+	want v1.Hash
+	size int64 // Expected size, or -1 if unknown.
+	n    int64 // Number of bytes read.
+}
+
+// newVerifyReader returns a reader that verifies data read from rc matches digest h and, if it
+// is not negative, size.
+func newVerifyReader(rc io.ReadCloser, h v1.Hash, size int64) (*verifyReader, error) {
+	hasher, err := v1.Hasher(h.Algorithm)
+	if err != nil {
+		return nil, err
+	}
+
+	return &verifyReader{
+		rc:   rc,
+		h:    hasher,
+		want: h,
+		size: size,
+	}, nil
+}
+
+// Read reads up to len(p) bytes into p. It implements the io.Reader interface.
+func (vr *verifyReader) Read(p []byte) (int, error) {
+	n, err := vr.rc.Read(p)
+	vr.h.Write(p[:n])
+	vr.n += int64(n)
+
+	if errors.Is(err, io.EOF) {
+		if vr.size >= 0 && vr.n != vr.size {
+			return n, fmt.Errorf("%w: blob %v: got %v bytes, want %v", errSizeMismatch, vr.want, vr.n, vr.size)
+		}
+
+		got := v1.Hash{Algorithm: vr.want.Algorithm, Hex: fmt.Sprintf("%x", vr.h.Sum(nil))}
+		if got != vr.want {
+			return n, fmt.Errorf("%w: got %v, want %v", errDigestMismatch, got, vr.want)
+		}
+	}
+
+	return n, err
+}
+
+// Close closes the underlying reader. It implements the io.Closer interface.
+func (vr *verifyReader) Close() error {
+	return vr.rc.Close()
+}
+
+// readBlob reads the blob described by desc using open, verifying its digest and size.
+func readBlob(open blobOpener, desc v1.Descriptor) ([]byte, error) {
+	rc, err := open(desc.Digest)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	vr, err := newVerifyReader(rc, desc.Digest, desc.Size)
+	if err != nil {
+		return nil, err
+	}
+
+	return io.ReadAll(vr)
+}
+
+// findDescriptor returns the descriptor in descs with digest h.
+func findDescriptor(descs []v1.Descriptor, h v1.Hash) (v1.Descriptor, error) {
+	for _, desc := range descs {
+		if desc.Digest == h {
+			return desc, nil
+		}
+	}
+	return v1.Descriptor{}, fmt.Errorf("%w: %v", errDescriptorNotFound, h)
+}
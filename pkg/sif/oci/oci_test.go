@@ -0,0 +1,163 @@
+// Copyright (c) 2023, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package oci
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+)
+
+// testLayout describes an OCI image layout written by writeTestLayout.
+type testLayout struct {
+	path   string
+	root   []byte             // Contents of index.json.
+	index  v1.Descriptor      // Nested index.
+	images []v1.Descriptor    // Image manifests.
+	layers map[v1.Hash][]byte // Uncompressed layer contents, by compressed digest.
+	blobs  []v1.Hash          // All blobs, in the order they were written.
+}
+
+// writeBlob writes b as a blob with media type mt, and returns its descriptor.
+func (tl *testLayout) writeBlob(t *testing.T, mt types.MediaType, b []byte) v1.Descriptor {
+	t.Helper()
+
+	h, n, err := v1.SHA256(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := blobPath(tl.path, h)
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		if err := os.WriteFile(path, b, 0o644); err != nil { //nolint:gosec
+			t.Fatal(err)
+		}
+		tl.blobs = append(tl.blobs, h)
+	}
+
+	return v1.Descriptor{MediaType: mt, Size: n, Digest: h}
+}
+
+// writeJSON writes v as a JSON blob with media type mt, and returns its descriptor.
+func (tl *testLayout) writeJSON(t *testing.T, mt types.MediaType, v any) v1.Descriptor {
+	t.Helper()
+
+	b, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return tl.writeBlob(t, mt, b)
+}
+
+// writeLayer writes a gzip-compressed layer with contents b, and returns its descriptor and diff
+// ID.
+func (tl *testLayout) writeLayer(t *testing.T, b []byte) (v1.Descriptor, v1.Hash) {
+	t.Helper()
+
+	var buf bytes.Buffer
+
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(b); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	desc := tl.writeBlob(t, types.OCILayer, buf.Bytes())
+	tl.layers[desc.Digest] = b
+
+	diffID, _, err := v1.SHA256(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return desc, diffID
+}
+
+// writeImage writes an image for the specified architecture with a layer shared with other images,
+// and a layer unique to the image.
+func (tl *testLayout) writeImage(t *testing.T, arch string) v1.Descriptor {
+	t.Helper()
+
+	shared, sharedDiffID := tl.writeLayer(t, []byte("shared layer"))
+	unique, uniqueDiffID := tl.writeLayer(t, []byte("layer for "+arch))
+
+	config := tl.writeJSON(t, types.OCIConfigJSON, v1.ConfigFile{
+		Architecture: arch,
+		OS:           "linux",
+		RootFS: v1.RootFS{
+			Type:    "layers",
+			DiffIDs: []v1.Hash{sharedDiffID, uniqueDiffID},
+		},
+	})
+
+	desc := tl.writeJSON(t, types.OCIManifestSchema1, v1.Manifest{
+		SchemaVersion: 2,
+		MediaType:     types.OCIManifestSchema1,
+		Config:        config,
+		Layers:        []v1.Descriptor{shared, unique},
+	})
+	desc.Platform = &v1.Platform{OS: "linux", Architecture: arch}
+
+	tl.images = append(tl.images, desc)
+
+	return desc
+}
+
+// writeTestLayout writes an OCI image layout to a temporary directory. The root index references
+// a nested index and an image. The nested index references the same image, and a second image.
+// Both images share a layer.
+func writeTestLayout(t *testing.T) *testLayout {
+	t.Helper()
+
+	tl := testLayout{
+		path:   t.TempDir(),
+		layers: make(map[v1.Hash][]byte),
+	}
+
+	amd64 := tl.writeImage(t, "amd64")
+	arm64 := tl.writeImage(t, "arm64")
+
+	tl.index = tl.writeJSON(t, types.OCIImageIndex, v1.IndexManifest{
+		SchemaVersion: 2,
+		MediaType:     types.OCIImageIndex,
+		Manifests:     []v1.Descriptor{amd64, arm64},
+	})
+
+	root, err := json.Marshal(v1.IndexManifest{
+		SchemaVersion: 2,
+		MediaType:     types.OCIImageIndex,
+		Manifests:     []v1.Descriptor{tl.index, amd64},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tl.root = root
+
+	if err := os.WriteFile(filepath.Join(tl.path, indexFile), root, 0o644); err != nil { //nolint:gosec
+		t.Fatal(err)
+	}
+
+	marker := []byte(`{"imageLayoutVersion":"1.0.0"}`)
+	if err := os.WriteFile(filepath.Join(tl.path, layoutFile), marker, 0o644); err != nil { //nolint:gosec
+		t.Fatal(err)
+	}
+
+	return &tl
+}
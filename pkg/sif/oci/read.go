@@ -0,0 +1,58 @@
+// Copyright (c) 2023, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package oci
+
+import (
+	"fmt"
+	"io"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+	"github.com/sylabs/sif/v2/pkg/sif"
+)
+
+// fileImageOpener returns a blobOpener that opens blob objects in f.
+func fileImageOpener(f *sif.FileImage) blobOpener {
+	return func(h v1.Hash) (io.ReadCloser, error) {
+		d, err := f.GetDescriptor(
+			sif.WithDataType(sif.DataOCIBlob),
+			sif.WithOCIBlobDigest(h),
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get blob %v: %w", h, err)
+		}
+
+		return io.NopCloser(d.GetReader()), nil
+	}
+}
+
+// ImageIndexFromFileImage returns a v1.ImageIndex backed by the root index and blob objects in
+// f. Manifests, configs and layers are read directly from f as they are required, and their
+// digest and size are verified as they are read.
+//
+// The returned v1.ImageIndex is only valid until f is unloaded.
+func ImageIndexFromFileImage(f *sif.FileImage) (v1.ImageIndex, error) {
+	if f == nil {
+		return nil, fmt.Errorf("oci: %w", errNilFileImage)
+	}
+
+	d, err := f.GetDescriptor(sif.WithDataType(sif.DataOCIRootIndex))
+	if err != nil {
+		return nil, fmt.Errorf("oci: failed to get root index: %w", err)
+	}
+
+	raw, err := d.GetData()
+	if err != nil {
+		return nil, fmt.Errorf("oci: %w", err)
+	}
+
+	ii, err := newIndex(fileImageOpener(f), raw, types.OCIImageIndex)
+	if err != nil {
+		return nil, fmt.Errorf("oci: %w", err)
+	}
+
+	return ii, nil
+}
@@ -0,0 +1,201 @@
+// Copyright (c) 2023, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package oci
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/sylabs/sif/v2/pkg/sif"
+)
+
+var corpus = filepath.Join("..", "..", "..", "test", "images")
+
+// writeTestImage writes the OCI image layout tl to a new SIF image.
+func writeTestImage(t *testing.T, tl *testLayout) *sif.FileImage {
+	t.Helper()
+
+	f, err := sif.CreateContainer(sif.NewBuffer(nil), sif.OptCreateDeterministic())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := WriteLayout(f, tl.path); err != nil {
+		t.Fatal(err)
+	}
+
+	return f
+}
+
+func TestImageIndexFromFileImage(t *testing.T) {
+	tl := writeTestLayout(t)
+
+	ii, err := ImageIndexFromFileImage(writeTestImage(t, tl))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	raw, err := ii.RawManifest()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(raw, tl.root) {
+		t.Errorf("got raw manifest %s, want %s", raw, tl.root)
+	}
+
+	nested, err := ii.ImageIndex(tl.index.Digest)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, desc := range tl.images {
+		img, err := nested.Image(desc.Digest)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if h, err := img.Digest(); err != nil {
+			t.Fatal(err)
+		} else if got, want := h, desc.Digest; got != want {
+			t.Errorf("got image digest %v, want %v", got, want)
+		}
+
+		if _, err := img.ConfigFile(); err != nil {
+			t.Fatal(err)
+		}
+
+		ls, err := img.Layers()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, l := range ls {
+			h, err := l.Digest()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			rc, err := l.Uncompressed()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			b, err := io.ReadAll(rc)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if err := rc.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			if got, want := b, tl.layers[h]; !bytes.Equal(got, want) {
+				t.Errorf("got layer contents %q, want %q", got, want)
+			}
+		}
+	}
+}
+
+func TestImageIndexFromFileImage_RoundTrip(t *testing.T) {
+	tl := writeTestLayout(t)
+
+	ii, err := ImageIndexFromFileImage(writeTestImage(t, tl))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Write the SIF-backed index to a second image, which should contain the same blobs.
+	f, err := sif.CreateContainer(sif.NewBuffer(nil), sif.OptCreateDeterministic())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := Write(f, ii); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, h := range tl.blobs {
+		if _, err := f.GetDescriptor(sif.WithOCIBlobDigest(h)); err != nil {
+			t.Errorf("blob %v: %v", h, err)
+		}
+	}
+}
+
+func TestImageIndexFromFileImage_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		f       func(t *testing.T) *sif.FileImage
+		wantErr error
+	}{
+		{
+			name: "NilFileImage",
+			f: func(t *testing.T) *sif.FileImage {
+				return nil
+			},
+			wantErr: errNilFileImage,
+		},
+		{
+			name: "NoRootIndex",
+			f: func(t *testing.T) *sif.FileImage {
+				f, err := sif.LoadContainerFromPath(
+					filepath.Join(corpus, "one-object-oci-blob.sif"),
+					sif.OptLoadWithFlag(os.O_RDONLY),
+				)
+				if err != nil {
+					t.Fatal(err)
+				}
+				t.Cleanup(func() { f.UnloadContainer() })
+
+				return f
+			},
+			wantErr: sif.ErrObjectNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ImageIndexFromFileImage(tt.f(t))
+			if got, want := err, tt.wantErr; !errors.Is(got, want) {
+				t.Fatalf("got error %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestImageIndexFromFileImage_MissingBlob(t *testing.T) {
+	tl := writeTestLayout(t)
+
+	f := writeTestImage(t, tl)
+
+	// Delete the nested index blob.
+	d, err := f.GetDescriptor(sif.WithOCIBlobDigest(tl.index.Digest))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := f.DeleteObject(d.ID()); err != nil {
+		t.Fatal(err)
+	}
+
+	ii, err := ImageIndexFromFileImage(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := ii.ImageIndex(tl.index.Digest); !errors.Is(err, sif.ErrObjectNotFound) {
+		t.Errorf("got error %v, want %v", err, sif.ErrObjectNotFound)
+	}
+
+	if _, err := ii.Image(v1.Hash{Algorithm: "sha256", Hex: "00"}); !errors.Is(err, errDescriptorNotFound) {
+		t.Errorf("got error %v, want %v", err, errDescriptorNotFound)
+	}
+}
@@ -0,0 +1,235 @@
+// Copyright (c) 2023, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package oci
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/sylabs/sif/v2/pkg/sif"
+)
+
+var (
+	errNilFileImage    = errors.New("nil file image")
+	errRootIndexExists = errors.New("image already contains a root index")
+)
+
+// writer writes the blobs of an OCI image to a FileImage, within a transaction.
+type writer struct {
+	tx      *sif.Tx
+	written map[v1.Hash]bool // Blobs written (or found) so far.
+}
+
+// hasBlob returns true if the image contains a blob with digest h.
+func (w *writer) hasBlob(h v1.Hash) (bool, error) {
+	if w.written[h] {
+		return true, nil
+	}
+
+	ds, err := w.tx.GetDescriptors(
+		sif.WithDataType(sif.DataOCIBlob),
+		sif.WithOCIBlobDigest(h),
+	)
+	if err != nil && !errors.Is(err, sif.ErrNoObjects) {
+		return false, err
+	}
+
+	return len(ds) > 0, nil
+}
+
+// writeBlob writes the blob described by desc, if it is not already present. The blob contents
+// are obtained by calling open, and verified against desc as they are written.
+func (w *writer) writeBlob(desc v1.Descriptor, open func() (io.ReadCloser, error)) error {
+	if ok, err := w.hasBlob(desc.Digest); err != nil || ok {
+		return err
+	}
+
+	rc, err := open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	vr, err := newVerifyReader(rc, desc.Digest, desc.Size)
+	if err != nil {
+		return err
+	}
+
+	di, err := sif.NewDescriptorInput(sif.DataOCIBlob, vr)
+	if err != nil {
+		return err
+	}
+
+	if _, err := w.tx.AddObject(di); err != nil {
+		return fmt.Errorf("failed to write blob %v: %w", desc.Digest, err)
+	}
+
+	w.written[desc.Digest] = true
+
+	return nil
+}
+
+// rawOpener returns a function that opens b.
+func rawOpener(b []byte) func() (io.ReadCloser, error) {
+	return func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(b)), nil
+	}
+}
+
+// writeImage writes the manifest, config and layers of img, which is described by desc.
+func (w *writer) writeImage(desc v1.Descriptor, img v1.Image) error {
+	raw, err := img.RawManifest()
+	if err != nil {
+		return err
+	}
+
+	if err := w.writeBlob(desc, rawOpener(raw)); err != nil {
+		return err
+	}
+
+	m, err := img.Manifest()
+	if err != nil {
+		return err
+	}
+
+	config, err := img.RawConfigFile()
+	if err != nil {
+		return err
+	}
+
+	if err := w.writeBlob(m.Config, rawOpener(config)); err != nil {
+		return err
+	}
+
+	for _, ld := range m.Layers {
+		// Non-distributable layers are not expected to be present.
+		if !ld.MediaType.IsDistributable() {
+			continue
+		}
+
+		l, err := img.LayerByDigest(ld.Digest)
+		if err != nil {
+			return err
+		}
+
+		if err := w.writeBlob(ld, l.Compressed); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// writeIndex writes the manifests referenced by ii, and their blobs. The manifest of ii itself is
+// not written.
+func (w *writer) writeIndex(ii v1.ImageIndex) error {
+	im, err := ii.IndexManifest()
+	if err != nil {
+		return err
+	}
+
+	for _, desc := range im.Manifests {
+		switch {
+		case desc.MediaType.IsIndex():
+			child, err := ii.ImageIndex(desc.Digest)
+			if err != nil {
+				return err
+			}
+
+			raw, err := child.RawManifest()
+			if err != nil {
+				return err
+			}
+
+			if err := w.writeBlob(desc, rawOpener(raw)); err != nil {
+				return err
+			}
+
+			if err := w.writeIndex(child); err != nil {
+				return err
+			}
+
+		case desc.MediaType.IsImage():
+			img, err := ii.Image(desc.Digest)
+			if err != nil {
+				return err
+			}
+
+			if err := w.writeImage(desc, img); err != nil {
+				return err
+			}
+
+		default:
+			return fmt.Errorf("%w: %v", errUnsupportedMediaType, desc.MediaType)
+		}
+	}
+
+	return nil
+}
+
+// Write writes the OCI image index ii to f. The manifest of ii is written as a root index object,
+// and all manifests, configs and layers reachable from ii are written as blob objects. Blobs are
+// deduplicated by digest, so a blob that is referenced more than once, or is already present in
+// f, is written only once. Non-distributable layers are not written.
+//
+// The objects are added to f in a single transaction, so if an error is encountered, f is left
+// unmodified. If f already contains a root index, an error is returned.
+func Write(f *sif.FileImage, ii v1.ImageIndex) error {
+	if f == nil {
+		return fmt.Errorf("oci: %w", errNilFileImage)
+	}
+
+	if _, err := f.GetDescriptor(sif.WithDataType(sif.DataOCIRootIndex)); err == nil {
+		return fmt.Errorf("oci: %w", errRootIndexExists)
+	} else if !errors.Is(err, sif.ErrObjectNotFound) && !errors.Is(err, sif.ErrNoObjects) {
+		return fmt.Errorf("oci: %w", err)
+	}
+
+	err := f.Update(func(tx *sif.Tx) error {
+		w := writer{
+			tx:      tx,
+			written: make(map[v1.Hash]bool),
+		}
+
+		if err := w.writeIndex(ii); err != nil {
+			return err
+		}
+
+		raw, err := ii.RawManifest()
+		if err != nil {
+			return err
+		}
+
+		di, err := sif.NewDescriptorInput(sif.DataOCIRootIndex, bytes.NewReader(raw))
+		if err != nil {
+			return err
+		}
+
+		if _, err := tx.AddObject(di); err != nil {
+			return fmt.Errorf("failed to write root index: %w", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("oci: %w", err)
+	}
+
+	return nil
+}
+
+// WriteLayout writes the OCI image layout at path to f. See Write for details.
+func WriteLayout(f *sif.FileImage, path string) error {
+	ii, err := LayoutImageIndex(path)
+	if err != nil {
+		return fmt.Errorf("oci: %w", err)
+	}
+
+	return Write(f, ii)
+}
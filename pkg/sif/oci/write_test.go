@@ -0,0 +1,230 @@
+// Copyright (c) 2023, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package oci
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"testing"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/sylabs/sif/v2/pkg/sif"
+)
+
+func TestWriteLayout(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(t *testing.T, f *sif.FileImage, tl *testLayout)
+		wantErr error
+	}{
+		{
+			name: "OK",
+		},
+		{
+			name: "ExistingBlob",
+			modify: func(t *testing.T, f *sif.FileImage, tl *testLayout) {
+				b, err := os.ReadFile(blobPath(tl.path, tl.blobs[0]))
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				di, err := sif.NewDescriptorInput(sif.DataOCIBlob, bytes.NewReader(b))
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				if err := f.AddObject(di); err != nil {
+					t.Fatal(err)
+				}
+			},
+		},
+		{
+			name: "RootIndexExists",
+			modify: func(t *testing.T, f *sif.FileImage, tl *testLayout) {
+				di, err := sif.NewDescriptorInput(sif.DataOCIRootIndex, bytes.NewReader([]byte("{}")))
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				if err := f.AddObject(di); err != nil {
+					t.Fatal(err)
+				}
+			},
+			wantErr: errRootIndexExists,
+		},
+		{
+			name: "MissingBlob",
+			modify: func(t *testing.T, f *sif.FileImage, tl *testLayout) {
+				if err := os.Remove(blobPath(tl.path, tl.blobs[1])); err != nil {
+					t.Fatal(err)
+				}
+			},
+			wantErr: os.ErrNotExist,
+		},
+		{
+			name: "CorruptBlob",
+			modify: func(t *testing.T, f *sif.FileImage, tl *testLayout) {
+				path := blobPath(tl.path, tl.blobs[1])
+
+				b, err := os.ReadFile(path)
+				if err != nil {
+					t.Fatal(err)
+				}
+				b[0] ^= 0xff
+
+				if err := os.WriteFile(path, b, 0o600); err != nil {
+					t.Fatal(err)
+				}
+			},
+			wantErr: errDigestMismatch,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tl := writeTestLayout(t)
+
+			f, err := sif.CreateContainer(sif.NewBuffer(nil), sif.OptCreateDeterministic())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if tt.modify != nil {
+				tt.modify(t, f, tl)
+			}
+
+			err = WriteLayout(f, tl.path)
+			if got, want := err, tt.wantErr; !errors.Is(got, want) {
+				t.Fatalf("got error %v, want %v", got, want)
+			}
+
+			if err != nil {
+				return
+			}
+
+			root, err := f.GetDescriptor(sif.WithDataType(sif.DataOCIRootIndex))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			b, err := root.GetData()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !bytes.Equal(b, tl.root) {
+				t.Errorf("got root index %s, want %s", b, tl.root)
+			}
+
+			// Each blob must be present exactly once, with the expected contents.
+			ds, err := f.GetDescriptors(sif.WithDataType(sif.DataOCIBlob))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got, want := len(ds), len(tl.blobs); got != want {
+				t.Errorf("got %v blobs, want %v", got, want)
+			}
+
+			for _, h := range tl.blobs {
+				d, err := f.GetDescriptor(sif.WithOCIBlobDigest(h))
+				if err != nil {
+					t.Fatalf("blob %v: %v", h, err)
+				}
+
+				b, err := d.GetData()
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				if got, _, err := v1.SHA256(bytes.NewReader(b)); err != nil {
+					t.Fatal(err)
+				} else if got != h {
+					t.Errorf("got blob digest %v, want %v", got, h)
+				}
+			}
+		})
+	}
+}
+
+// peakBuffer is a sif.Buffer that records the largest size it has reached.
+type peakBuffer struct {
+	*sif.Buffer
+	peak int64
+}
+
+func (b *peakBuffer) Write(p []byte) (int, error) {
+	n, err := b.Buffer.Write(p)
+	if l := b.Len(); l > b.peak {
+		b.peak = l
+	}
+	return n, err
+}
+
+func TestWriteLayout_Rollback(t *testing.T) {
+	tl := writeTestLayout(t)
+
+	// Corrupt a layer, so that verification fails after the blobs that precede it are written.
+	for h := range tl.layers {
+		path := blobPath(tl.path, h)
+
+		b, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b[0] ^= 0xff
+
+		if err := os.WriteFile(path, b, 0o600); err != nil {
+			t.Fatal(err)
+		}
+
+		break
+	}
+
+	b := &peakBuffer{Buffer: sif.NewBuffer(nil)}
+
+	f, err := sif.CreateContainer(b, sif.OptCreateDeterministic())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	before := bytes.Clone(b.Bytes())
+
+	if err := WriteLayout(f, tl.path); !errors.Is(err, errDigestMismatch) {
+		t.Fatalf("got error %v, want %v", err, errDigestMismatch)
+	}
+
+	if b.peak <= int64(len(before)) {
+		t.Error("no data written before verification failed")
+	}
+
+	// The written data must be truncated, and the header and descriptors left unmodified.
+	if !bytes.Equal(b.Bytes(), before) {
+		t.Error("image modified by failed write")
+	}
+
+	if got, want := f.DescriptorsFree(), f.DescriptorsTotal(); got != want {
+		t.Errorf("got %v free descriptors, want %v", got, want)
+	}
+
+	if got, want := f.DataSize(), int64(0); got != want {
+		t.Errorf("got data size %v, want %v", got, want)
+	}
+}
+
+func TestWrite_NilFileImage(t *testing.T) {
+	tl := writeTestLayout(t)
+
+	ii, err := LayoutImageIndex(tl.path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := Write(nil, ii); !errors.Is(err, errNilFileImage) {
+		t.Errorf("got error %v, want %v", err, errNilFileImage)
+	}
+}
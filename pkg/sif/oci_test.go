@@ -0,0 +1,497 @@
+// Copyright (c) 2023, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package sif
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+	"github.com/sebdah/goldie/v2"
+)
+
+// testOCIBlob is a blob used to construct test OCI images.
+type testOCIBlob struct {
+	b    []byte
+	desc v1.Descriptor
+}
+
+// newTestOCIBlob returns a blob with contents b and media type mt.
+func newTestOCIBlob(t *testing.T, mt types.MediaType, b []byte) testOCIBlob {
+	t.Helper()
+
+	h, n, err := v1.SHA256(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return testOCIBlob{b, v1.Descriptor{MediaType: mt, Size: n, Digest: h}}
+}
+
+// newTestOCIJSONBlob returns a blob containing v encoded as JSON, with media type mt.
+func newTestOCIJSONBlob(t *testing.T, mt types.MediaType, v any) testOCIBlob {
+	t.Helper()
+
+	b, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return newTestOCIBlob(t, mt, b)
+}
+
+// newTestOCIIndex returns an index manifest blob referencing blobs.
+func newTestOCIIndex(t *testing.T, blobs ...testOCIBlob) testOCIBlob {
+	t.Helper()
+
+	im := v1.IndexManifest{
+		SchemaVersion: 2,
+		MediaType:     types.OCIImageIndex,
+	}
+
+	for _, b := range blobs {
+		im.Manifests = append(im.Manifests, b.desc)
+	}
+
+	return newTestOCIJSONBlob(t, types.OCIImageIndex, im)
+}
+
+//nolint:maintidx
+func TestFileImage_ReplaceOCIRootIndex(t *testing.T) {
+	shared := newTestOCIBlob(t, types.OCILayer, []byte("shared layer"))
+
+	newImage := func(arch string) (manifest, config, layer testOCIBlob) {
+		layer = newTestOCIBlob(t, types.OCILayer, []byte("layer for "+arch))
+		config = newTestOCIJSONBlob(t, types.OCIConfigJSON, v1.ConfigFile{
+			Architecture: arch,
+			OS:           "linux",
+		})
+		manifest = newTestOCIJSONBlob(t, types.OCIManifestSchema1, v1.Manifest{
+			SchemaVersion: 2,
+			MediaType:     types.OCIManifestSchema1,
+			Config:        config.desc,
+			Layers:        []v1.Descriptor{shared.desc, layer.desc},
+		})
+		return manifest, config, layer
+	}
+
+	amd64Manifest, amd64Config, amd64Layer := newImage("amd64")
+	arm64Manifest, arm64Config, arm64Layer := newImage("arm64")
+
+	nested := newTestOCIIndex(t, arm64Manifest)
+
+	blobs := []testOCIBlob{
+		shared,
+		amd64Layer, amd64Config, amd64Manifest,
+		arm64Layer, arm64Config, arm64Manifest,
+		nested,
+	}
+
+	getDescriptorInputs := func(root *testOCIBlob) []DescriptorInput {
+		var dis []DescriptorInput
+		for _, b := range blobs {
+			dis = append(dis, getDescriptorInput(t, DataOCIBlob, b.b))
+		}
+		if root != nil {
+			dis = append(dis, getDescriptorInput(t, DataOCIRootIndex, root.b))
+		}
+		return dis
+	}
+
+	amd64Index := newTestOCIIndex(t, amd64Manifest)
+	arm64Index := newTestOCIIndex(t, arm64Manifest)
+	nestedIndex := newTestOCIIndex(t, nested)
+	allIndex := newTestOCIIndex(t, amd64Manifest, nested)
+	missingIndex := newTestOCIIndex(t, newTestOCIBlob(t, types.OCIManifestSchema1, []byte("{}")))
+
+	tests := []struct {
+		name       string
+		createOpts []CreateOpt
+		index      testOCIBlob
+		opts       []OCIRootIndexOpt
+		wantBlobs  []testOCIBlob
+		wantErr    error
+	}{
+		{
+			name: "ErrMissingManifest",
+			createOpts: []CreateOpt{
+				OptCreateDeterministic(),
+				OptCreateWithDescriptors(getDescriptorInputs(&amd64Index)...),
+			},
+			index:   missingIndex,
+			wantErr: ErrObjectNotFound,
+		},
+		{
+			name: "ErrInsufficientCapacity",
+			createOpts: []CreateOpt{
+				OptCreateDeterministic(),
+				OptCreateWithDescriptorCapacity(int64(len(blobs))),
+				OptCreateWithDescriptors(getDescriptorInputs(nil)...),
+			},
+			index:   allIndex,
+			wantErr: errInsufficientCapacity,
+		},
+		{
+			name: "NoRootIndex",
+			createOpts: []CreateOpt{
+				OptCreateDeterministic(),
+				OptCreateWithDescriptors(getDescriptorInputs(nil)...),
+			},
+			index:     amd64Index,
+			wantBlobs: []testOCIBlob{shared, amd64Layer, amd64Config, amd64Manifest},
+		},
+		{
+			name: "Replace",
+			createOpts: []CreateOpt{
+				OptCreateDeterministic(),
+				OptCreateWithDescriptors(getDescriptorInputs(&amd64Index)...),
+			},
+			index:     arm64Index,
+			wantBlobs: []testOCIBlob{shared, arm64Layer, arm64Config, arm64Manifest},
+		},
+		{
+			name: "ReplaceNoCompact",
+			createOpts: []CreateOpt{
+				OptCreateDeterministic(),
+				OptCreateWithDescriptors(getDescriptorInputs(&amd64Index)...),
+			},
+			index: arm64Index,
+			opts: []OCIRootIndexOpt{
+				OptOCIRootIndexCompact(false),
+			},
+			wantBlobs: []testOCIBlob{shared, arm64Layer, arm64Config, arm64Manifest},
+		},
+		{
+			name: "ReplaceNested",
+			createOpts: []CreateOpt{
+				OptCreateDeterministic(),
+				OptCreateWithDescriptors(getDescriptorInputs(&amd64Index)...),
+			},
+			index:     nestedIndex,
+			wantBlobs: []testOCIBlob{shared, arm64Layer, arm64Config, arm64Manifest, nested},
+		},
+		{
+			name: "Deterministic",
+			createOpts: []CreateOpt{
+				OptCreateWithID("de170c43-36ab-44a8-bca9-1ea1a070a274"),
+				OptCreateWithTime(time.Unix(946702800, 0)),
+				OptCreateWithDescriptors(getDescriptorInputs(&amd64Index)...),
+			},
+			index: arm64Index,
+			opts: []OCIRootIndexOpt{
+				OptOCIRootIndexDeterministic(),
+			},
+			wantBlobs: []testOCIBlob{shared, arm64Layer, arm64Config, arm64Manifest},
+		},
+		{
+			name: "WithTime",
+			createOpts: []CreateOpt{
+				OptCreateDeterministic(),
+				OptCreateWithDescriptors(getDescriptorInputs(&amd64Index)...),
+			},
+			index: arm64Index,
+			opts: []OCIRootIndexOpt{
+				OptOCIRootIndexWithTime(time.Unix(946702800, 0)),
+			},
+			wantBlobs: []testOCIBlob{shared, arm64Layer, arm64Config, arm64Manifest},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var b Buffer
+
+			f, err := CreateContainer(&b, tt.createOpts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = f.ReplaceOCIRootIndex(bytes.NewReader(tt.index.b), tt.opts...)
+			if got, want := err, tt.wantErr; !errors.Is(got, want) {
+				t.Fatalf("got error %v, want %v", got, want)
+			}
+
+			if err == nil {
+				d, err := f.GetDescriptor(WithDataType(DataOCIRootIndex))
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				if h, err := d.OCIBlobDigest(); err != nil {
+					t.Fatal(err)
+				} else if got, want := h, tt.index.desc.Digest; got != want {
+					t.Errorf("got root index digest %v, want %v", got, want)
+				}
+
+				ds, err := f.GetDescriptors(WithDataType(DataOCIBlob))
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				var got, want []string
+
+				for _, d := range ds {
+					h, err := d.OCIBlobDigest()
+					if err != nil {
+						t.Fatal(err)
+					}
+					got = append(got, h.String())
+				}
+
+				for _, b := range tt.wantBlobs {
+					want = append(want, b.desc.Digest.String())
+				}
+
+				sort.Strings(got)
+				sort.Strings(want)
+
+				if g, w := len(got), len(want); g != w {
+					t.Fatalf("got %v blobs, want %v", g, w)
+				}
+
+				for i := range got {
+					if got[i] != want[i] {
+						t.Errorf("got blob %v, want %v", got[i], want[i])
+					}
+				}
+			}
+
+			if err := f.UnloadContainer(); err != nil {
+				t.Error(err)
+			}
+
+			g := goldie.New(t, goldie.WithTestNameForDir(true))
+			g.Assert(t, tt.name, b.Bytes())
+		})
+	}
+}
+
+var errTestWrite = errors.New("test write error")
+
+// faultyBuffer is a Buffer whose writes fail once fail is set.
+type faultyBuffer struct {
+	Buffer
+	fail bool
+}
+
+func (b *faultyBuffer) Write(p []byte) (int, error) {
+	if b.fail {
+		return 0, errTestWrite
+	}
+	return b.Buffer.Write(p)
+}
+
+func TestFileImage_ReplaceOCIRootIndexRollback(t *testing.T) {
+	blob := newTestOCIBlob(t, types.OCILayer, []byte("layer"))
+
+	tests := []struct {
+		name    string
+		compact bool
+	}{
+		{name: "Compact", compact: true},
+		{name: "NoCompact", compact: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var b faultyBuffer
+
+			f, err := CreateContainer(&b,
+				OptCreateDeterministic(),
+				OptCreateWithDescriptors(
+					getDescriptorInput(t, DataOCIBlob, blob.b),
+					getDescriptorInput(t, DataOCIRootIndex, newTestOCIIndex(t, blob).b),
+				),
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			h := f.h
+			rds := append([]rawDescriptor(nil), f.rds...)
+			want := bytes.Clone(b.Bytes())
+
+			// The existing root index and blob are deleted, after which writing the new root index
+			// fails.
+			b.fail = true
+
+			err = f.ReplaceOCIRootIndex(bytes.NewReader(newTestOCIIndex(t).b),
+				OptOCIRootIndexCompact(tt.compact),
+			)
+			if got, want := err, errTestWrite; !errors.Is(got, want) {
+				t.Fatalf("got error %v, want %v", got, want)
+			}
+
+			if f.h != h {
+				t.Error("header modified")
+			}
+
+			if !reflect.DeepEqual(f.rds, rds) {
+				t.Error("descriptors modified")
+			}
+
+			if got := b.Bytes(); !bytes.Equal(got, want) {
+				t.Errorf("image modified")
+			}
+
+			// The image must remain usable.
+			b.fail = false
+
+			if err := f.ReplaceOCIRootIndex(bytes.NewReader(newTestOCIIndex(t).b),
+				OptOCIRootIndexCompact(tt.compact),
+			); err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err := f.GetDescriptor(WithDataType(DataOCIBlob)); !errors.Is(err, ErrObjectNotFound) {
+				t.Errorf("got error %v, want %v", err, ErrObjectNotFound)
+			}
+		})
+	}
+}
+
+func TestFileImage_CheckOCIBlobs(t *testing.T) {
+	tests := []struct {
+		name       string
+		createOpts []CreateOpt
+		corruptIDs []uint32
+		wantIDs    []uint32
+	}{
+		{
+			name: "Empty",
+			createOpts: []CreateOpt{
+				OptCreateDeterministic(),
+			},
+		},
+		{
+			name: "OK",
+			createOpts: []CreateOpt{
+				OptCreateDeterministic(),
+				OptCreateWithDescriptors(
+					getDescriptorInput(t, DataGeneric, []byte{0xfa, 0xce}),
+					getDescriptorInput(t, DataOCIBlob, []byte{0xfe, 0xed}),
+					getDescriptorInput(t, DataOCIRootIndex, []byte("{}")),
+				),
+			},
+		},
+		{
+			name: "CorruptGeneric",
+			createOpts: []CreateOpt{
+				OptCreateDeterministic(),
+				OptCreateWithDescriptors(
+					getDescriptorInput(t, DataGeneric, []byte{0xfa, 0xce}),
+					getDescriptorInput(t, DataOCIBlob, []byte{0xfe, 0xed}),
+				),
+			},
+			corruptIDs: []uint32{1},
+		},
+		{
+			name: "CorruptBlob",
+			createOpts: []CreateOpt{
+				OptCreateDeterministic(),
+				OptCreateWithDescriptors(
+					getDescriptorInput(t, DataOCIBlob, []byte{0xfa, 0xce}),
+					getDescriptorInput(t, DataOCIBlob, []byte{0xfe, 0xed}),
+					getDescriptorInput(t, DataOCIRootIndex, []byte("{}")),
+				),
+			},
+			corruptIDs: []uint32{2},
+			wantIDs:    []uint32{2},
+		},
+		{
+			name: "CorruptMultiple",
+			createOpts: []CreateOpt{
+				OptCreateDeterministic(),
+				OptCreateWithDescriptors(
+					getDescriptorInput(t, DataOCIBlob, []byte{0xfa, 0xce}),
+					getDescriptorInput(t, DataOCIBlob, []byte{0xfe, 0xed}),
+					getDescriptorInput(t, DataOCIRootIndex, []byte("{}")),
+				),
+			},
+			corruptIDs: []uint32{1, 3},
+			wantIDs:    []uint32{1, 3},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var b Buffer
+
+			f, err := CreateContainer(&b, tt.createOpts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Cleanup(func() {
+				if err := f.UnloadContainer(); err != nil {
+					t.Error(err)
+				}
+			})
+
+			for _, id := range tt.corruptIDs {
+				d, err := f.GetDescriptor(WithID(id))
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				b.Bytes()[d.Offset()] ^= 0xff
+			}
+
+			err = f.CheckOCIBlobs()
+
+			if len(tt.wantIDs) == 0 {
+				if err != nil {
+					t.Fatalf("got error %v, want nil", err)
+				}
+				return
+			}
+
+			u, ok := err.(interface{ Unwrap() []error })
+			if !ok {
+				t.Fatalf("got error %v, want joined errors", err)
+			}
+
+			errs := u.Unwrap()
+
+			if got, want := len(errs), len(tt.wantIDs); got != want {
+				t.Fatalf("got %v errors, want %v", got, want)
+			}
+
+			for i, err := range errs {
+				var de *OCIBlobDigestError
+				if !errors.As(err, &de) {
+					t.Fatalf("got error %v, want %T", err, de)
+				}
+
+				if got, want := de.ID, tt.wantIDs[i]; got != want {
+					t.Errorf("got ID %v, want %v", got, want)
+				}
+
+				d, err := f.GetDescriptor(WithID(de.ID))
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				if h, err := d.OCIBlobDigest(); err != nil {
+					t.Fatal(err)
+				} else if got, want := de.Expected, h; got != want {
+					t.Errorf("got expected digest %v, want %v", got, want)
+				}
+
+				if h, _, err := v1.SHA256(d.GetReader()); err != nil {
+					t.Fatal(err)
+				} else if got, want := de.Actual, h; got != want {
+					t.Errorf("got actual digest %v, want %v", got, want)
+				}
+			}
+		})
+	}
+}
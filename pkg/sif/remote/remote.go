@@ -0,0 +1,351 @@
+// Copyright (c) 2023, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+// Package remote provides access to SIF images hosted on HTTP servers that support range
+// requests.
+package remote
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+var (
+	errRangeNotSupported = errors.New("server does not support range requests")
+	errInvalidBlockSize  = errors.New("block size must be positive")
+	errInvalidCacheSize  = errors.New("cache size must not be negative")
+	errUnexpectedLength  = errors.New("unexpected response length")
+	errContentRange      = errors.New("invalid content range")
+	errNegativeOffset    = errors.New("negative offset")
+)
+
+// unexpectedStatusError records an unexpected HTTP response status.
+type unexpectedStatusError struct {
+	status string
+}
+
+func (e *unexpectedStatusError) Error() string {
+	return fmt.Sprintf("unexpected response status: %v", e.status)
+}
+
+func (e *unexpectedStatusError) Is(target error) bool {
+	t, ok := target.(*unexpectedStatusError)
+	if !ok {
+		return false
+	}
+	return e.status == t.status || t.status == ""
+}
+
+// readerOpts accumulates ReaderAt options.
+type readerOpts struct {
+	ctx       context.Context //nolint:containedctx
+	client    *http.Client
+	blockSize int64
+	cacheSize int
+}
+
+// ReaderOpt are used to specify ReaderAt options.
+type ReaderOpt func(*readerOpts) error
+
+// OptReaderContext specifies ctx as the context used for HTTP requests.
+func OptReaderContext(ctx context.Context) ReaderOpt {
+	return func(ro *readerOpts) error {
+		ro.ctx = ctx
+		return nil
+	}
+}
+
+// OptReaderHTTPClient specifies c as the client used for HTTP requests.
+func OptReaderHTTPClient(c *http.Client) ReaderOpt {
+	return func(ro *readerOpts) error {
+		ro.client = c
+		return nil
+	}
+}
+
+// OptReaderBlockSize specifies n as the size of each block fetched from the server, in bytes.
+func OptReaderBlockSize(n int64) ReaderOpt {
+	return func(ro *readerOpts) error {
+		if n <= 0 {
+			return errInvalidBlockSize
+		}
+		ro.blockSize = n
+		return nil
+	}
+}
+
+// OptReaderCacheSize specifies n as the maximum number of blocks to cache. If n is zero, blocks
+// are not cached.
+func OptReaderCacheSize(n int) ReaderOpt {
+	return func(ro *readerOpts) error {
+		if n < 0 {
+			return errInvalidCacheSize
+		}
+		ro.cacheSize = n
+		return nil
+	}
+}
+
+// block is a cached block of data.
+type block struct {
+	index int64
+	b     []byte
+}
+
+// ReaderAt implements io.ReaderAt over a resource accessed using HTTP range requests. Data is
+// fetched in fixed-size blocks, the most recently used of which are cached.
+type ReaderAt struct {
+	url  string
+	opts readerOpts
+	size int64
+
+	mu     sync.Mutex
+	lru    *list.List              // Cached blocks, most recently used first.
+	blocks map[int64]*list.Element // Cached blocks, indexed by block index.
+}
+
+// NewReaderAt returns a ReaderAt that reads the resource at url, according to opts.
+//
+// The server must support HTTP range requests. The size of the resource is determined when
+// NewReaderAt is called, and is assumed not to change.
+//
+// By default, http.DefaultClient is used to perform requests. To use a different client, consider
+// using OptReaderHTTPClient. By default, requests are performed using context.Background. To
+// use a different context, consider using OptReaderContext.
+//
+// By default, data is fetched in blocks of 256 KiB, and up to 64 blocks are cached. To change
+// this behavior, consider using OptReaderBlockSize and/or OptReaderCacheSize.
+func NewReaderAt(url string, opts ...ReaderOpt) (*ReaderAt, error) {
+	ro := readerOpts{
+		ctx:       context.Background(),
+		client:    http.DefaultClient,
+		blockSize: 256 << 10,
+		cacheSize: 64,
+	}
+
+	for _, opt := range opts {
+		if err := opt(&ro); err != nil {
+			return nil, fmt.Errorf("%w", err)
+		}
+	}
+
+	r := &ReaderAt{
+		url:    url,
+		opts:   ro,
+		lru:    list.New(),
+		blocks: make(map[int64]*list.Element),
+	}
+
+	// Fetch the first block, which also reveals the size of the resource.
+	b, size, err := r.fetch(0, ro.blockSize)
+	if err != nil {
+		return nil, fmt.Errorf("%w", err)
+	}
+	r.size = size
+	r.cache(0, b)
+
+	return r, nil
+}
+
+// Size returns the size of the resource, in bytes.
+func (r *ReaderAt) Size() int64 { return r.size }
+
+// parseContentRange parses the value of a Content-Range header, returning the first byte
+// position and the complete length of the resource.
+func parseContentRange(s string) (int64, int64, error) {
+	// Expected format is "bytes <first>-<last>/<length>".
+	rng, length, ok := strings.Cut(strings.TrimPrefix(s, "bytes "), "/")
+	if !ok {
+		return 0, 0, fmt.Errorf("%w %q", errContentRange, s)
+	}
+
+	first, _, ok := strings.Cut(rng, "-")
+	if !ok {
+		return 0, 0, fmt.Errorf("%w %q", errContentRange, s)
+	}
+
+	off, err := strconv.ParseInt(first, 10, 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("%w %q: %w", errContentRange, s, err)
+	}
+
+	size, err := strconv.ParseInt(length, 10, 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("%w %q: %w", errContentRange, s, err)
+	}
+
+	return off, size, nil
+}
+
+// fetch requests n bytes starting at offset off from the server. The returned slice may be
+// shorter than n if the end of the resource is reached. The complete length of the resource is
+// also returned.
+func (r *ReaderAt) fetch(off, n int64) ([]byte, int64, error) {
+	req, err := http.NewRequestWithContext(r.opts.ctx, http.MethodGet, r.url, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", off, off+n-1))
+
+	res, err := r.opts.client.Do(req)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer res.Body.Close()
+
+	switch res.StatusCode {
+	case http.StatusPartialContent:
+	case http.StatusOK:
+		return nil, 0, errRangeNotSupported
+	case http.StatusRequestedRangeNotSatisfiable:
+		// No range of an empty resource can be satisfied.
+		if res.Header.Get("Content-Range") == "bytes */0" {
+			return nil, 0, nil
+		}
+		return nil, 0, &unexpectedStatusError{res.Status}
+	default:
+		return nil, 0, &unexpectedStatusError{res.Status}
+	}
+
+	first, size, err := parseContentRange(res.Header.Get("Content-Range"))
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if first != off {
+		return nil, 0, fmt.Errorf("%w: got range starting at %v, want %v", errUnexpectedLength, first, off)
+	}
+
+	if off+n > size {
+		n = size - off
+	}
+
+	b := make([]byte, n)
+	if _, err := io.ReadFull(res.Body, b); err != nil {
+		return nil, 0, fmt.Errorf("%w: %v", errUnexpectedLength, err)
+	}
+
+	return b, size, nil
+}
+
+// cached returns the cached block with the specified index, if present.
+func (r *ReaderAt) cached(index int64) ([]byte, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	e, ok := r.blocks[index]
+	if !ok {
+		return nil, false
+	}
+
+	r.lru.MoveToFront(e)
+
+	return e.Value.(*block).b, true //nolint:forcetypeassert
+}
+
+// cache adds block b with the specified index to the cache, evicting the least recently used
+// block(s) as necessary.
+func (r *ReaderAt) cache(index int64, b []byte) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.opts.cacheSize == 0 {
+		return
+	}
+
+	if e, ok := r.blocks[index]; ok {
+		r.lru.MoveToFront(e)
+		return
+	}
+
+	for r.lru.Len() >= r.opts.cacheSize {
+		e := r.lru.Back()
+		delete(r.blocks, e.Value.(*block).index) //nolint:forcetypeassert
+		r.lru.Remove(e)
+	}
+
+	r.blocks[index] = r.lru.PushFront(&block{index, b})
+}
+
+// ReadAt reads len(p) bytes into p starting at offset off. It implements the io.ReaderAt
+// interface.
+//
+// Blocks that are not cached are fetched from the server. Where several consecutive blocks are
+// required, they are fetched using a single request.
+func (r *ReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, fmt.Errorf("%w: %v", errNegativeOffset, off)
+	}
+
+	if off >= r.size {
+		return 0, io.EOF
+	}
+
+	end := off + int64(len(p))
+	if end > r.size {
+		end = r.size
+	}
+
+	bs := r.opts.blockSize
+	first, last := off/bs, (end-1)/bs
+
+	var n int
+	for i := first; i <= last; {
+		// Obtain data starting at block i, along with the index of the next block required.
+		b, next := []byte(nil), i+1
+
+		if cb, ok := r.cached(i); ok {
+			b = cb
+		} else {
+			// Fetch this block, and any uncached blocks that follow it.
+			for ; next <= last; next++ {
+				if _, ok := r.cached(next); ok {
+					break
+				}
+			}
+
+			buf, _, err := r.fetch(i*bs, (next-i)*bs)
+			if err != nil {
+				return n, err
+			}
+
+			for k := i; k < next && (k-i)*bs < int64(len(buf)); k++ {
+				lo, hi := (k-i)*bs, (k-i+1)*bs
+				if hi > int64(len(buf)) {
+					hi = int64(len(buf))
+				}
+				r.cache(k, buf[lo:hi])
+			}
+
+			b = buf
+		}
+
+		start := int64(0)
+		if i == first {
+			start = off - i*bs
+		}
+
+		if start > int64(len(b)) {
+			return n, errUnexpectedLength
+		}
+
+		n += copy(p[n:], b[start:])
+
+		i = next
+	}
+
+	if n < len(p) {
+		return n, io.EOF
+	}
+
+	return n, nil
+}
@@ -0,0 +1,338 @@
+// Copyright (c) 2023, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package remote
+
+import (
+	"bytes"
+	"crypto"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/sigstore/sigstore/pkg/cryptoutils"
+	"github.com/sigstore/sigstore/pkg/signature"
+	"github.com/sylabs/sif/v2/pkg/integrity"
+	"github.com/sylabs/sif/v2/pkg/sif"
+)
+
+var corpus = filepath.Join("..", "..", "..", "test", "images")
+
+// newTestServer returns a server that serves b, supporting range requests. The number of requests
+// served is recorded in n.
+func newTestServer(t *testing.T, b []byte, n *int64) *httptest.Server {
+	t.Helper()
+
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(n, 1)
+		http.ServeContent(w, r, "image.sif", time.Time{}, bytes.NewReader(b))
+	}))
+	t.Cleanup(s.Close)
+
+	return s
+}
+
+func TestNewReaderAt(t *testing.T) {
+	var n int64
+	s := newTestServer(t, []byte{0xfa, 0xce}, &n)
+
+	noRange := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte{0xfa, 0xce})
+	}))
+	defer noRange.Close()
+
+	notFound := httptest.NewServer(http.NotFoundHandler())
+	defer notFound.Close()
+
+	empty := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Range", "bytes */0")
+		w.WriteHeader(http.StatusRequestedRangeNotSatisfiable)
+	}))
+	defer empty.Close()
+
+	badRange := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Range", "bytes 0-1")
+		w.WriteHeader(http.StatusPartialContent)
+	}))
+	defer badRange.Close()
+
+	tests := []struct {
+		name     string
+		url      string
+		opts     []ReaderOpt
+		wantErr  error
+		wantSize int64
+	}{
+		{
+			name:     "OK",
+			url:      s.URL,
+			wantSize: 2,
+		},
+		{
+			name:     "OptReaderBlockSize",
+			url:      s.URL,
+			opts:     []ReaderOpt{OptReaderBlockSize(1)},
+			wantSize: 2,
+		},
+		{
+			name:    "OptReaderBlockSizeInvalid",
+			url:     s.URL,
+			opts:    []ReaderOpt{OptReaderBlockSize(0)},
+			wantErr: errInvalidBlockSize,
+		},
+		{
+			name:    "OptReaderCacheSizeInvalid",
+			url:     s.URL,
+			opts:    []ReaderOpt{OptReaderCacheSize(-1)},
+			wantErr: errInvalidCacheSize,
+		},
+		{
+			name:    "RangeNotSupported",
+			url:     noRange.URL,
+			wantErr: errRangeNotSupported,
+		},
+		{
+			name:    "NotFound",
+			url:     notFound.URL,
+			wantErr: &unexpectedStatusError{},
+		},
+		{
+			name:     "Empty",
+			url:      empty.URL,
+			wantSize: 0,
+		},
+		{
+			name:    "InvalidContentRange",
+			url:     badRange.URL,
+			wantErr: errContentRange,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := NewReaderAt(tt.url, tt.opts...)
+			if got, want := err, tt.wantErr; !errors.Is(got, want) {
+				t.Fatalf("got error %v, want %v", got, want)
+			}
+
+			if err == nil {
+				if got, want := r.Size(), tt.wantSize; got != want {
+					t.Errorf("got size %v, want %v", got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestReaderAt_ReadAt(t *testing.T) {
+	b := make([]byte, 1000)
+	for i := range b {
+		b[i] = byte(i)
+	}
+
+	tests := []struct {
+		name         string
+		opts         []ReaderOpt
+		off          int64
+		n            int
+		wantN        int
+		wantErr      error
+		wantRequests int64
+	}{
+		{
+			name:         "FirstBlock",
+			off:          0,
+			n:            64,
+			wantN:        64,
+			wantRequests: 1,
+		},
+		{
+			name:         "WithinBlock",
+			off:          10,
+			n:            20,
+			wantN:        20,
+			wantRequests: 1,
+		},
+		{
+			name:         "SpanBlocks",
+			off:          60,
+			n:            400,
+			wantN:        400,
+			wantRequests: 2,
+		},
+		{
+			name:         "SpanBlocksNoCache",
+			opts:         []ReaderOpt{OptReaderCacheSize(0)},
+			off:          60,
+			n:            400,
+			wantN:        400,
+			wantRequests: 2,
+		},
+		{
+			name:         "End",
+			off:          900,
+			n:            100,
+			wantN:        100,
+			wantRequests: 2,
+		},
+		{
+			name:         "PastEnd",
+			off:          900,
+			n:            200,
+			wantN:        100,
+			wantErr:      io.EOF,
+			wantRequests: 2,
+		},
+		{
+			name:         "EOF",
+			off:          1000,
+			n:            1,
+			wantN:        0,
+			wantErr:      io.EOF,
+			wantRequests: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requests int64
+			s := newTestServer(t, b, &requests)
+
+			opts := append([]ReaderOpt{OptReaderBlockSize(64)}, tt.opts...)
+
+			r, err := NewReaderAt(s.URL, opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			p := make([]byte, tt.n)
+
+			n, err := r.ReadAt(p, tt.off)
+			if got, want := err, tt.wantErr; !errors.Is(got, want) {
+				t.Fatalf("got error %v, want %v", got, want)
+			}
+
+			if got, want := n, tt.wantN; got != want {
+				t.Fatalf("got %v bytes, want %v", got, want)
+			}
+
+			if got, want := p[:n], b[tt.off:tt.off+int64(n)]; !bytes.Equal(got, want) {
+				t.Errorf("got data %v, want %v", got, want)
+			}
+
+			if got, want := atomic.LoadInt64(&requests), tt.wantRequests; got != want {
+				t.Errorf("got %v requests, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestReaderAt_Cache(t *testing.T) {
+	b := make([]byte, 1000)
+	for i := range b {
+		b[i] = byte(i)
+	}
+
+	var requests int64
+	s := newTestServer(t, b, &requests)
+
+	r, err := NewReaderAt(s.URL, OptReaderBlockSize(64), OptReaderCacheSize(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reads := []struct {
+		off          int64
+		wantRequests int64
+	}{
+		{off: 0, wantRequests: 1},   // Block 0 cached by NewReaderAt.
+		{off: 64, wantRequests: 2},  // Block 1 fetched.
+		{off: 0, wantRequests: 2},   // Block 0 cached.
+		{off: 128, wantRequests: 3}, // Block 2 fetched, block 1 evicted.
+		{off: 0, wantRequests: 3},   // Block 0 cached.
+		{off: 64, wantRequests: 4},  // Block 1 fetched, block 2 evicted.
+	}
+
+	for _, rd := range reads {
+		p := make([]byte, 64)
+
+		if _, err := r.ReadAt(p, rd.off); err != nil {
+			t.Fatal(err)
+		}
+
+		if got, want := p, b[rd.off:rd.off+64]; !bytes.Equal(got, want) {
+			t.Errorf("got data %v, want %v", got, want)
+		}
+
+		if got, want := atomic.LoadInt64(&requests), rd.wantRequests; got != want {
+			t.Errorf("offset %v: got %v requests, want %v", rd.off, got, want)
+		}
+	}
+}
+
+func TestReaderAt_Verify(t *testing.T) {
+	b, err := os.ReadFile(filepath.Join(corpus, "one-group-signed-dsse.sif"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var requests int64
+	s := newTestServer(t, b, &requests)
+
+	r, err := NewReaderAt(s.URL, OptReaderBlockSize(4096))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := sif.LoadContainerReader(r, r.Size())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := f.UnloadContainer(); err != nil {
+			t.Error(err)
+		}
+	})
+
+	if got, want := f.DescriptorsTotal()-f.DescriptorsFree(), int64(3); got != want {
+		t.Errorf("got %v descriptors, want %v", got, want)
+	}
+
+	pub, err := cryptoutils.UnmarshalPEMToPublicKey(mustReadFile(t, "ed25519-public.pem"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sv, err := signature.LoadVerifier(pub, crypto.SHA256)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	v, err := integrity.NewVerifier(f, integrity.OptVerifyWithVerifier(sv))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := v.Verify(); err != nil {
+		t.Error(err)
+	}
+}
+
+// mustReadFile returns the contents of the key file with the specified name.
+func mustReadFile(t *testing.T, name string) []byte {
+	t.Helper()
+
+	b, err := os.ReadFile(filepath.Join("..", "..", "..", "test", "keys", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
@@ -0,0 +1,317 @@
+// Copyright (c) 2023, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package sif
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// FixType describes a class of change made to repair an image.
+type FixType int
+
+// List of supported fix types.
+const (
+	FixDescriptorsFree  FixType = iota + 1 // Recompute free descriptor count
+	FixDataSize                            // Recompute data section size
+	FixHeaderArch                          // Set header arch from primary partition
+	FixUnusedDescriptor                    // Zero unused descriptor
+	FixTrailingData                        // Truncate data following data section
+)
+
+// String returns a human-readable representation of t.
+func (t FixType) String() string {
+	switch t {
+	case FixDescriptorsFree:
+		return "Descriptors Free"
+	case FixDataSize:
+		return "Data Size"
+	case FixHeaderArch:
+		return "Header Arch"
+	case FixUnusedDescriptor:
+		return "Unused Descriptor"
+	case FixTrailingData:
+		return "Trailing Data"
+	}
+	return "Unknown"
+}
+
+// Fix describes a change made, or planned, to repair an image.
+type Fix struct {
+	Type    FixType // Type of fix.
+	Message string  // Human-readable description.
+	Skipped bool    // Fix required, but not made because it could not be made safely.
+}
+
+// String returns a human-readable representation of fix.
+func (fix Fix) String() string {
+	if fix.Skipped {
+		return fmt.Sprintf("%v (skipped): %v", fix.Type, fix.Message)
+	}
+	return fmt.Sprintf("%v: %v", fix.Type, fix.Message)
+}
+
+// repairOpts accumulates image repair options.
+type repairOpts struct {
+	dryRun bool
+	t      time.Time
+}
+
+// RepairOpt are used to specify image repair options.
+type RepairOpt func(*repairOpts) error
+
+// OptRepairDryRun specifies whether the required fixes should be determined without modifying
+// the image.
+func OptRepairDryRun(b bool) RepairOpt {
+	return func(ro *repairOpts) error {
+		ro.dryRun = b
+		return nil
+	}
+}
+
+// OptRepairDeterministic sets header/descriptor fields to values that support deterministic
+// modification of images.
+func OptRepairDeterministic() RepairOpt {
+	return func(ro *repairOpts) error {
+		ro.t = time.Time{}
+		return nil
+	}
+}
+
+// OptRepairWithTime specifies t as the image modification time.
+func OptRepairWithTime(t time.Time) RepairOpt {
+	return func(ro *repairOpts) error {
+		ro.t = t
+		return nil
+	}
+}
+
+// truncateUnsafeProblems returns the types of problems in f that make truncation of data
+// following the data section unsafe, since the data may belong to an object whose descriptor
+// is damaged. The caller must hold f.mu.
+func (f *FileImage) truncateUnsafeProblems(size int64) []string {
+	c := checker{
+		f:    f,
+		size: size,
+	}
+
+	c.checkDescriptors()
+	c.checkObjects()
+
+	var types []string
+
+	seen := make(map[ProblemType]bool)
+
+	for _, p := range c.problems {
+		switch p.Type {
+		case ProblemDescriptorsFree, ProblemDuplicateID, ProblemObjectBounds, ProblemObjectOverlap:
+			if !seen[p.Type] {
+				seen[p.Type] = true
+				types = append(types, p.Type.String())
+			}
+		}
+	}
+
+	return types
+}
+
+// primaryArch returns the architecture of the primary system partition in f. If f does not
+// contain a primary system partition, hdrArchUnknown is returned. If f contains multiple primary
+// system partitions, false is returned.
+func (f *FileImage) primaryArch() (archType, bool) {
+	arch := hdrArchUnknown
+
+	n := 0
+
+	for _, rd := range f.rds {
+		if !rd.Used || !rd.isPartitionOfType(PartPrimSys) {
+			continue
+		}
+
+		var p partition
+		if err := rd.getExtra(binaryUnmarshaler{&p}); err != nil {
+			return arch, false
+		}
+
+		arch = p.Arch
+		n++
+	}
+
+	return arch, n <= 1
+}
+
+// Repair repairs the global header and descriptors of f, according to opts, and returns a list of
+// the fixes made. The following fixes are made as required:
+//
+//   - The free descriptor count recorded in the global header is recomputed from the descriptors.
+//   - The data section size recorded in the global header is corrected if the data section
+//     extends beyond the end of the file, or does not include all data objects.
+//   - The architecture recorded in the global header is set from the primary system partition.
+//   - Unused descriptors are zeroed.
+//   - Data following the data section is truncated.
+//
+// Space within the data section that is not occupied by data objects, such as that left by
+// deleting an object without compaction, is not reclaimed. Consider using Compact for this.
+//
+// Problems that cannot be repaired automatically, such as overlapping data objects, are not
+// modified. Consider using Check to identify these. If Check would report an incorrect free
+// descriptor count, duplicate object IDs, or data objects that overlap or extend beyond the end of
+// the file, the descriptors cannot be trusted to describe all data in f, so data following the
+// data section is not truncated, and the corresponding fix is reported as skipped. The data
+// section size is never set to extend beyond the end of the file.
+//
+// To determine the fixes required without modifying f, use OptRepairDryRun.
+//
+// By default, the image modification time is set to the current time for non-deterministic images,
+// and unset otherwise. To override this, consider using OptRepairDeterministic or
+// OptRepairWithTime.
+func (f *FileImage) Repair(opts ...RepairOpt) ([]Fix, error) {
+	f.wmu.Lock()
+	defer f.wmu.Unlock()
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	ro := repairOpts{}
+
+	if !f.isDeterministic() {
+		ro.t = time.Now()
+	}
+
+	for _, opt := range opts {
+		if err := opt(&ro); err != nil {
+			return nil, fmt.Errorf("%w", err)
+		}
+	}
+
+	if f.readOnly && !ro.dryRun {
+		return nil, ErrReadOnly
+	}
+
+	size, err := f.size()
+	if err != nil {
+		return nil, fmt.Errorf("%w", err)
+	}
+
+	var fixes []Fix
+
+	add := func(t FixType, format string, a ...any) {
+		fixes = append(fixes, Fix{Type: t, Message: fmt.Sprintf(format, a...)})
+	}
+
+	h := f.h
+	rds := make([]rawDescriptor, len(f.rds))
+	copy(rds, f.rds)
+
+	// Zero unused descriptors, and compute the free descriptor count and end of the data objects.
+	var free int64
+
+	objEnd := h.DataOffset
+
+	for i, rd := range rds {
+		if !rd.Used {
+			if rd != (rawDescriptor{}) {
+				add(FixUnusedDescriptor, "zero unused descriptor %v", i)
+				rds[i] = rawDescriptor{}
+			}
+			free++
+			continue
+		}
+
+		if e := rd.Offset + rd.Size; e > objEnd {
+			objEnd = e
+		}
+	}
+
+	if h.DescriptorsFree != free {
+		add(FixDescriptorsFree, "set free descriptor count from %v to %v", h.DescriptorsFree, free)
+		h.DescriptorsFree = free
+	}
+
+	// The data section is never extended beyond the end of the file, so data objects that extend
+	// beyond the end of the file are not included in it.
+	limit := size
+	if limit < h.DataOffset {
+		limit = h.DataOffset
+	}
+
+	if objEnd > limit {
+		objEnd = limit
+	}
+
+	// The data section is only resized if it extends beyond the end of the file, or does not
+	// include all data objects.
+	end := h.DataOffset + h.DataSize
+
+	if h.DataSize > 0 && end > size {
+		end = limit
+	}
+
+	if objEnd > end {
+		end = objEnd
+	}
+
+	if dataSize := end - h.DataOffset; h.DataSize != dataSize {
+		add(FixDataSize, "set data size from %v to %v", h.DataSize, dataSize)
+		h.DataSize = dataSize
+	}
+
+	if arch, ok := f.primaryArch(); ok && h.Arch != arch {
+		add(FixHeaderArch, "set header arch from %v to %v", h.Arch.GoArch(), arch.GoArch())
+		h.Arch = arch
+	}
+
+	// Data following the data section is truncated. The file may legitimately end before the data
+	// section if the image is empty.
+	truncate := size > end
+	if truncate {
+		if types := f.truncateUnsafeProblems(size); len(types) > 0 {
+			fixes = append(fixes, Fix{
+				Type: FixTrailingData,
+				Message: fmt.Sprintf("not truncating %v byte(s) following offset %v due to problem(s): %v",
+					size-end, end, strings.Join(types, ", ")),
+				Skipped: true,
+			})
+			truncate = false
+		} else {
+			add(FixTrailingData, "truncate %v byte(s) following offset %v", size-end, end)
+		}
+	}
+
+	// Skipped fixes do not require the image to be modified.
+	required := 0
+	for _, fix := range fixes {
+		if !fix.Skipped {
+			required++
+		}
+	}
+
+	if ro.dryRun || required == 0 {
+		return fixes, nil
+	}
+
+	f.h = h
+	f.rds = rds
+
+	if err := f.writeDescriptors(); err != nil {
+		return nil, fmt.Errorf("%w", err)
+	}
+
+	f.h.ModifiedAt = ro.t.Unix()
+
+	if err := f.writeHeader(); err != nil {
+		return nil, fmt.Errorf("%w", err)
+	}
+
+	if truncate {
+		if err := f.rw.Truncate(end); err != nil {
+			return nil, fmt.Errorf("%w", err)
+		}
+	}
+
+	return fixes, nil
+}
@@ -0,0 +1,330 @@
+// Copyright (c) 2023, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package sif
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/sebdah/goldie/v2"
+)
+
+//nolint:maintidx
+func TestFileImage_Repair(t *testing.T) {
+	createOpts := func() []CreateOpt {
+		return []CreateOpt{
+			OptCreateDeterministic(),
+			OptCreateWithDescriptorCapacity(4),
+			OptCreateWithDescriptors(
+				getDescriptorInput(t, DataPartition, []byte{0xfa, 0xce},
+					OptPartitionMetadata(FsSquash, PartPrimSys, "386"),
+				),
+				getDescriptorInput(t, DataGeneric, []byte{0xfe, 0xed}),
+			),
+		}
+	}
+
+	tests := []struct {
+		name         string
+		createOpts   []CreateOpt
+		modify       func(t *testing.T, f *FileImage, b *Buffer)
+		opts         []RepairOpt
+		dryRun       bool
+		wantFixes    []FixType
+		wantSkipped  []FixType
+		wantProblems []ProblemType
+	}{
+		{
+			name: "Empty",
+			createOpts: []CreateOpt{
+				OptCreateDeterministic(),
+			},
+		},
+		{
+			name:       "OK",
+			createOpts: createOpts(),
+		},
+		{
+			name:       "DescriptorsFree",
+			createOpts: createOpts(),
+			modify: func(t *testing.T, f *FileImage, b *Buffer) {
+				f.h.DescriptorsFree = 4
+			},
+			wantFixes: []FixType{FixDescriptorsFree},
+		},
+		{
+			name:       "DataSize",
+			createOpts: createOpts(),
+			modify: func(t *testing.T, f *FileImage, b *Buffer) {
+				f.h.DataSize = 0
+			},
+			wantFixes: []FixType{FixDataSize},
+		},
+		{
+			name:       "HeaderArch",
+			createOpts: createOpts(),
+			modify: func(t *testing.T, f *FileImage, b *Buffer) {
+				f.h.Arch = hdrArchAMD64
+			},
+			wantFixes: []FixType{FixHeaderArch},
+		},
+		{
+			name: "HeaderArchNoPrimary",
+			createOpts: []CreateOpt{
+				OptCreateDeterministic(),
+			},
+			modify: func(t *testing.T, f *FileImage, b *Buffer) {
+				f.h.Arch = hdrArchAMD64
+			},
+			wantFixes: []FixType{FixHeaderArch},
+		},
+		{
+			name:       "UnusedDescriptor",
+			createOpts: createOpts(),
+			modify: func(t *testing.T, f *FileImage, b *Buffer) {
+				f.rds[3].ID = 4
+				f.rds[3].Size = 2
+			},
+			wantFixes: []FixType{FixUnusedDescriptor},
+		},
+		{
+			name:       "TrailingData",
+			createOpts: createOpts(),
+			modify: func(t *testing.T, f *FileImage, b *Buffer) {
+				if _, err := b.Seek(0, io.SeekEnd); err != nil {
+					t.Fatal(err)
+				}
+
+				if _, err := b.Write([]byte{0xde, 0xad, 0xbe, 0xef}); err != nil {
+					t.Fatal(err)
+				}
+			},
+			wantFixes: []FixType{FixTrailingData},
+		},
+		{
+			name:       "InterruptedAdd",
+			createOpts: createOpts(),
+			modify: func(t *testing.T, f *FileImage, b *Buffer) {
+				// Simulate an add that wrote data and a descriptor, but not the header.
+				if err := f.AddObject(getDescriptorInput(t, DataGeneric, []byte{0xde, 0xad})); err != nil {
+					t.Fatal(err)
+				}
+
+				f.h.DescriptorsFree++
+				f.h.DataSize -= f.rds[2].SizeWithPadding
+			},
+			wantFixes: []FixType{FixDescriptorsFree, FixDataSize},
+		},
+		{
+			name:       "InterruptedDelete",
+			createOpts: createOpts(),
+			modify: func(t *testing.T, f *FileImage, b *Buffer) {
+				// Simulate a delete that reset the descriptor, but was interrupted before the header
+				// was written.
+				f.rds[1] = rawDescriptor{}
+			},
+			wantFixes: []FixType{FixDescriptorsFree},
+		},
+		{
+			name:       "DeleteNoCompact",
+			createOpts: createOpts(),
+			modify: func(t *testing.T, f *FileImage, b *Buffer) {
+				// Space left by deleting the last object is not damage.
+				if err := f.DeleteObject(2); err != nil {
+					t.Fatal(err)
+				}
+			},
+		},
+		{
+			name:       "DataSizeBeyondEOF",
+			createOpts: createOpts(),
+			modify: func(t *testing.T, f *FileImage, b *Buffer) {
+				f.h.DataSize += 1000
+			},
+			wantFixes: []FixType{FixDataSize},
+		},
+		{
+			name:       "TrailingDataOverlap",
+			createOpts: createOpts(),
+			modify: func(t *testing.T, f *FileImage, b *Buffer) {
+				f.rds[1].Offset = f.rds[0].Offset
+
+				if _, err := b.Seek(0, io.SeekEnd); err != nil {
+					t.Fatal(err)
+				}
+
+				if _, err := b.Write([]byte{0xde, 0xad, 0xbe, 0xef}); err != nil {
+					t.Fatal(err)
+				}
+			},
+			wantSkipped: []FixType{FixTrailingData},
+		},
+		{
+			name:       "TrailingDataOverlapFixed",
+			createOpts: createOpts(),
+			modify: func(t *testing.T, f *FileImage, b *Buffer) {
+				f.rds[1].Offset = f.rds[0].Offset
+				f.h.DataSize = 0
+
+				if _, err := b.Seek(0, io.SeekEnd); err != nil {
+					t.Fatal(err)
+				}
+
+				if _, err := b.Write([]byte{0xde, 0xad, 0xbe, 0xef}); err != nil {
+					t.Fatal(err)
+				}
+			},
+			wantFixes:    []FixType{FixDataSize},
+			wantSkipped:  []FixType{FixTrailingData},
+			wantProblems: []ProblemType{ProblemTrailingData, ProblemObjectOverlap},
+		},
+		{
+			name:       "ObjectBounds",
+			createOpts: createOpts(),
+			modify: func(t *testing.T, f *FileImage, b *Buffer) {
+				f.rds[1].Size = 1 << 20
+			},
+		},
+		{
+			name:       "DryRun",
+			createOpts: createOpts(),
+			modify: func(t *testing.T, f *FileImage, b *Buffer) {
+				f.h.DescriptorsFree = 4
+				f.h.Arch = hdrArchAMD64
+			},
+			opts: []RepairOpt{
+				OptRepairDryRun(true),
+			},
+			dryRun:    true,
+			wantFixes: []FixType{FixDescriptorsFree, FixHeaderArch},
+		},
+		{
+			name:       "WithTime",
+			createOpts: createOpts(),
+			modify: func(t *testing.T, f *FileImage, b *Buffer) {
+				f.h.DescriptorsFree = 4
+			},
+			opts: []RepairOpt{
+				OptRepairWithTime(time.Unix(946702800, 0)),
+			},
+			wantFixes: []FixType{FixDescriptorsFree},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var b Buffer
+
+			f, err := CreateContainer(&b, tt.createOpts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if tt.modify != nil {
+				tt.modify(t, f, &b)
+
+				// Write the modified header and descriptors to the buffer, and reload the image.
+				if err := f.writeDescriptors(); err != nil {
+					t.Fatal(err)
+				}
+
+				if err := f.writeHeader(); err != nil {
+					t.Fatal(err)
+				}
+
+				if f, err = LoadContainer(&b); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			before := bytes.Clone(b.Bytes())
+
+			fixes, err := f.Repair(tt.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var got, gotSkipped []FixType
+			for _, fix := range fixes {
+				if fix.Skipped {
+					gotSkipped = append(gotSkipped, fix.Type)
+				} else {
+					got = append(got, fix.Type)
+				}
+			}
+
+			if want := tt.wantFixes; !reflect.DeepEqual(got, want) {
+				t.Errorf("got fixes %v, want %v", fixes, want)
+			}
+
+			if want := tt.wantSkipped; !reflect.DeepEqual(gotSkipped, want) {
+				t.Errorf("got skipped fixes %v, want %v", fixes, want)
+			}
+
+			// The image must not be modified if no fixes are required, or on a dry run.
+			if tt.dryRun || len(tt.wantFixes) == 0 {
+				if !bytes.Equal(b.Bytes(), before) {
+					t.Error("image modified")
+				}
+				return
+			}
+
+			problems, err := Check(f)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var gotProblems []ProblemType
+			for _, p := range problems {
+				gotProblems = append(gotProblems, p.Type)
+			}
+
+			if want := tt.wantProblems; !reflect.DeepEqual(gotProblems, want) {
+				t.Errorf("got problems %v after repair, want %v", problems, want)
+			}
+
+			if err := f.UnloadContainer(); err != nil {
+				t.Error(err)
+			}
+
+			g := goldie.New(t, goldie.WithTestNameForDir(true))
+			g.Assert(t, tt.name, b.Bytes())
+		})
+	}
+}
+
+func TestFileImage_Repair_ReadOnly(t *testing.T) {
+	b, err := os.ReadFile(filepath.Join(corpus, "one-group.sif"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := LoadContainerReader(bytes.NewReader(b), int64(len(b)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := f.UnloadContainer(); err != nil {
+			t.Error(err)
+		}
+	})
+
+	if _, err := f.Repair(); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("got error %v, want %v", err, ErrReadOnly)
+	}
+
+	if fixes, err := f.Repair(OptRepairDryRun(true)); err != nil {
+		t.Errorf("got error %v, want nil", err)
+	} else if len(fixes) > 0 {
+		t.Errorf("got fixes %v, want none", fixes)
+	}
+}
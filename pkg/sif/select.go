@@ -113,29 +113,98 @@ func (f *FileImage) descriptorFromRaw(rd *rawDescriptor) Descriptor {
 	}
 }
 
-// GetDescriptors returns a slice of in-use descriptors for which all selector funcs return true.
-// If the image contains no data objects, an error wrapping ErrNoObjects is returned.
-func (f *FileImage) GetDescriptors(fns ...DescriptorSelectorFunc) ([]Descriptor, error) {
-	if f.DescriptorsFree() == f.DescriptorsTotal() {
+// descriptors returns copies of the in-use descriptors in f. The caller must hold f.mu.
+func (f *FileImage) descriptors() []Descriptor {
+	var ds []Descriptor
+
+	for i := range f.rds {
+		if f.rds[i].Used {
+			ds = append(ds, f.descriptorFromRaw(&f.rds[i]))
+		}
+	}
+
+	return ds
+}
+
+// objectDescriptors returns copies of the in-use descriptors in f. If the image contains no data
+// objects, an error wrapping ErrNoObjects is returned. The caller must hold f.mu.
+func (f *FileImage) objectDescriptors() ([]Descriptor, error) {
+	if f.h.DescriptorsFree == f.h.DescriptorsTotal {
 		return nil, fmt.Errorf("%w", ErrNoObjects)
 	}
 
-	var ds []Descriptor
+	return f.descriptors(), nil
+}
 
-	err := f.withDescriptors(multiSelectorFunc(fns...), func(d *rawDescriptor) error {
-		ds = append(ds, f.descriptorFromRaw(d))
-		return nil
-	})
+// snapshotDescriptors returns copies of the in-use descriptors in f, such that selector funcs can
+// be applied to them without holding f.mu. If the image contains no data objects, an error
+// wrapping ErrNoObjects is returned.
+func (f *FileImage) snapshotDescriptors() ([]Descriptor, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	return f.objectDescriptors()
+}
+
+// selectDescriptors returns the descriptors in ds for which all selector funcs return true.
+func selectDescriptors(ds []Descriptor, fns ...DescriptorSelectorFunc) ([]Descriptor, error) {
+	selectFn := multiSelectorFunc(fns...)
+
+	var selected []Descriptor
+
+	for _, d := range ds {
+		if ok, err := selectFn(d); err != nil {
+			return nil, fmt.Errorf("%w", err)
+		} else if ok {
+			selected = append(selected, d)
+		}
+	}
+
+	return selected, nil
+}
+
+// selectDescriptor returns the descriptor in ds selected by fns. If no descriptor is selected by
+// fns, an error wrapping ErrObjectNotFound is returned. If multiple descriptors are selected by
+// fns, an error wrapping ErrMultipleObjectsFound is returned.
+func selectDescriptor(ds []Descriptor, fns ...DescriptorSelectorFunc) (Descriptor, error) {
+	selectFn := multiSelectorFunc(fns...)
+
+	var d *Descriptor
+
+	for i := range ds {
+		if ok, err := selectFn(ds[i]); err != nil {
+			return Descriptor{}, fmt.Errorf("%w", err)
+		} else if !ok {
+			continue
+		}
+
+		if d != nil {
+			return Descriptor{}, fmt.Errorf("%w", ErrMultipleObjectsFound)
+		}
+		d = &ds[i]
+	}
+
+	if d == nil {
+		return Descriptor{}, fmt.Errorf("%w", ErrObjectNotFound)
+	}
+
+	return *d, nil
+}
+
+// GetDescriptors returns a slice of in-use descriptors for which all selector funcs return true.
+// If the image contains no data objects, an error wrapping ErrNoObjects is returned.
+func (f *FileImage) GetDescriptors(fns ...DescriptorSelectorFunc) ([]Descriptor, error) {
+	ds, err := f.snapshotDescriptors()
 	if err != nil {
-		return nil, fmt.Errorf("%w", err)
+		return nil, err
 	}
 
-	return ds, nil
+	return selectDescriptors(ds, fns...)
 }
 
 // getDescriptor returns a pointer to the in-use descriptor selected by fns. If no descriptor is
 // selected by fns, ErrObjectNotFound is returned. If multiple descriptors are selected by fns,
-// ErrMultipleObjectsFound is returned.
+// ErrMultipleObjectsFound is returned. The caller must hold f.mu.
 func (f *FileImage) getDescriptor(fns ...DescriptorSelectorFunc) (*rawDescriptor, error) {
 	var d *rawDescriptor
 
@@ -159,16 +228,12 @@ func (f *FileImage) getDescriptor(fns ...DescriptorSelectorFunc) (*rawDescriptor
 // error wrapping ErrObjectNotFound is returned. If multiple descriptors are selected by fns, an
 // error wrapping ErrMultipleObjectsFound is returned.
 func (f *FileImage) GetDescriptor(fns ...DescriptorSelectorFunc) (Descriptor, error) {
-	if f.DescriptorsFree() == f.DescriptorsTotal() {
-		return Descriptor{}, fmt.Errorf("%w", ErrNoObjects)
-	}
-
-	d, err := f.getDescriptor(fns...)
+	ds, err := f.snapshotDescriptors()
 	if err != nil {
-		return Descriptor{}, fmt.Errorf("%w", err)
+		return Descriptor{}, err
 	}
 
-	return f.descriptorFromRaw(d), nil
+	return selectDescriptor(ds, fns...)
 }
 
 // multiSelectorFunc returns a DescriptorSelectorFunc that selects a descriptor iff all of fns
@@ -186,7 +251,7 @@ func multiSelectorFunc(fns ...DescriptorSelectorFunc) DescriptorSelectorFunc {
 
 // withDescriptors calls onMatchFn with each in-use descriptor in f for which selectFn returns
 // true. If selectFn or onMatchFn return a non-nil error, the iteration halts, and the error is
-// returned to the caller.
+// returned to the caller. The caller must hold f.mu.
 func (f *FileImage) withDescriptors(selectFn DescriptorSelectorFunc, onMatchFn func(*rawDescriptor) error) error {
 	for i, d := range f.rds {
 		if !d.Used {
@@ -215,8 +280,13 @@ func abortOnMatch(*rawDescriptor) error { return errAbort }
 
 // WithDescriptors calls fn with each in-use descriptor in f, until fn returns true.
 func (f *FileImage) WithDescriptors(fn func(d Descriptor) bool) {
-	selectFn := func(d Descriptor) (bool, error) {
-		return fn(d), nil
+	f.mu.RLock()
+	ds := f.descriptors()
+	f.mu.RUnlock()
+
+	for _, d := range ds {
+		if fn(d) {
+			return
+		}
 	}
-	_ = f.withDescriptors(selectFn, abortOnMatch)
 }
@@ -78,6 +78,7 @@ import (
 	"bytes"
 	"fmt"
 	"io"
+	"sync"
 	"time"
 
 	"github.com/google/uuid"
@@ -352,65 +353,84 @@ type ReadWriter interface {
 }
 
 // FileImage describes the representation of a SIF file in memory.
+//
+// A FileImage is safe for concurrent use by multiple goroutines. Note that a Descriptor reads data
+// from the backing storage of the image, so concurrent reads of data objects alongside
+// modification of the image require backing storage that supports this, such as an *os.File.
 type FileImage struct {
 	rw ReadWriter // Backing storage for image.
 
+	wmu sync.Mutex      // Serializes modification of image.
+	mu  sync.RWMutex    // Protects h, rds and minIDs.
 	h   header          // Raw global header from image.
 	rds []rawDescriptor // Raw descriptors from image.
 
+	readOnly      bool              // Image loaded without write capability.
 	closeOnUnload bool              // Close rw on Unload.
 	minIDs        map[uint32]uint32 // Minimum object IDs for each group ID.
 }
 
+// getHeader returns a copy of the global header of f.
+func (f *FileImage) getHeader() header {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	return f.h
+}
+
 // LaunchScript returns the image launch script.
 func (f *FileImage) LaunchScript() string {
-	return string(bytes.TrimRight(f.h.LaunchScript[:], "\x00"))
+	h := f.getHeader()
+	return string(bytes.TrimRight(h.LaunchScript[:], "\x00"))
 }
 
 // Version returns the SIF specification version of the image.
 func (f *FileImage) Version() string {
-	return string(bytes.TrimRight(f.h.Version[:], "\x00"))
+	h := f.getHeader()
+	return string(bytes.TrimRight(h.Version[:], "\x00"))
 }
 
 // PrimaryArch returns the primary CPU architecture of the image, or "unknown" if the primary CPU
 // architecture cannot be determined.
-func (f *FileImage) PrimaryArch() string { return f.h.Arch.GoArch() }
+func (f *FileImage) PrimaryArch() string { return f.getHeader().Arch.GoArch() }
 
 // ID returns the ID of the image.
-func (f *FileImage) ID() string { return f.h.ID.String() }
+func (f *FileImage) ID() string { return f.getHeader().ID.String() }
 
 // CreatedAt returns the creation time of the image.
-func (f *FileImage) CreatedAt() time.Time { return time.Unix(f.h.CreatedAt, 0) }
+func (f *FileImage) CreatedAt() time.Time { return time.Unix(f.getHeader().CreatedAt, 0) }
 
 // ModifiedAt returns the last modification time of the image.
-func (f *FileImage) ModifiedAt() time.Time { return time.Unix(f.h.ModifiedAt, 0) }
+func (f *FileImage) ModifiedAt() time.Time { return time.Unix(f.getHeader().ModifiedAt, 0) }
 
 // DescriptorsFree returns the number of free descriptors in the image.
-func (f *FileImage) DescriptorsFree() int64 { return f.h.DescriptorsFree }
+func (f *FileImage) DescriptorsFree() int64 { return f.getHeader().DescriptorsFree }
 
 // DescriptorsTotal returns the total number of descriptors in the image.
-func (f *FileImage) DescriptorsTotal() int64 { return f.h.DescriptorsTotal }
+func (f *FileImage) DescriptorsTotal() int64 { return f.getHeader().DescriptorsTotal }
 
 // DescriptorsOffset returns the offset (in bytes) of the descriptors section in the image.
-func (f *FileImage) DescriptorsOffset() int64 { return f.h.DescriptorsOffset }
+func (f *FileImage) DescriptorsOffset() int64 { return f.getHeader().DescriptorsOffset }
 
 // DescriptorsSize returns the size (in bytes) of the descriptors section in the image.
-func (f *FileImage) DescriptorsSize() int64 { return f.h.DescriptorsSize }
+func (f *FileImage) DescriptorsSize() int64 { return f.getHeader().DescriptorsSize }
 
 // DataOffset returns the offset (in bytes) of the data section in the image.
-func (f *FileImage) DataOffset() int64 { return f.h.DataOffset }
+func (f *FileImage) DataOffset() int64 { return f.getHeader().DataOffset }
 
 // DataSize returns the size (in bytes) of the data section in the image.
-func (f *FileImage) DataSize() int64 { return f.h.DataSize }
+func (f *FileImage) DataSize() int64 { return f.getHeader().DataSize }
 
 // GetHeaderIntegrityReader returns an io.Reader that reads the integrity-protected fields from the
 // header of the image.
 func (f *FileImage) GetHeaderIntegrityReader() io.Reader {
-	return f.h.GetIntegrityReader()
+	return f.getHeader().GetIntegrityReader()
 }
 
 // isDeterministic returns true if the UUID and timestamps in the header of f are set to
-// deterministic values.
+// deterministic values. The caller must hold f.mu.
 func (f *FileImage) isDeterministic() bool {
-	return f.h.ID == uuid.Nil && f.CreatedAt().IsZero() && f.ModifiedAt().IsZero()
+	return f.h.ID == uuid.Nil &&
+		time.Unix(f.h.CreatedAt, 0).IsZero() &&
+		time.Unix(f.h.ModifiedAt, 0).IsZero()
 }
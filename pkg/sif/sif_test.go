@@ -6,8 +6,12 @@ package sif
 
 import (
 	"bytes"
+	"errors"
+	"fmt"
 	"io"
 	"path/filepath"
+	"runtime"
+	"sync"
 	"testing"
 
 	"github.com/google/uuid"
@@ -101,3 +105,201 @@ func TestHeader_GetIntegrityReader(t *testing.T) {
 		})
 	}
 }
+
+// TestFileImage_Concurrent exercises concurrent reads and modifications of an image. It is most
+// useful when run with the race detector enabled.
+func TestFileImage_Concurrent(t *testing.T) {
+	const (
+		writers = 4
+		objects = 8
+	)
+
+	f, err := CreateContainerAtPath(filepath.Join(t.TempDir(), "image.sif"),
+		OptCreateWithDescriptorCapacity(2*writers*objects),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := f.UnloadContainer(); err != nil {
+			t.Error(err)
+		}
+	})
+
+	// written records the names of objects added to the image.
+	var written sync.Map
+
+	withName := func(name string) DescriptorSelectorFunc {
+		return func(d Descriptor) (bool, error) { return d.Name() == name, nil }
+	}
+
+	objectData := func(w, i int) (string, []byte) {
+		name := fmt.Sprintf("writer-%v-object-%v", w, i)
+		return name, []byte(name)
+	}
+
+	var writersWG sync.WaitGroup
+
+	for w := 0; w < writers; w++ {
+		w := w
+
+		writersWG.Add(2)
+
+		// Add objects one at a time.
+		go func() {
+			defer writersWG.Done()
+
+			for i := 0; i < objects; i++ {
+				name, b := objectData(w, i)
+
+				di := getDescriptorInput(t, DataGeneric, b, OptObjectName(name))
+				if err := f.AddObject(di); err != nil {
+					t.Error(err)
+					return
+				}
+
+				written.Store(name, b)
+			}
+		}()
+
+		// Add objects in a batch.
+		go func() {
+			defer writersWG.Done()
+
+			err := f.Update(func(tx *Tx) error {
+				for i := objects; i < 2*objects; i++ {
+					name, b := objectData(w, i)
+
+					di := getDescriptorInput(t, DataGeneric, b, OptObjectName(name))
+					if _, err := tx.AddObject(di); err != nil {
+						return err
+					}
+
+					if _, err := tx.GetDescriptor(withName(name)); err != nil {
+						return err
+					}
+				}
+				return nil
+			})
+			if err != nil {
+				t.Error(err)
+				return
+			}
+
+			for i := objects; i < 2*objects; i++ {
+				name, b := objectData(w, i)
+				written.Store(name, b)
+			}
+		}()
+	}
+
+	done := make(chan struct{})
+
+	var readersWG sync.WaitGroup
+
+	// checkDescriptor checks that the data of d matches what was written.
+	checkDescriptor := func(d Descriptor) {
+		b, err := d.GetData()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		if got, want := string(b), d.Name(); got != want {
+			t.Errorf("got data %q, want %q", got, want)
+		}
+	}
+
+	readers := []func(){
+		func() {
+			ds, err := f.GetDescriptors(WithDataType(DataGeneric))
+			if err != nil && !errors.Is(err, ErrNoObjects) {
+				t.Error(err)
+			}
+
+			for _, d := range ds {
+				checkDescriptor(d)
+			}
+		},
+		func() {
+			f.WithDescriptors(func(d Descriptor) bool {
+				checkDescriptor(d)
+				return false
+			})
+		},
+		func() {
+			d, err := f.GetDescriptor(WithID(1))
+			if err == nil {
+				checkDescriptor(d)
+			} else if !errors.Is(err, ErrNoObjects) && !errors.Is(err, ErrObjectNotFound) {
+				t.Error(err)
+			}
+		},
+		func() {
+			if free, total := f.DescriptorsFree(), f.DescriptorsTotal(); free > total {
+				t.Errorf("got %v free descriptors, want at most %v", free, total)
+			}
+		},
+		func() {
+			if _, err := Check(f); err != nil {
+				t.Error(err)
+			}
+		},
+	}
+
+	for _, read := range readers {
+		read := read
+
+		readersWG.Add(1)
+
+		go func() {
+			defer readersWG.Done()
+
+			for {
+				select {
+				case <-done:
+					return
+				default:
+					read()
+
+					// Yield, so that writers make progress when few CPUs are available.
+					runtime.Gosched()
+				}
+			}
+		}()
+	}
+
+	writersWG.Wait()
+	close(done)
+	readersWG.Wait()
+
+	// Verify the final state of the image.
+	problems, err := Check(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, p := range problems {
+		t.Errorf("unexpected problem: %v", p)
+	}
+
+	if got, want := f.DescriptorsFree(), int64(0); got != want {
+		t.Errorf("got %v free descriptors, want %v", got, want)
+	}
+
+	written.Range(func(k, _ any) bool {
+		name, ok := k.(string)
+		if !ok {
+			t.Fatalf("unexpected key type %T", k)
+		}
+
+		d, err := f.GetDescriptor(withName(name))
+		if err != nil {
+			t.Error(err)
+			return true
+		}
+
+		checkDescriptor(d)
+		return true
+	})
+}
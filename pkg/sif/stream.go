@@ -0,0 +1,203 @@
+// Copyright (c) 2023, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package sif
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// countingWriter wraps an io.Writer, tracking the number of bytes written.
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (cw *countingWriter) Write(b []byte) (int, error) {
+	n, err := cw.w.Write(b)
+	cw.n += int64(n)
+	return n, err
+}
+
+var errPadOffset = errors.New("cannot pad to offset")
+
+// padTo writes zeros to cw until offset bytes have been written.
+func (cw *countingWriter) padTo(offset int64) error {
+	if offset < cw.n {
+		return fmt.Errorf("%w %v, %v bytes already written", errPadOffset, offset, cw.n)
+	}
+
+	_, err := io.CopyN(cw, zeroReader{}, offset-cw.n)
+	return err
+}
+
+// streamObject is a data object to be written by WriteContainer.
+type streamObject struct {
+	r    io.Reader
+	size int64
+	d    *rawDescriptor
+}
+
+// spool copies the data object described by di to a temporary file, returning the file and the
+// number of bytes copied. The caller is responsible for closing and removing the file.
+func spool(di DescriptorInput) (*os.File, int64, error) {
+	f, err := os.CreateTemp("", "sif-spool-*")
+	if err != nil {
+		return nil, 0, err
+	}
+
+	n, err := io.Copy(f, di.r)
+	if err == nil {
+		_, err = f.Seek(0, io.SeekStart)
+	}
+
+	if err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return nil, 0, err
+	}
+
+	return f, n, nil
+}
+
+// copyObject copies exactly size bytes from r to w. An error is returned if r does not contain
+// exactly size bytes.
+func copyObject(w io.Writer, r io.Reader, size int64) error {
+	n, err := io.CopyN(w, r, size)
+	if errors.Is(err, io.EOF) {
+		return fmt.Errorf("%w: got %v bytes, want %v", errObjectSizeMismatch, n, size)
+	} else if err != nil {
+		return err
+	}
+
+	// Check there is no data beyond the expected size.
+	if m, err := io.Copy(io.Discard, r); err != nil {
+		return err
+	} else if m > 0 {
+		return fmt.Errorf("%w: got %v bytes, want %v", errObjectSizeMismatch, n+m, size)
+	}
+
+	return nil
+}
+
+// layoutDataObject records details of the data object described by di in the descriptor at index
+// i of f, using time t. The data object is assumed to contain size bytes, and is placed at the end
+// of the data section of f.
+func (f *FileImage) layoutDataObject(i int, di DescriptorInput, size int64, t time.Time) (*rawDescriptor, error) { //nolint:lll
+	d, err := f.reserveDescriptor(i, di)
+	if err != nil {
+		return nil, err
+	}
+
+	offsetUnaligned := f.h.DataOffset + f.h.DataSize
+	offset := nextAligned(offsetUnaligned, di.opts.alignment)
+
+	if err := di.fillDescriptor(t, d); err != nil {
+		return nil, err
+	}
+	d.Used = true
+	d.Offset = offset
+	d.Size = size
+	d.SizeWithPadding = offset - offsetUnaligned + size
+
+	// Update minimum object ID map.
+	if minID, ok := f.minIDs[d.GroupID]; !ok || d.ID < minID {
+		f.minIDs[d.GroupID] = d.ID
+	}
+
+	f.h.DescriptorsFree--
+	f.h.DataSize += d.SizeWithPadding
+
+	return d, nil
+}
+
+// WriteContainer writes a new SIF container to w, according to opts. Unlike CreateContainer, w
+// is written sequentially, and so need not support seeking. This makes WriteContainer suitable
+// for writing to pipes, network connections, and other non-seekable outputs.
+//
+// Since the global header and descriptors precede the data objects in the output, the size of
+// each data object must be known before any data is written. Data objects with a size specified
+// via OptObjectSize are copied directly to w. Other data objects, as well as OCI blobs (whose
+// digest must be computed up front) are first buffered in temporary files.
+//
+// The options supported by WriteContainer are the same as those of CreateContainer, and the
+// output is identical to that of CreateContainer when given the same options and a deterministic
+// image ID and timestamp (see OptCreateDeterministic).
+func WriteContainer(w io.Writer, opts ...CreateOpt) error {
+	co, err := getCreateOpts(opts...)
+	if err != nil {
+		return fmt.Errorf("%w", err)
+	}
+
+	f := newFileImage(nil, co)
+
+	// Determine the size of each data object, spooling where necessary, and lay out descriptors.
+	objects := make([]streamObject, 0, len(co.dis))
+
+	var spooled []*os.File
+	defer func() {
+		for _, sf := range spooled {
+			sf.Close()
+			os.Remove(sf.Name())
+		}
+	}()
+
+	for i, di := range co.dis {
+		r, size := di.r, di.opts.size
+
+		if size < 0 || di.dt == DataOCIRootIndex || di.dt == DataOCIBlob {
+			sf, n, err := spool(di)
+			if err != nil {
+				return fmt.Errorf("%w", err)
+			}
+			spooled = append(spooled, sf)
+
+			if size >= 0 && n != size {
+				return fmt.Errorf("%w: got %v bytes, want %v", errObjectSizeMismatch, n, size)
+			}
+
+			r, size = sf, n
+		}
+
+		d, err := f.layoutDataObject(i, di, size, co.t)
+		if err != nil {
+			return fmt.Errorf("%w", err)
+		}
+
+		objects = append(objects, streamObject{r: r, size: size, d: d})
+	}
+
+	// Write global header, descriptors, and data objects, in order.
+	cw := &countingWriter{w: w}
+
+	if err := binary.Write(cw, binary.LittleEndian, f.h); err != nil {
+		return fmt.Errorf("%w", err)
+	}
+
+	if err := cw.padTo(f.h.DescriptorsOffset); err != nil {
+		return fmt.Errorf("%w", err)
+	}
+
+	if err := binary.Write(cw, binary.LittleEndian, f.rds); err != nil {
+		return fmt.Errorf("%w", err)
+	}
+
+	for _, o := range objects {
+		if err := cw.padTo(o.d.Offset); err != nil {
+			return fmt.Errorf("%w", err)
+		}
+
+		if err := copyObject(cw, o.r, o.size); err != nil {
+			return fmt.Errorf("%w", err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,234 @@
+// Copyright (c) 2023, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package sif
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestWriteContainer(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    func(t *testing.T) []CreateOpt
+		wantErr error
+	}{
+		{
+			name: "ErrInsufficientCapacity",
+			opts: func(t *testing.T) []CreateOpt {
+				return []CreateOpt{
+					OptCreateDeterministic(),
+					OptCreateWithDescriptorCapacity(0),
+					OptCreateWithDescriptors(
+						getDescriptorInput(t, DataGeneric, []byte{0xfa, 0xce}),
+					),
+				}
+			},
+			wantErr: errInsufficientCapacity,
+		},
+		{
+			name: "ErrPrimaryPartition",
+			opts: func(t *testing.T) []CreateOpt {
+				return []CreateOpt{
+					OptCreateDeterministic(),
+					OptCreateWithDescriptors(
+						getDescriptorInput(t, DataPartition, []byte{0xfa, 0xce},
+							OptPartitionMetadata(FsSquash, PartPrimSys, "386"),
+						),
+						getDescriptorInput(t, DataPartition, []byte{0xfe, 0xed},
+							OptPartitionMetadata(FsSquash, PartPrimSys, "amd64"),
+						),
+					),
+				}
+			},
+			wantErr: errPrimaryPartition,
+		},
+		{
+			name: "ErrObjectSizeShort",
+			opts: func(t *testing.T) []CreateOpt {
+				return []CreateOpt{
+					OptCreateDeterministic(),
+					OptCreateWithDescriptors(
+						getDescriptorInput(t, DataGeneric, []byte{0xfa, 0xce},
+							OptObjectSize(3),
+						),
+					),
+				}
+			},
+			wantErr: errObjectSizeMismatch,
+		},
+		{
+			name: "ErrObjectSizeLong",
+			opts: func(t *testing.T) []CreateOpt {
+				return []CreateOpt{
+					OptCreateDeterministic(),
+					OptCreateWithDescriptors(
+						getDescriptorInput(t, DataGeneric, []byte{0xfa, 0xce},
+							OptObjectSize(1),
+						),
+					),
+				}
+			},
+			wantErr: errObjectSizeMismatch,
+		},
+		{
+			name: "ErrOCIBlobSizeMismatch",
+			opts: func(t *testing.T) []CreateOpt {
+				return []CreateOpt{
+					OptCreateDeterministic(),
+					OptCreateWithDescriptors(
+						getDescriptorInput(t, DataOCIBlob, []byte{0xfa, 0xce},
+							OptObjectSize(1),
+						),
+					),
+				}
+			},
+			wantErr: errObjectSizeMismatch,
+		},
+		{
+			name: "Empty",
+			opts: func(*testing.T) []CreateOpt {
+				return []CreateOpt{
+					OptCreateDeterministic(),
+				}
+			},
+		},
+		{
+			name: "EmptyLaunchScript",
+			opts: func(*testing.T) []CreateOpt {
+				return []CreateOpt{
+					OptCreateDeterministic(),
+					OptCreateWithLaunchScript("#!/usr/bin/env launch-script\n"),
+				}
+			},
+		},
+		{
+			name: "EmptyDescriptorLimitedCapacity",
+			opts: func(*testing.T) []CreateOpt {
+				return []CreateOpt{
+					OptCreateDeterministic(),
+					OptCreateWithDescriptorCapacity(1),
+				}
+			},
+		},
+		{
+			name: "OneDescriptor",
+			opts: func(t *testing.T) []CreateOpt {
+				return []CreateOpt{
+					OptCreateDeterministic(),
+					OptCreateWithDescriptors(
+						getDescriptorInput(t, DataGeneric, []byte{0xfa, 0xce}),
+					),
+				}
+			},
+		},
+		{
+			name: "OneDescriptorWithSize",
+			opts: func(t *testing.T) []CreateOpt {
+				return []CreateOpt{
+					OptCreateDeterministic(),
+					OptCreateWithDescriptors(
+						getDescriptorInput(t, DataGeneric, []byte{0xfa, 0xce},
+							OptObjectSize(2),
+						),
+					),
+				}
+			},
+		},
+		{
+			name: "TwoDescriptors",
+			opts: func(t *testing.T) []CreateOpt {
+				return []CreateOpt{
+					OptCreateDeterministic(),
+					OptCreateWithDescriptors(
+						getDescriptorInput(t, DataGeneric, []byte{0xfa, 0xce}),
+						getDescriptorInput(t, DataPartition, []byte{0xfe, 0xed},
+							OptPartitionMetadata(FsSquash, PartPrimSys, "386"),
+						),
+					),
+				}
+			},
+		},
+		{
+			name: "TwoDescriptorsWithSize",
+			opts: func(t *testing.T) []CreateOpt {
+				return []CreateOpt{
+					OptCreateDeterministic(),
+					OptCreateWithDescriptors(
+						getDescriptorInput(t, DataGeneric, []byte{0xfa, 0xce},
+							OptObjectSize(2),
+						),
+						getDescriptorInput(t, DataPartition, []byte{0xfe, 0xed},
+							OptPartitionMetadata(FsSquash, PartPrimSys, "386"),
+							OptObjectSize(2),
+						),
+					),
+				}
+			},
+		},
+		{
+			name: "TwoDescriptorsAligned",
+			opts: func(t *testing.T) []CreateOpt {
+				return []CreateOpt{
+					OptCreateDeterministic(),
+					OptCreateWithDescriptors(
+						getDescriptorInput(t, DataGeneric, []byte{0xfa, 0xce},
+							OptObjectAlignment(4),
+						),
+						getDescriptorInput(t, DataPartition, []byte{0xfe, 0xed},
+							OptPartitionMetadata(FsSquash, PartPrimSys, "386"),
+							OptObjectAlignment(4),
+						),
+					),
+				}
+			},
+		},
+		{
+			name: "OCIBlobs",
+			opts: func(t *testing.T) []CreateOpt {
+				return []CreateOpt{
+					OptCreateDeterministic(),
+					OptCreateWithDescriptors(
+						getDescriptorInput(t, DataOCIBlob, []byte{0xfa, 0xce}),
+						getDescriptorInput(t, DataOCIRootIndex, []byte{0xfe, 0xed},
+							OptObjectSize(2),
+						),
+					),
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got bytes.Buffer
+
+			err := WriteContainer(&got, tt.opts(t)...)
+
+			if got, want := err, tt.wantErr; !errors.Is(got, want) {
+				t.Fatalf("got error %v, want %v", got, want)
+			}
+
+			if err == nil {
+				var want Buffer
+
+				f, err := CreateContainer(&want, tt.opts(t)...)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				if err := f.UnloadContainer(); err != nil {
+					t.Error(err)
+				}
+
+				if !bytes.Equal(got.Bytes(), want.Bytes()) {
+					t.Errorf("output differs from CreateContainer")
+				}
+			}
+		})
+	}
+}
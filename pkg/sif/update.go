@@ -0,0 +1,360 @@
+// Copyright (c) 2023, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package sif
+
+import (
+	"encoding"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var errTxClosed = errors.New("transaction closed")
+
+// Tx is a batch of modifications to a FileImage. A Tx is only valid for the duration of the
+// function passed to FileImage.Update.
+type Tx struct {
+	f        *FileImage // Image being modified.
+	w        *FileImage // Working copy of f, to which modifications are made.
+	size     int64      // Size of image when transaction began.
+	failure  error      // Error recorded by fail when transaction began.
+	t        time.Time
+	modified bool  // Image modified by transaction.
+	closed   bool  // Transaction committed or rolled back.
+	err      error // First error encountered by transaction.
+}
+
+// do calls fn to perform a modification within tx. If tx is closed, or a previous modification
+// failed, fn is not called and an error is returned.
+func (tx *Tx) do(fn func() error) error {
+	if tx.closed {
+		return errTxClosed
+	}
+
+	if tx.err != nil {
+		return tx.err
+	}
+
+	// The working copy shares backing storage with the image, so hold the image lock while it may
+	// be accessed.
+	tx.f.mu.Lock()
+	defer tx.f.mu.Unlock()
+
+	if err := fn(); err != nil {
+		tx.err = fmt.Errorf("%w", err)
+		return tx.err
+	}
+
+	tx.modified = true
+
+	return nil
+}
+
+// GetDescriptors returns a slice of in-use descriptors for which all selector funcs return true,
+// reflecting modifications made so far within tx. If the image contains no data objects, an error
+// wrapping ErrNoObjects is returned.
+func (tx *Tx) GetDescriptors(fns ...DescriptorSelectorFunc) ([]Descriptor, error) {
+	if tx.closed {
+		return nil, errTxClosed
+	}
+
+	ds, err := tx.w.objectDescriptors()
+	if err != nil {
+		return nil, err
+	}
+
+	return selectDescriptors(ds, fns...)
+}
+
+// GetDescriptor returns the in-use descriptor selected by fns, reflecting modifications made so
+// far within tx. If the image contains no data objects, an error wrapping ErrNoObjects is
+// returned. If no descriptor is selected by fns, an error wrapping ErrObjectNotFound is returned.
+// If multiple descriptors are selected by fns, an error wrapping ErrMultipleObjectsFound is
+// returned.
+func (tx *Tx) GetDescriptor(fns ...DescriptorSelectorFunc) (Descriptor, error) {
+	if tx.closed {
+		return Descriptor{}, errTxClosed
+	}
+
+	ds, err := tx.w.objectDescriptors()
+	if err != nil {
+		return Descriptor{}, err
+	}
+
+	return selectDescriptor(ds, fns...)
+}
+
+// AddObject adds a new data object and its descriptor to the image, and returns the new
+// descriptor. The data object is written immediately, but the descriptor is not written until the
+// transaction is committed.
+//
+// If the image does not have sufficient descriptor capacity, an error is returned, and the
+// transaction will be rolled back.
+func (tx *Tx) AddObject(di DescriptorInput) (Descriptor, error) {
+	var d Descriptor
+
+	err := tx.do(func() error {
+		// Find an unused descriptor.
+		i := 0
+		for _, rd := range tx.w.rds {
+			if !rd.Used {
+				break
+			}
+			i++
+		}
+
+		if err := tx.w.writeDataObject(i, di, tx.t); err != nil {
+			return err
+		}
+
+		d = tx.w.descriptorFromRaw(&tx.w.rds[i])
+
+		return nil
+	})
+
+	return d, err
+}
+
+// DeleteObject deletes the data object with id. The data region of the deleted object is left
+// unmodified. To reclaim the space occupied by deleted objects, consider using FileImage.Compact
+// once the transaction has been committed.
+func (tx *Tx) DeleteObject(id uint32) error {
+	return tx.do(func() error {
+		d, err := tx.w.getDescriptor(WithID(id))
+		if err != nil {
+			return err
+		}
+
+		// If we remove the primary partition, set the global header Arch field to HdrArchUnknown
+		// to indicate that the SIF file doesn't include a primary partition and no dependency
+		// on any architecture exists.
+		if d.isPartitionOfType(PartPrimSys) {
+			tx.w.h.Arch = hdrArchUnknown
+		}
+
+		*d = rawDescriptor{}
+		tx.w.h.DescriptorsFree++
+
+		return nil
+	})
+}
+
+// SetMetadata sets the metadata of the data object with id to md.
+func (tx *Tx) SetMetadata(id uint32, md encoding.BinaryMarshaler) error {
+	return tx.do(func() error {
+		rd, err := tx.w.getDescriptor(WithID(id))
+		if err != nil {
+			return err
+		}
+
+		if err := rd.setExtra(md); err != nil {
+			return err
+		}
+
+		rd.ModifiedAt = tx.t.Unix()
+
+		return nil
+	})
+}
+
+// imageState records the state of an image, so that a failed modification can be rolled back.
+type imageState struct {
+	h       header
+	rds     []rawDescriptor
+	minIDs  map[uint32]uint32
+	size    int64
+	failure error
+}
+
+// workingCopy returns a copy of f that shares its backing storage, such that the header and
+// descriptors of the copy can be modified without affecting f. The caller must hold f.mu.
+func (f *FileImage) workingCopy() *FileImage {
+	rds := make([]rawDescriptor, len(f.rds))
+	copy(rds, f.rds)
+
+	minIDs := make(map[uint32]uint32, len(f.minIDs))
+	for k, v := range f.minIDs {
+		minIDs[k] = v
+	}
+
+	return &FileImage{rw: f.rw, h: f.h, rds: rds, minIDs: minIDs}
+}
+
+// saveState records the state of f. The caller must hold f.mu.
+func (f *FileImage) saveState() (imageState, error) {
+	size, err := f.size()
+	if err != nil {
+		return imageState{}, err
+	}
+
+	w := f.workingCopy()
+
+	return imageState{w.h, w.rds, w.minIDs, size, f.failure()}, nil
+}
+
+// truncate discards any data written beyond size. The caller must hold f.mu.
+func (f *FileImage) truncate(size int64) error {
+	if end, err := f.size(); err == nil && end > size {
+		return f.rw.Truncate(size)
+	}
+
+	return nil
+}
+
+// restoreState rolls f back to state s, discarding any data written beyond the end of the image
+// since s was recorded, along with any errors recorded by fail. Data written within the image is
+// not restored, so s must only be restored if the data section of f has not been modified in
+// place. The caller must hold f.mu.
+func (f *FileImage) restoreState(s imageState) error {
+	f.h = s.h
+	f.rds = s.rds
+	f.minIDs = s.minIDs
+	f.setFailure(s.failure)
+
+	return f.truncate(s.size)
+}
+
+// updateOpts accumulates image update options.
+type updateOpts struct {
+	t time.Time
+}
+
+// UpdateOpt are used to specify image update options.
+type UpdateOpt func(*updateOpts) error
+
+// OptUpdateDeterministic sets header/descriptor fields to values that support deterministic
+// modification of images.
+func OptUpdateDeterministic() UpdateOpt {
+	return func(uo *updateOpts) error {
+		uo.t = time.Time{}
+		return nil
+	}
+}
+
+// OptUpdateWithTime specifies t as the image/object modification time.
+func OptUpdateWithTime(t time.Time) UpdateOpt {
+	return func(uo *updateOpts) error {
+		uo.t = t
+		return nil
+	}
+}
+
+// begin starts a transaction that modifies a working copy of f, according to opts. The caller must
+// hold f.wmu.
+func (f *FileImage) begin(opts ...UpdateOpt) (*Tx, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.readOnly {
+		return nil, ErrReadOnly
+	}
+
+	uo := updateOpts{}
+
+	if !f.isDeterministic() {
+		uo.t = time.Now()
+	}
+
+	for _, opt := range opts {
+		if err := opt(&uo); err != nil {
+			return nil, err
+		}
+	}
+
+	// Record size, so that the transaction can be rolled back.
+	size, err := f.size()
+	if err != nil {
+		return nil, err
+	}
+
+	return &Tx{f: f, w: f.workingCopy(), size: size, failure: f.failure(), t: uo.t}, nil
+}
+
+// commit writes the descriptors and global header modified within tx, and makes them visible via
+// tx.f. The caller must hold tx.f.wmu.
+func (tx *Tx) commit() error {
+	tx.f.mu.Lock()
+	defer tx.f.mu.Unlock()
+
+	if err := tx.w.writeDescriptors(); err != nil {
+		return err
+	}
+
+	tx.w.h.ModifiedAt = tx.t.Unix()
+
+	if err := tx.w.writeHeader(); err != nil {
+		return err
+	}
+
+	tx.f.h = tx.w.h
+	tx.f.rds = tx.w.rds
+	tx.f.minIDs = tx.w.minIDs
+
+	return nil
+}
+
+// rollback discards any data written within tx, along with any errors recorded by fail. The
+// caller must hold tx.f.wmu.
+func (tx *Tx) rollback() error {
+	tx.f.mu.Lock()
+	defer tx.f.mu.Unlock()
+
+	tx.f.setFailure(tx.failure)
+
+	return tx.f.truncate(tx.size)
+}
+
+// Update calls fn to make a batch of modifications to f, according to opts. The descriptors and
+// global header of f are written once, when fn returns, rather than following each modification.
+// This reduces the I/O required to make a large number of modifications, such as adding many data
+// objects.
+//
+// Modifications made via the Tx are not visible via the methods of f until fn returns and the
+// transaction is committed. To read descriptors that reflect modifications made within fn, use the
+// methods of the Tx instead. Methods of f that read the image may be called within fn, but other
+// modifications of f, including another call to Update, are blocked until the transaction
+// completes. Therefore, fn must not call methods of f that modify the image.
+//
+// If fn returns an error, or any modification made via the Tx fails (for example, due to
+// insufficient descriptor capacity), the transaction is rolled back. Any data written by fn is
+// discarded, the descriptors and global header of f are left unmodified, and an error is returned.
+//
+// By default, the image/object modification times are set to the current time for
+// non-deterministic images, and unset otherwise. To override this, consider using
+// OptUpdateDeterministic or OptUpdateWithTime.
+func (f *FileImage) Update(fn func(tx *Tx) error, opts ...UpdateOpt) error {
+	f.wmu.Lock()
+	defer f.wmu.Unlock()
+
+	tx, err := f.begin(opts...)
+	if err != nil {
+		return fmt.Errorf("%w", err)
+	}
+	defer func() { tx.closed = true }()
+
+	err = fn(tx)
+	if err == nil {
+		err = tx.err
+	}
+
+	if err != nil {
+		if rerr := tx.rollback(); rerr != nil {
+			return fmt.Errorf("%w", errors.Join(err, rerr))
+		}
+
+		return fmt.Errorf("%w", err)
+	}
+
+	if !tx.modified {
+		return nil
+	}
+
+	if err := tx.commit(); err != nil {
+		return fmt.Errorf("%w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,264 @@
+// Copyright (c) 2023, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package sif
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/sebdah/goldie/v2"
+)
+
+//nolint:maintidx
+func TestFileImage_Update(t *testing.T) {
+	errTest := errors.New("test error")
+
+	createOpts := func() []CreateOpt {
+		return []CreateOpt{
+			OptCreateDeterministic(),
+			OptCreateWithDescriptorCapacity(4),
+			OptCreateWithDescriptors(
+				getDescriptorInput(t, DataPartition, []byte{0xfa, 0xce},
+					OptPartitionMetadata(FsSquash, PartPrimSys, "386"),
+				),
+				getDescriptorInput(t, DataGeneric, []byte{0xfe, 0xed}),
+			),
+		}
+	}
+
+	tests := []struct {
+		name       string
+		createOpts []CreateOpt
+		fn         func(t *testing.T, tx *Tx) error
+		opts       []UpdateOpt
+		wantErr    error
+	}{
+		{
+			name:       "Empty",
+			createOpts: createOpts(),
+			fn:         func(t *testing.T, tx *Tx) error { return nil },
+		},
+		{
+			name:       "AddObjects",
+			createOpts: createOpts(),
+			fn: func(t *testing.T, tx *Tx) error {
+				if _, err := tx.AddObject(getDescriptorInput(t, DataGeneric, []byte{0xde, 0xad})); err != nil {
+					return err
+				}
+				d, err := tx.AddObject(getDescriptorInput(t, DataGeneric, []byte{0xbe, 0xef},
+					OptObjectAlignment(1024),
+				))
+				if err != nil {
+					return err
+				}
+				if got, want := d.ID(), uint32(4); got != want {
+					t.Errorf("got ID %v, want %v", got, want)
+				}
+				return nil
+			},
+			opts: []UpdateOpt{OptUpdateDeterministic()},
+		},
+		{
+			name:       "DeleteObject",
+			createOpts: createOpts(),
+			fn: func(t *testing.T, tx *Tx) error {
+				return tx.DeleteObject(1)
+			},
+			opts: []UpdateOpt{OptUpdateDeterministic()},
+		},
+		{
+			name:       "SetMetadata",
+			createOpts: createOpts(),
+			fn: func(t *testing.T, tx *Tx) error {
+				return tx.SetMetadata(2, newOCIBlobDigest())
+			},
+			opts: []UpdateOpt{OptUpdateDeterministic()},
+		},
+		{
+			name:       "Mixed",
+			createOpts: createOpts(),
+			fn: func(t *testing.T, tx *Tx) error {
+				if err := tx.DeleteObject(2); err != nil {
+					return err
+				}
+				if _, err := tx.AddObject(getDescriptorInput(t, DataGeneric, []byte{0xde, 0xad})); err != nil {
+					return err
+				}
+				return tx.SetMetadata(1, newOCIBlobDigest())
+			},
+			opts: []UpdateOpt{OptUpdateDeterministic()},
+		},
+		{
+			name:       "WithTime",
+			createOpts: createOpts(),
+			fn: func(t *testing.T, tx *Tx) error {
+				_, err := tx.AddObject(getDescriptorInput(t, DataGeneric, []byte{0xde, 0xad}))
+				return err
+			},
+			opts: []UpdateOpt{OptUpdateWithTime(time.Unix(946702800, 0))},
+		},
+		{
+			name:       "InsufficientCapacity",
+			createOpts: createOpts(),
+			fn: func(t *testing.T, tx *Tx) error {
+				for i := 0; i < 3; i++ {
+					if _, err := tx.AddObject(getDescriptorInput(t, DataGeneric, []byte{0xde, 0xad})); err != nil {
+						return err
+					}
+				}
+				return nil
+			},
+			wantErr: errInsufficientCapacity,
+		},
+		{
+			name:       "InsufficientCapacityIgnored",
+			createOpts: createOpts(),
+			fn: func(t *testing.T, tx *Tx) error {
+				for i := 0; i < 3; i++ {
+					_, _ = tx.AddObject(getDescriptorInput(t, DataGeneric, []byte{0xde, 0xad}))
+				}
+				return tx.SetMetadata(1, newOCIBlobDigest())
+			},
+			wantErr: errInsufficientCapacity,
+		},
+		{
+			name:       "ObjectNotFound",
+			createOpts: createOpts(),
+			fn: func(t *testing.T, tx *Tx) error {
+				if err := tx.DeleteObject(1); err != nil {
+					return err
+				}
+				return tx.DeleteObject(3)
+			},
+			wantErr: ErrObjectNotFound,
+		},
+		{
+			name:       "FnError",
+			createOpts: createOpts(),
+			fn: func(t *testing.T, tx *Tx) error {
+				if _, err := tx.AddObject(getDescriptorInput(t, DataGeneric, []byte{0xde, 0xad})); err != nil {
+					return err
+				}
+				return errTest
+			},
+			wantErr: errTest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var b Buffer
+
+			f, err := CreateContainer(&b, tt.createOpts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			before := bytes.Clone(b.Bytes())
+
+			updateErr := f.Update(func(tx *Tx) error { return tt.fn(t, tx) }, tt.opts...)
+			if got, want := updateErr, tt.wantErr; !errors.Is(got, want) {
+				t.Fatalf("got error %v, want %v", got, want)
+			}
+
+			// The in-memory state must be consistent with backing storage.
+			l, err := LoadContainer(&b)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got, want := f.h, l.h; got != want {
+				t.Errorf("got header %+v, want %+v", got, want)
+			}
+
+			if got, want := f.rds, l.rds; !reflect.DeepEqual(got, want) {
+				t.Errorf("got descriptors %+v, want %+v", got, want)
+			}
+
+			if updateErr != nil {
+				if got, want := b.Bytes(), before; !bytes.Equal(got, want) {
+					t.Error("image modified by failed update")
+				}
+				return
+			}
+
+			g := goldie.New(t, goldie.WithTestNameForDir(true))
+			g.Assert(t, tt.name, b.Bytes())
+		})
+	}
+}
+
+func TestFileImage_UpdateTxClosed(t *testing.T) {
+	var b Buffer
+
+	f, err := CreateContainer(&b, OptCreateDeterministic())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var tx *Tx
+
+	if err := f.Update(func(x *Tx) error {
+		tx = x
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := tx.AddObject(getDescriptorInput(t, DataGeneric, []byte{0xfa, 0xce})); !errors.Is(err, errTxClosed) {
+		t.Errorf("got error %v, want %v", err, errTxClosed)
+	}
+}
+
+func TestFileImage_UpdateRead(t *testing.T) {
+	var b Buffer
+
+	f, err := CreateContainer(&b,
+		OptCreateDeterministic(),
+		OptCreateWithDescriptorCapacity(4),
+		OptCreateWithDescriptors(
+			getDescriptorInput(t, DataGeneric, []byte{0xfa, 0xce}),
+		),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := f.Update(func(tx *Tx) error {
+		if _, err := tx.AddObject(getDescriptorInput(t, DataGeneric, []byte{0xfe, 0xed})); err != nil {
+			return err
+		}
+
+		// Methods of f must not observe modifications until the transaction is committed.
+		if got, want := f.DescriptorsFree(), int64(3); got != want {
+			t.Errorf("got %v free descriptors, want %v", got, want)
+		}
+
+		if _, err := f.GetDescriptor(WithID(2)); !errors.Is(err, ErrObjectNotFound) {
+			t.Errorf("got error %v, want %v", err, ErrObjectNotFound)
+		}
+
+		// Methods of tx must observe modifications.
+		if _, err := tx.GetDescriptor(WithID(2)); err != nil {
+			t.Errorf("got error %v", err)
+		}
+
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := f.DescriptorsFree(), int64(2); got != want {
+		t.Errorf("got %v free descriptors, want %v", got, want)
+	}
+
+	if _, err := f.GetDescriptor(WithID(2)); err != nil {
+		t.Errorf("got error %v", err)
+	}
+}
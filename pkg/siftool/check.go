@@ -0,0 +1,41 @@
+// Copyright (c) 2023, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package siftool
+
+import (
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// getCheckExamples returns check command examples based on rootCmd.
+func getCheckExamples(rootPath string) string {
+	examples := []string{
+		rootPath +
+			" check image.sif",
+	}
+	return strings.Join(examples, "\n")
+}
+
+// getCheck returns a command that checks the integrity of data objects in a SIF image.
+func (c *command) getCheck() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "check <sif_path>",
+		Short: "Check OCI blob digests",
+		Long: "Check the integrity of the OCI root index and blobs in a SIF image, which may be a " +
+			"local file or an HTTP(S) URL. The digest of each object is recomputed, and compared " +
+			"against the digest recorded when the object was written.",
+		Example: getCheckExamples(c.opts.rootPath),
+		Args:    cobra.ExactArgs(1),
+	}
+
+	cmd.PreRunE = c.initApp
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		return c.app.CheckOCIBlobs(args[0])
+	}
+
+	return cmd
+}
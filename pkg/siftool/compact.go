@@ -0,0 +1,26 @@
+// Copyright (c) 2023, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package siftool
+
+import (
+	"github.com/spf13/cobra"
+)
+
+// getCompact returns a command that reclaims unused space in a SIF.
+func (c *command) getCompact() *cobra.Command {
+	return &cobra.Command{
+		Use:     "compact <sif_path>",
+		Short:   "Reclaim unused space",
+		Long:    "Reclaim unused space in the data section of a SIF image.",
+		Example: c.opts.rootPath + " compact image.sif",
+		Args:    cobra.ExactArgs(1),
+		PreRunE: c.initApp,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return c.app.Compact(args[0])
+		},
+		DisableFlagsInUseLine: true,
+	}
+}
@@ -0,0 +1,45 @@
+// Copyright (c) 2023, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package siftool
+
+import (
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// getExtractExamples returns extract command examples based on rootCmd.
+func getExtractExamples(rootPath string) string {
+	examples := []string{
+		rootPath +
+			" extract image.sif /etc/os-release os-release",
+		rootPath +
+			" extract --id 2 image.sif /opt/app app",
+	}
+	return strings.Join(examples, "\n")
+}
+
+// getExtract returns a command that extracts a file or directory from a SquashFS partition of a
+// SIF image.
+func (c *command) getExtract() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "extract <sif_path> <path> <dest>",
+		Short: "Extract file(s) from a partition",
+		Long: "Extract a file or directory from a SquashFS partition of a SIF image, which may be a " +
+			"local file or an HTTP(S) URL. By default, the primary system partition is used.",
+		Example: getExtractExamples(c.opts.rootPath),
+		Args:    cobra.ExactArgs(3),
+	}
+
+	id := cmd.Flags().Uint32("id", 0, "extract from the partition with the specified ID")
+
+	cmd.PreRunE = c.initApp
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		return c.app.Extract(args[0], *id, args[1], args[2])
+	}
+
+	return cmd
+}
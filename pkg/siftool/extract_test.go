@@ -0,0 +1,39 @@
+// Copyright (c) 2023, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package siftool
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func Test_command_getExtract(t *testing.T) {
+	tests := []struct {
+		name string
+		opts commandOpts
+		args []string
+	}{
+		{
+			name: "Primary",
+			args: []string{filepath.Join(corpus, "two-groups.sif"), "/hello.txt"},
+		},
+		{
+			name: "ID",
+			args: []string{"--id", "2", filepath.Join(corpus, "two-groups.sif"), "hello.txt"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &command{opts: tt.opts}
+
+			cmd := c.getExtract()
+
+			args := append(tt.args, filepath.Join(t.TempDir(), "hello.txt"))
+
+			runCommand(t, cmd, args, nil)
+		})
+	}
+}
@@ -0,0 +1,41 @@
+// Copyright (c) 2023, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package siftool
+
+import (
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// getFsckExamples returns fsck command examples based on rootCmd.
+func getFsckExamples(rootPath string) string {
+	examples := []string{
+		rootPath +
+			" fsck image.sif",
+	}
+	return strings.Join(examples, "\n")
+}
+
+// getFsck returns a command that checks the structure of a SIF image.
+func (c *command) getFsck() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "fsck <sif_path>",
+		Short: "Check image structure",
+		Long: "Check the structure of a SIF image, which may be a local file or an HTTP(S) URL. " +
+			"Problems such as overlapping data objects, data objects extending beyond the end of " +
+			"the file, inconsistent header fields and links to missing data objects are reported.",
+		Example: getFsckExamples(c.opts.rootPath),
+		Args:    cobra.ExactArgs(1),
+	}
+
+	cmd.PreRunE = c.initApp
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		return c.app.Fsck(args[0])
+	}
+
+	return cmd
+}
@@ -0,0 +1,37 @@
+// Copyright (c) 2023, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package siftool
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func Test_command_getFsck(t *testing.T) {
+	tests := []struct {
+		name string
+		opts commandOpts
+		path string
+	}{
+		{
+			name: "Empty",
+			path: filepath.Join(corpus, "empty.sif"),
+		},
+		{
+			name: "TwoGroups",
+			path: filepath.Join(corpus, "two-groups.sif"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &command{opts: tt.opts}
+
+			cmd := c.getFsck()
+
+			runCommand(t, cmd, []string{tt.path}, nil)
+		})
+	}
+}
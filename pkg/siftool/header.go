@@ -1,4 +1,4 @@
-// Copyright (c) 2018-2021, Sylabs Inc. All rights reserved.
+// Copyright (c) 2018-2023, Sylabs Inc. All rights reserved.
 // Copyright (c) 2018, Divya Cote <divya.cote@gmail.com> All rights reserved.
 // Copyright (c) 2017, SingularityWare, LLC. All rights reserved.
 // Copyright (c) 2017, Yannick Cote <yhcote@gmail.com> All rights reserved.
@@ -9,16 +9,29 @@
 package siftool
 
 import (
+	"strings"
+
 	"github.com/spf13/cobra"
 )
 
+// getHeaderExamples returns header command examples based on rootCmd.
+func getHeaderExamples(rootPath string) string {
+	examples := []string{
+		rootPath +
+			" header image.sif",
+		rootPath +
+			" header https://example.com/image.sif",
+	}
+	return strings.Join(examples, "\n")
+}
+
 // getHeader returns a command that displays the global SIF header.
 func (c *command) getHeader() *cobra.Command {
 	return &cobra.Command{
 		Use:     "header <sif_path>",
 		Short:   "Display global header",
-		Long:    "Display global header from a SIF image.",
-		Example: c.opts.rootPath + " header image.sif",
+		Long:    "Display global header from a SIF image, which may be a local file or an HTTP(S) URL.",
+		Example: getHeaderExamples(c.opts.rootPath),
 		Args:    cobra.ExactArgs(1),
 		PreRunE: c.initApp,
 		RunE: func(cmd *cobra.Command, args []string) error {
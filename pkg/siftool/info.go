@@ -1,4 +1,4 @@
-// Copyright (c) 2018-2021, Sylabs Inc. All rights reserved.
+// Copyright (c) 2018-2023, Sylabs Inc. All rights reserved.
 // Copyright (c) 2018, Divya Cote <divya.cote@gmail.com> All rights reserved.
 // Copyright (c) 2017, SingularityWare, LLC. All rights reserved.
 // Copyright (c) 2017, Yannick Cote <yhcote@gmail.com> All rights reserved.
@@ -11,18 +11,30 @@ package siftool
 import (
 	"fmt"
 	"strconv"
+	"strings"
 
 	"github.com/spf13/cobra"
 )
 
+// getInfoExamples returns info command examples based on rootCmd.
+func getInfoExamples(rootPath string) string {
+	examples := []string{
+		rootPath +
+			" info 1 image.sif",
+		rootPath +
+			" info 1 https://example.com/image.sif",
+	}
+	return strings.Join(examples, "\n")
+}
+
 // getInfo returns a command that displays detailed information of an object descriptor from a SIF
 // image.
 func (c *command) getInfo() *cobra.Command {
 	return &cobra.Command{
 		Use:     "info <id> <sif_path>",
 		Short:   "Display data object info",
-		Long:    "Display info about a data object from a SIF image.",
-		Example: c.opts.rootPath + " info 1 image.sif",
+		Long:    "Display info about a data object from a SIF image, which may be a local file or an HTTP(S) URL.",
+		Example: getInfoExamples(c.opts.rootPath),
 		Args:    cobra.ExactArgs(2),
 		PreRunE: c.initApp,
 		RunE: func(cmd *cobra.Command, args []string) error {
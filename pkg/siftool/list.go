@@ -1,4 +1,4 @@
-// Copyright (c) 2018-2021, Sylabs Inc. All rights reserved.
+// Copyright (c) 2018-2023, Sylabs Inc. All rights reserved.
 // Copyright (c) 2018, Divya Cote <divya.cote@gmail.com> All rights reserved.
 // Copyright (c) 2017, SingularityWare, LLC. All rights reserved.
 // Copyright (c) 2017, Yannick Cote <yhcote@gmail.com> All rights reserved.
@@ -9,16 +9,29 @@
 package siftool
 
 import (
+	"strings"
+
 	"github.com/spf13/cobra"
 )
 
+// getListExamples returns list command examples based on rootCmd.
+func getListExamples(rootPath string) string {
+	examples := []string{
+		rootPath +
+			" list image.sif",
+		rootPath +
+			" list https://example.com/image.sif",
+	}
+	return strings.Join(examples, "\n")
+}
+
 // getList returns a command that lists object descriptors from a SIF image.
 func (c *command) getList() *cobra.Command {
 	return &cobra.Command{
 		Use:     "list <sif_path>",
 		Short:   "List data objects",
-		Long:    "List data objects from a SIF image.",
-		Example: c.opts.rootPath + " list image.sif",
+		Long:    "List data objects from a SIF image, which may be a local file or an HTTP(S) URL.",
+		Example: getListExamples(c.opts.rootPath),
 		Args:    cobra.ExactArgs(1),
 		PreRunE: c.initApp,
 		RunE: func(cmd *cobra.Command, args []string) error {
@@ -0,0 +1,68 @@
+// Copyright (c) 2023, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package siftool
+
+import (
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/sylabs/sif/v2/pkg/user"
+)
+
+// getMountExamples returns mount command examples based on rootCmd.
+func getMountExamples(rootPath string) string {
+	examples := []string{
+		rootPath +
+			" mount image.sif path/",
+		rootPath +
+			" mount --id 3 --writable image.sif path/",
+		rootPath +
+			" mount --squashfuse-path /opt/bin/squashfuse image.sif path/",
+	}
+	return strings.Join(examples, "\n")
+}
+
+// getMount returns a command that mounts a partition of a SIF image.
+func (c *command) getMount() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "mount <sif_path> <mount_path>",
+		Short: "Mount partition",
+		Long: "Mount a partition of a SIF image. By default, the primary system partition is " +
+			"mounted read-only.",
+		Example: getMountExamples(c.opts.rootPath),
+		Args:    cobra.ExactArgs(2),
+	}
+
+	id := cmd.Flags().Uint32("id", 0, "mount the partition with the specified ID")
+	writable := cmd.Flags().Bool("writable", false, "mount the partition read-write (EXT3 only)")
+	squashfusePath := cmd.Flags().String("squashfuse-path", "", "path to squashfuse binary")
+	fuse2fsPath := cmd.Flags().String("fuse2fs-path", "", "path to fuse2fs binary")
+
+	cmd.PreRunE = c.initApp
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		var opts []user.MountOpt
+
+		if *id != 0 {
+			opts = append(opts, user.OptMountPartitionID(*id))
+		}
+
+		if *writable {
+			opts = append(opts, user.OptMountWritable(true))
+		}
+
+		if *squashfusePath != "" {
+			opts = append(opts, user.OptMountSquashfusePath(*squashfusePath))
+		}
+
+		if *fuse2fsPath != "" {
+			opts = append(opts, user.OptMountFuse2fsPath(*fuse2fsPath))
+		}
+
+		return c.app.Mount(cmd.Context(), args[0], args[1], opts...)
+	}
+
+	return cmd
+}
@@ -0,0 +1,51 @@
+// Copyright (c) 2023, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package siftool
+
+import (
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func Test_command_getMount(t *testing.T) {
+	// Substitute a binary that always succeeds for squashfuse/fuse2fs, so that the tests do not
+	// depend on FUSE.
+	truePath, err := exec.LookPath("true")
+	if err != nil {
+		t.Skip("true not found, skipping mount tests")
+	}
+
+	tests := []struct {
+		name string
+		opts commandOpts
+		args []string
+	}{
+		{
+			name: "Primary",
+			args: []string{"--squashfuse-path", truePath, filepath.Join(corpus, "one-group.sif")},
+		},
+		{
+			name: "ID",
+			args: []string{"--id", "3", "--fuse2fs-path", truePath, filepath.Join(corpus, "two-groups.sif")},
+		},
+		{
+			name: "Writable",
+			args: []string{
+				"--id", "3", "--writable", "--fuse2fs-path", truePath, filepath.Join(corpus, "two-groups.sif"),
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &command{opts: tt.opts}
+
+			cmd := c.getMount()
+
+			runCommand(t, cmd, append(tt.args, t.TempDir()), nil)
+		})
+	}
+}
@@ -0,0 +1,56 @@
+// Copyright (c) 2023, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package siftool
+
+import (
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// getOCIExportExamples returns oci export command examples based on rootCmd.
+func getOCIExportExamples(rootPath string) string {
+	examples := []string{
+		rootPath +
+			" oci export image.sif layout/",
+	}
+	return strings.Join(examples, "\n")
+}
+
+// getOCIExport returns a command that writes the OCI image in a SIF image to an OCI image layout.
+func (c *command) getOCIExport() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "export <sif_path> <layout_path>",
+		Short: "Export OCI image layout",
+		Long: "Export the OCI root index and blobs of a SIF image, which may be a local file or an " +
+			"HTTP(S) URL, to an OCI image layout directory. The digest of each blob is verified as " +
+			"it is written.",
+		Example: getOCIExportExamples(c.opts.rootPath),
+		Args:    cobra.ExactArgs(2),
+	}
+
+	cmd.PreRunE = c.initApp
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		return c.app.OCIExport(args[0], args[1])
+	}
+
+	return cmd
+}
+
+// getOCI returns a command that groups commands which operate on OCI images.
+func (c *command) getOCI() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "oci",
+		Short: "Manage OCI images",
+		Long:  "Manage OCI images stored as a root index and blobs within a SIF image.",
+	}
+
+	cmd.AddCommand(
+		c.getOCIExport(),
+	)
+
+	return cmd
+}
@@ -0,0 +1,68 @@
+// Copyright (c) 2023, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package siftool
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sylabs/sif/v2/internal/app/siftool"
+	"github.com/sylabs/sif/v2/pkg/sif"
+)
+
+// makeTestOCISIF returns the path to a new SIF containing a root index and a blob.
+func makeTestOCISIF(t *testing.T) string {
+	t.Helper()
+
+	path := makeTestSIF(t, false)
+
+	app, err := siftool.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, dt := range []sif.DataType{sif.DataOCIRootIndex, sif.DataOCIBlob} {
+		f, err := os.Open(filepath.Join("testdata", "input", "input.bin"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = app.Add(path, dt, f)
+		f.Close()
+
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return path
+}
+
+func Test_command_getOCIExport(t *testing.T) {
+	tests := []struct {
+		name string
+		opts commandOpts
+	}{
+		{
+			name: "OK",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &command{opts: tt.opts}
+
+			cmd := c.getOCIExport()
+
+			args := []string{
+				makeTestOCISIF(t),
+				filepath.Join(t.TempDir(), "layout"),
+			}
+
+			runCommand(t, cmd, args, nil)
+		})
+	}
+}
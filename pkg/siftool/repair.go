@@ -0,0 +1,46 @@
+// Copyright (c) 2023, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package siftool
+
+import (
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// getRepairExamples returns repair command examples based on rootCmd.
+func getRepairExamples(rootPath string) string {
+	examples := []string{
+		rootPath +
+			" repair --dry-run image.sif",
+		rootPath +
+			" repair image.sif",
+	}
+	return strings.Join(examples, "\n")
+}
+
+// getRepair returns a command that repairs the global header and descriptors of a SIF image.
+func (c *command) getRepair() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "repair <sif_path>",
+		Short: "Repair image header and descriptors",
+		Long: "Repair the global header and descriptors of a SIF image. The free descriptor count " +
+			"and data section size are recomputed, the header architecture is set from the " +
+			"primary system partition, unused descriptors are zeroed, and data following the " +
+			"last data object is truncated.",
+		Example: getRepairExamples(c.opts.rootPath),
+		Args:    cobra.ExactArgs(1),
+	}
+
+	dryRun := cmd.Flags().Bool("dry-run", false, "display required changes without modifying the image")
+
+	cmd.PreRunE = c.initApp
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		return c.app.Repair(args[0], *dryRun)
+	}
+
+	return cmd
+}
@@ -0,0 +1,37 @@
+// Copyright (c) 2023, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package siftool
+
+import (
+	"testing"
+)
+
+func Test_command_getRepair(t *testing.T) {
+	tests := []struct {
+		name  string
+		opts  commandOpts
+		flags []string
+	}{
+		{
+			name: "OK",
+		},
+		{
+			name:  "DryRun",
+			flags: []string{"--dry-run"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &command{opts: tt.opts}
+
+			cmd := c.getRepair()
+
+			args := append(tt.flags, makeTestSIF(t, true))
+
+			runCommand(t, cmd, args, nil)
+		})
+	}
+}
@@ -0,0 +1,34 @@
+// Copyright (c) 2023, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package siftool
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/spf13/cobra"
+)
+
+// getResizeDescriptors returns a command that sets the descriptor capacity of a SIF.
+func (c *command) getResizeDescriptors() *cobra.Command {
+	return &cobra.Command{
+		Use:     "resize-descriptors <capacity> <sif_path>",
+		Short:   "Set descriptor capacity",
+		Long:    "Set the maximum number of data object descriptors in a SIF image.",
+		Example: c.opts.rootPath + " resize-descriptors 96 image.sif",
+		Args:    cobra.ExactArgs(2),
+		PreRunE: c.initApp,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			n, err := strconv.ParseInt(args[0], 10, 64)
+			if err != nil {
+				return fmt.Errorf("while converting capacity: %w", err)
+			}
+
+			return c.app.ResizeDescriptors(args[1], n)
+		},
+		DisableFlagsInUseLine: true,
+	}
+}
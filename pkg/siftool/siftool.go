@@ -49,9 +49,8 @@ func OptWithExperimental(b bool) CommandOpt {
 
 // AddCommands adds siftool commands to cmd according to opts.
 //
-// A set of commands are provided to display elements such as the SIF global
-// header, the data object descriptors and to dump data objects. It is also
-// possible to modify a SIF file via this tool via the add/del commands.
+// Experimental commands, such as mount and unmount, are only added if enabled via
+// OptWithExperimental.
 func AddCommands(cmd *cobra.Command, opts ...CommandOpt) error {
 	c := command{
 		opts: commandOpts{
@@ -70,11 +69,27 @@ func AddCommands(cmd *cobra.Command, opts ...CommandOpt) error {
 		c.getList(),
 		c.getInfo(),
 		c.getDump(),
+		c.getExtract(),
+		c.getOCI(),
 		c.getNew(),
 		c.getAdd(),
 		c.getDel(),
 		c.getSetPrim(),
+		c.getCompact(),
+		c.getResizeDescriptors(),
+		c.getSign(),
+		c.getVerify(),
+		c.getCheck(),
+		c.getFsck(),
+		c.getRepair(),
 	)
 
+	if c.opts.experimental {
+		cmd.AddCommand(
+			c.getMount(),
+			c.getUnmount(),
+		)
+	}
+
 	return nil
 }
@@ -49,7 +49,6 @@ func makeTestSIF(t *testing.T, withDataObject bool) string {
 	return tf.Name()
 }
 
-//nolint:unparam
 func runCommand(t *testing.T, cmd *cobra.Command, args []string, wantErr error) {
 	t.Helper()
 
@@ -90,6 +89,14 @@ func TestAddCommands(t *testing.T) {
 			name: "Add",
 			args: []string{"help", "add"},
 		},
+		{
+			name: "Check",
+			args: []string{"help", "check"},
+		},
+		{
+			name: "Compact",
+			args: []string{"help", "compact"},
+		},
 		{
 			name: "Del",
 			args: []string{"help", "del"},
@@ -98,6 +105,14 @@ func TestAddCommands(t *testing.T) {
 			name: "Dump",
 			args: []string{"help", "dump"},
 		},
+		{
+			name: "Extract",
+			args: []string{"help", "extract"},
+		},
+		{
+			name: "Fsck",
+			args: []string{"help", "fsck"},
+		},
 		{
 			name: "Header",
 			args: []string{"help", "header"},
@@ -110,14 +125,48 @@ func TestAddCommands(t *testing.T) {
 			name: "List",
 			args: []string{"help", "list"},
 		},
+		{
+			name: "Mount",
+			opts: []CommandOpt{OptWithExperimental(true)},
+			args: []string{"help", "mount"},
+		},
 		{
 			name: "New",
 			args: []string{"help", "new"},
 		},
+		{
+			name: "OCI",
+			args: []string{"help", "oci"},
+		},
+		{
+			name: "OCIExport",
+			args: []string{"help", "oci", "export"},
+		},
+		{
+			name: "Repair",
+			args: []string{"help", "repair"},
+		},
+		{
+			name: "ResizeDescriptors",
+			args: []string{"help", "resize-descriptors"},
+		},
 		{
 			name: "SetPrim",
 			args: []string{"help", "setprim"},
 		},
+		{
+			name: "Sign",
+			args: []string{"help", "sign"},
+		},
+		{
+			name: "Unmount",
+			opts: []CommandOpt{OptWithExperimental(true)},
+			args: []string{"help", "unmount"},
+		},
+		{
+			name: "Verify",
+			args: []string{"help", "verify"},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -0,0 +1,156 @@
+// Copyright (c) 2023, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package siftool
+
+import (
+	"crypto"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/sigstore/sigstore/pkg/cryptoutils"
+	"github.com/sigstore/sigstore/pkg/signature"
+	"github.com/spf13/cobra"
+	"github.com/sylabs/sif/v2/pkg/integrity"
+)
+
+// getSignExamples returns sign command examples based on rootCmd.
+func getSignExamples(rootPath string) string {
+	examples := []string{
+		rootPath +
+			" sign --key private.pem image.sif",
+		rootPath +
+			" sign --keyring secring.asc --group 1 image.sif",
+		rootPath +
+			" sign --key private.pem --object 1,2 --deterministic image.sif",
+	}
+	return strings.Join(examples, "\n")
+}
+
+var (
+	errKeyMaterialArgs     = errors.New("exactly one of --key or --keyring must be passed")
+	errNoPrivateKey        = errors.New("no entity with a private key found in keyring")
+	errMultiplePrivateKeys = errors.New("multiple entities with a private key found in keyring")
+	errPrivateKeyEncrypted = errors.New("private key is encrypted")
+)
+
+// loadSigner returns a signer using the PEM-encoded private key at path.
+func loadSigner(path string) (signature.Signer, error) { //nolint:ireturn
+	s, err := signature.LoadSignerFromPEMFile(path, crypto.SHA256, cryptoutils.SkipPassword)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load private key: %w", err)
+	}
+	return s, nil
+}
+
+// loadEntity returns the entity with a private key from the armored OpenPGP keyring at path.
+func loadEntity(path string) (*openpgp.Entity, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	el, err := openpgp.ReadArmoredKeyRing(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read keyring: %w", err)
+	}
+
+	var e *openpgp.Entity
+	for _, candidate := range el {
+		if candidate.PrivateKey == nil {
+			continue
+		}
+
+		if e != nil {
+			return nil, errMultiplePrivateKeys
+		}
+		e = candidate
+	}
+
+	if e == nil {
+		return nil, errNoPrivateKey
+	}
+
+	if e.PrivateKey.Encrypted {
+		return nil, errPrivateKeyEncrypted
+	}
+
+	return e, nil
+}
+
+// getSign returns a command that adds digital signature(s) to a SIF.
+func (c *command) getSign() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "sign <sif_path>",
+		Short:   "Add digital signature(s)",
+		Long:    "Add digital signature(s) to a SIF image.",
+		Example: getSignExamples(c.opts.rootPath),
+		Args:    cobra.ExactArgs(1),
+	}
+
+	keyPath := cmd.Flags().String("key", "", "path to PEM-encoded private key")
+	keyringPath := cmd.Flags().String("keyring", "", "path to armored OpenPGP keyring containing a private key")
+	groupIDs := cmd.Flags().UintSlice("group", nil, "sign object group(s) with the specified ID(s)")
+	objectIDs := cmd.Flags().UintSlice("object", nil, "sign object(s) with the specified ID(s)")
+	deterministic := cmd.Flags().Bool("deterministic", false, "set image/object timestamps to support deterministic output")
+	timestamp := cmd.Flags().String("time", "", "set signature timestamp (RFC 3339 format)")
+
+	cmd.PreRunE = c.initApp
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		var opts []integrity.SignerOpt
+
+		switch {
+		case *keyPath != "" && *keyringPath == "":
+			s, err := loadSigner(*keyPath)
+			if err != nil {
+				return err
+			}
+			opts = append(opts, integrity.OptSignWithSigner(s))
+
+		case *keyPath == "" && *keyringPath != "":
+			e, err := loadEntity(*keyringPath)
+			if err != nil {
+				return err
+			}
+			opts = append(opts, integrity.OptSignWithEntity(e))
+
+		default:
+			return errKeyMaterialArgs
+		}
+
+		for _, id := range *groupIDs {
+			opts = append(opts, integrity.OptSignGroup(uint32(id)))
+		}
+
+		if len(*objectIDs) > 0 {
+			ids := make([]uint32, 0, len(*objectIDs))
+			for _, id := range *objectIDs {
+				ids = append(ids, uint32(id))
+			}
+			opts = append(opts, integrity.OptSignObjects(ids...))
+		}
+
+		if *deterministic {
+			opts = append(opts, integrity.OptSignDeterministic())
+		}
+
+		if *timestamp != "" {
+			t, err := time.Parse(time.RFC3339, *timestamp)
+			if err != nil {
+				return fmt.Errorf("while parsing time: %w", err)
+			}
+			opts = append(opts, integrity.OptSignWithTime(func() time.Time { return t }))
+		}
+
+		return c.app.Sign(args[0], opts...)
+	}
+
+	return cmd
+}
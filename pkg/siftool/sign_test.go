@@ -0,0 +1,111 @@
+// Copyright (c) 2023, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package siftool
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var keys = filepath.Join("..", "..", "test", "keys")
+
+// copyTestSIF copies the SIF at path to a temporary file, and returns the path of the copy.
+func copyTestSIF(t *testing.T, path string) string {
+	t.Helper()
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tf, err := os.CreateTemp("", "sif-test-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Remove(tf.Name()) })
+	defer tf.Close()
+
+	if _, err := tf.Write(b); err != nil {
+		t.Fatal(err)
+	}
+
+	return tf.Name()
+}
+
+func Test_command_getSign(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    commandOpts
+		path    string
+		flags   []string
+		wantErr error
+	}{
+		{
+			name:    "ErrKeyMaterialArgs",
+			path:    filepath.Join(corpus, "one-group.sif"),
+			wantErr: errKeyMaterialArgs,
+		},
+		{
+			name: "ED25519",
+			path: filepath.Join(corpus, "one-group.sif"),
+			flags: []string{
+				"--key", filepath.Join(keys, "ed25519-private.pem"),
+			},
+		},
+		{
+			name: "RSA",
+			path: filepath.Join(corpus, "one-group.sif"),
+			flags: []string{
+				"--key", filepath.Join(keys, "rsa-private.pem"),
+			},
+		},
+		{
+			name: "PGP",
+			path: filepath.Join(corpus, "one-group.sif"),
+			flags: []string{
+				"--keyring", filepath.Join(keys, "private.asc"),
+			},
+		},
+		{
+			name: "Group",
+			path: filepath.Join(corpus, "two-groups.sif"),
+			flags: []string{
+				"--key", filepath.Join(keys, "ed25519-private.pem"),
+				"--group", "2",
+			},
+		},
+		{
+			name: "Objects",
+			path: filepath.Join(corpus, "two-groups.sif"),
+			flags: []string{
+				"--key", filepath.Join(keys, "ed25519-private.pem"),
+				"--object", "1,3",
+			},
+		},
+		{
+			name: "DeterministicWithTime",
+			path: filepath.Join(corpus, "one-group.sif"),
+			flags: []string{
+				"--keyring", filepath.Join(keys, "private.asc"),
+				"--deterministic",
+				"--time", "2017-09-06T00:25:53Z",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &command{opts: tt.opts}
+
+			cmd := c.getSign()
+
+			args := []string{copyTestSIF(t, tt.path)}
+			args = append(args, tt.flags...)
+
+			runCommand(t, cmd, args, tt.wantErr)
+		})
+	}
+}
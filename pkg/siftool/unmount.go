@@ -0,0 +1,37 @@
+// Copyright (c) 2023, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package siftool
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/sylabs/sif/v2/pkg/user"
+)
+
+// getUnmount returns a command that unmounts a filesystem mounted from a SIF image.
+func (c *command) getUnmount() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "unmount <mount_path>",
+		Short:   "Unmount partition",
+		Long:    "Unmount a partition of a SIF image that was mounted using the mount command.",
+		Example: c.opts.rootPath + " unmount path/",
+		Args:    cobra.ExactArgs(1),
+	}
+
+	fusermountPath := cmd.Flags().String("fusermount-path", "", "path to fusermount binary")
+
+	cmd.PreRunE = c.initApp
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		var opts []user.UnmountOpt
+
+		if *fusermountPath != "" {
+			opts = append(opts, user.OptUnmountFusermountPath(*fusermountPath))
+		}
+
+		return c.app.Unmount(cmd.Context(), args[0], opts...)
+	}
+
+	return cmd
+}
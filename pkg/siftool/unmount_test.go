@@ -0,0 +1,40 @@
+// Copyright (c) 2023, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package siftool
+
+import (
+	"os/exec"
+	"testing"
+)
+
+func Test_command_getUnmount(t *testing.T) {
+	// Substitute a binary that always succeeds for fusermount, so that the tests do not depend on
+	// FUSE.
+	truePath, err := exec.LookPath("true")
+	if err != nil {
+		t.Skip("true not found, skipping unmount tests")
+	}
+
+	tests := []struct {
+		name string
+		opts commandOpts
+		args []string
+	}{
+		{
+			name: "FusermountPath",
+			args: []string{"--fusermount-path", truePath},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &command{opts: tt.opts}
+
+			cmd := c.getUnmount()
+
+			runCommand(t, cmd, append(tt.args, t.TempDir()), nil)
+		})
+	}
+}
@@ -0,0 +1,133 @@
+// Copyright (c) 2023, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package siftool
+
+import (
+	"crypto"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/sigstore/sigstore/pkg/cryptoutils"
+	"github.com/sigstore/sigstore/pkg/signature"
+	"github.com/spf13/cobra"
+	"github.com/sylabs/sif/v2/pkg/integrity"
+)
+
+// getVerifyExamples returns verify command examples based on rootCmd.
+func getVerifyExamples(rootPath string) string {
+	examples := []string{
+		rootPath +
+			" verify --key public.pem image.sif",
+		rootPath +
+			" verify --keyring pubring.asc --group 1 image.sif",
+		rootPath +
+			" verify --keyring pubring.asc --legacy-all image.sif",
+	}
+	return strings.Join(examples, "\n")
+}
+
+var errVerifyKeyMaterialArgs = errors.New("at least one of --key or --keyring must be passed")
+
+// loadVerifier returns a verifier using the PEM-encoded public key at path.
+func loadVerifier(path string) (signature.Verifier, error) { //nolint:ireturn
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	pub, err := cryptoutils.UnmarshalPEMToPublicKey(b)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load public key: %w", err)
+	}
+
+	return signature.LoadVerifier(pub, crypto.SHA256)
+}
+
+// loadKeyRing returns the armored OpenPGP keyring at path.
+func loadKeyRing(path string) (openpgp.KeyRing, error) { //nolint:ireturn
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	el, err := openpgp.ReadArmoredKeyRing(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read keyring: %w", err)
+	}
+
+	return el, nil
+}
+
+// getVerify returns a command that verifies digital signature(s) in a SIF.
+func (c *command) getVerify() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "verify <sif_path>",
+		Short: "Verify digital signature(s)",
+		Long: `Verify digital signature(s) in a SIF image.
+
+A line is output for each signature that is verified, showing the signing entity
+or key, the IDs of the verified objects and the verification result. If any
+signature fails to verify, a non-zero exit code is returned.`,
+		Example: getVerifyExamples(c.opts.rootPath),
+		Args:    cobra.ExactArgs(1),
+	}
+
+	keyPaths := cmd.Flags().StringSlice("key", nil, "path(s) to PEM-encoded public key(s)")
+	keyringPath := cmd.Flags().String("keyring", "", "path to armored OpenPGP keyring")
+	groupIDs := cmd.Flags().UintSlice("group", nil, "verify object group(s) with the specified ID(s)")
+	objectIDs := cmd.Flags().UintSlice("object", nil, "verify object(s) with the specified ID(s)")
+	legacy := cmd.Flags().Bool("legacy", false, "only verify legacy signatures")
+	legacyAll := cmd.Flags().Bool("legacy-all", false, "only verify legacy signatures, for all non-signature objects")
+
+	cmd.PreRunE = c.initApp
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		if len(*keyPaths) == 0 && *keyringPath == "" {
+			return errVerifyKeyMaterialArgs
+		}
+
+		var opts []integrity.VerifierOpt
+
+		for _, path := range *keyPaths {
+			v, err := loadVerifier(path)
+			if err != nil {
+				return err
+			}
+			opts = append(opts, integrity.OptVerifyWithVerifier(v))
+		}
+
+		if *keyringPath != "" {
+			kr, err := loadKeyRing(*keyringPath)
+			if err != nil {
+				return err
+			}
+			opts = append(opts, integrity.OptVerifyWithKeyRing(kr))
+		}
+
+		for _, id := range *groupIDs {
+			opts = append(opts, integrity.OptVerifyGroup(uint32(id)))
+		}
+
+		for _, id := range *objectIDs {
+			opts = append(opts, integrity.OptVerifyObject(uint32(id)))
+		}
+
+		if *legacy {
+			opts = append(opts, integrity.OptVerifyLegacy())
+		}
+
+		if *legacyAll {
+			opts = append(opts, integrity.OptVerifyLegacyAll())
+		}
+
+		return c.app.Verify(args[0], opts...)
+	}
+
+	return cmd
+}
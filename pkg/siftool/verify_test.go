@@ -0,0 +1,96 @@
+// Copyright (c) 2023, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package siftool
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/sylabs/sif/v2/pkg/integrity"
+)
+
+func Test_command_getVerify(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    commandOpts
+		path    string
+		flags   []string
+		wantErr error
+	}{
+		{
+			name:    "ErrKeyMaterialArgs",
+			path:    filepath.Join(corpus, "one-group-signed-dsse.sif"),
+			wantErr: errVerifyKeyMaterialArgs,
+		},
+		{
+			name: "ErrSignatureNotValid",
+			path: filepath.Join(corpus, "one-group-signed-dsse.sif"),
+			flags: []string{
+				"--key", filepath.Join(keys, "ecdsa-public.pem"),
+			},
+			wantErr: &integrity.SignatureNotValidError{},
+		},
+		{
+			name: "DSSE",
+			path: filepath.Join(corpus, "one-group-signed-dsse.sif"),
+			flags: []string{
+				"--key", filepath.Join(keys, "ed25519-public.pem"),
+				"--key", filepath.Join(keys, "rsa-public.pem"),
+			},
+		},
+		{
+			name: "PGP",
+			path: filepath.Join(corpus, "one-group-signed-pgp.sif"),
+			flags: []string{
+				"--keyring", filepath.Join(keys, "private.asc"),
+			},
+		},
+		{
+			name: "Group",
+			path: filepath.Join(corpus, "two-groups-signed-pgp.sif"),
+			flags: []string{
+				"--keyring", filepath.Join(keys, "private.asc"),
+				"--group", "2",
+			},
+		},
+		{
+			name: "Object",
+			path: filepath.Join(corpus, "two-groups-signed-pgp.sif"),
+			flags: []string{
+				"--keyring", filepath.Join(keys, "private.asc"),
+				"--object", "1",
+			},
+		},
+		{
+			name: "Legacy",
+			path: filepath.Join(corpus, "one-group-signed-legacy-group.sif"),
+			flags: []string{
+				"--keyring", filepath.Join(keys, "private.asc"),
+				"--legacy",
+			},
+		},
+		{
+			name: "LegacyAll",
+			path: filepath.Join(corpus, "one-group-signed-legacy-all.sif"),
+			flags: []string{
+				"--keyring", filepath.Join(keys, "private.asc"),
+				"--legacy-all",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &command{opts: tt.opts}
+
+			cmd := c.getVerify()
+
+			args := []string{tt.path}
+			args = append(args, tt.flags...)
+
+			runCommand(t, cmd, args, tt.wantErr)
+		})
+	}
+}
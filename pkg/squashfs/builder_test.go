@@ -0,0 +1,481 @@
+// Copyright (c) 2023, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package squashfs
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"io/fs"
+	"path"
+	"sort"
+	"testing"
+)
+
+// testEntry describes a filesystem object to be written by buildTestImage.
+type testEntry struct {
+	path   string
+	mode   fs.FileMode // Type and permission bits.
+	data   []byte      // Regular file contents.
+	target string      // Symbolic link target.
+}
+
+// buildOpts configures buildTestImage.
+type buildOpts struct {
+	blockLog   uint16 // Log2 of data block size.
+	compress   bool   // Compress data and metadata blocks where beneficial.
+	fragments  bool   // Store file tails in fragment blocks.
+	sparse     bool   // Store zero-filled blocks as sparse blocks.
+	extended   bool   // Use extended inode types.
+	mtime      uint32 // Modification time of all objects.
+	modTime    uint32 // Superblock modification time.
+	dirEntries int    // Maximum number of entries per directory header (0 means 256).
+}
+
+// compressBlock returns the zlib compressed form of b, and true, if it is smaller than b.
+func compressBlock(t *testing.T, b []byte) ([]byte, bool) {
+	t.Helper()
+
+	var buf bytes.Buffer
+	zw := zlib.NewWriter(&buf)
+	if _, err := zw.Write(b); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if buf.Len() < len(b) {
+		return buf.Bytes(), true
+	}
+	return b, false
+}
+
+// metadataWriter writes a stream of metadata as a sequence of metadata blocks.
+type metadataWriter struct {
+	t        *testing.T
+	compress bool
+	out      bytes.Buffer // Completed blocks.
+	cur      []byte       // Current (incomplete) block.
+	n        int          // Total uncompressed bytes written.
+}
+
+// pos returns the location at which the next byte will be written, relative to the start of the
+// stream.
+func (mw *metadataWriter) pos() (uint32, uint16) {
+	return uint32(mw.out.Len()), uint16(len(mw.cur))
+}
+
+// ref returns a reference to the location at which the next byte will be written.
+func (mw *metadataWriter) ref() uint64 {
+	block, off := mw.pos()
+	return uint64(block)<<16 | uint64(off)
+}
+
+func (mw *metadataWriter) flush() {
+	b, compressed := mw.cur, false
+	if mw.compress {
+		b, compressed = compressBlock(mw.t, mw.cur)
+	}
+
+	h := uint16(len(b))
+	if !compressed {
+		h |= metadataUncompressed
+	}
+
+	_ = binary.Write(&mw.out, binary.LittleEndian, h)
+	mw.out.Write(b)
+	mw.cur = nil
+}
+
+func (mw *metadataWriter) write(v any) {
+	var buf bytes.Buffer
+	if err := binary.Write(&buf, binary.LittleEndian, v); err != nil {
+		mw.t.Fatal(err)
+	}
+
+	mw.n += buf.Len()
+
+	for b := buf.Bytes(); len(b) > 0; {
+		n := metadataBlockSize - len(mw.cur)
+		if n > len(b) {
+			n = len(b)
+		}
+		mw.cur = append(mw.cur, b[:n]...)
+		b = b[n:]
+
+		if len(mw.cur) == metadataBlockSize {
+			mw.flush()
+		}
+	}
+}
+
+// bytes returns the complete stream, flushing any incomplete block.
+func (mw *metadataWriter) bytes() []byte {
+	if len(mw.cur) > 0 {
+		mw.flush()
+	}
+	return mw.out.Bytes()
+}
+
+// testNode is a node in the tree of objects written by buildTestImage.
+type testNode struct {
+	testEntry
+	name     string
+	number   uint32
+	children []*testNode
+
+	// Regular file layout.
+	blocksStart uint64
+	blockSizes  []uint32
+	fragIndex   uint32
+	fragOffset  uint32
+}
+
+// testBuilder writes a SquashFS image.
+type testBuilder struct {
+	t    *testing.T
+	opts buildOpts
+	out  bytes.Buffer
+
+	frag      []byte     // Pending fragment block.
+	fragments [][16]byte // Fragment table entries.
+
+	inodes *metadataWriter
+	dirs   *metadataWriter
+}
+
+// writeData writes the contents of regular file n to the data section.
+func (b *testBuilder) writeData(n *testNode) {
+	bs := 1 << b.opts.blockLog
+
+	n.blocksStart = uint64(b.out.Len())
+	n.fragIndex = noFragment
+
+	data := n.data
+	for len(data) > 0 {
+		if len(data) < bs && b.opts.fragments {
+			break
+		}
+
+		block := data
+		if len(block) > bs {
+			block = block[:bs]
+		}
+		data = data[len(block):]
+
+		if b.opts.sparse && bytes.Count(block, []byte{0}) == len(block) {
+			n.blockSizes = append(n.blockSizes, 0)
+			continue
+		}
+
+		out, compressed := block, false
+		if b.opts.compress {
+			out, compressed = compressBlock(b.t, block)
+		}
+
+		s := uint32(len(out))
+		if !compressed {
+			s |= dataUncompressed
+		}
+
+		b.out.Write(out)
+		n.blockSizes = append(n.blockSizes, s)
+	}
+
+	// Store tail in a fragment.
+	if len(data) > 0 {
+		if len(b.frag)+len(data) > bs {
+			b.flushFragment()
+		}
+
+		n.fragIndex = uint32(len(b.fragments))
+		n.fragOffset = uint32(len(b.frag))
+		b.frag = append(b.frag, data...)
+	}
+}
+
+// flushFragment writes the pending fragment block, if any.
+func (b *testBuilder) flushFragment() {
+	if len(b.frag) == 0 {
+		return
+	}
+
+	out, compressed := b.frag, false
+	if b.opts.compress {
+		out, compressed = compressBlock(b.t, b.frag)
+	}
+
+	s := uint32(len(out))
+	if !compressed {
+		s |= dataUncompressed
+	}
+
+	var e [16]byte
+	binary.LittleEndian.PutUint64(e[0:], uint64(b.out.Len()))
+	binary.LittleEndian.PutUint32(e[8:], s)
+	b.fragments = append(b.fragments, e)
+
+	b.out.Write(out)
+	b.frag = nil
+}
+
+// inodeType returns the inode type used for n.
+func (b *testBuilder) inodeType(n *testNode) inodeType {
+	var t inodeType
+
+	switch m := n.mode; {
+	case m.IsDir():
+		t = inodeBasicDir
+	case m&fs.ModeSymlink != 0:
+		t = inodeBasicSymlink
+	case m&fs.ModeCharDevice != 0:
+		t = inodeBasicCharDev
+	case m&fs.ModeDevice != 0:
+		t = inodeBasicBlockDev
+	case m&fs.ModeNamedPipe != 0:
+		t = inodeBasicFifo
+	case m&fs.ModeSocket != 0:
+		t = inodeBasicSocket
+	default:
+		t = inodeBasicFile
+	}
+
+	if b.opts.extended {
+		t += inodeExtDir - inodeBasicDir
+	}
+
+	return t
+}
+
+// writeInode writes the inode for n, returning a reference to it.
+func (b *testBuilder) writeInode(n *testNode, dirBlock uint32, dirOffset uint16, dirSize uint32, parent uint32) uint64 { //nolint:lll
+	ref := b.inodes.ref()
+
+	perm := uint16(n.mode.Perm())
+	if n.mode&fs.ModeSetuid != 0 {
+		perm |= 0o4000
+	}
+	if n.mode&fs.ModeSetgid != 0 {
+		perm |= 0o2000
+	}
+	if n.mode&fs.ModeSticky != 0 {
+		perm |= 0o1000
+	}
+
+	t := b.inodeType(n)
+
+	b.inodes.write(inodeHeader{
+		Type:    t,
+		Perm:    perm,
+		ModTime: b.opts.mtime,
+		Number:  n.number,
+	})
+
+	switch t {
+	case inodeBasicDir:
+		b.inodes.write([]uint32{dirBlock, 2})
+		b.inodes.write([]uint16{uint16(dirSize), dirOffset})
+		b.inodes.write(parent)
+	case inodeExtDir:
+		b.inodes.write([]uint32{2, dirSize, dirBlock, parent})
+		b.inodes.write([]uint16{0, dirOffset})
+		b.inodes.write(uint32(noFragment))
+	case inodeBasicFile:
+		b.inodes.write([]uint32{uint32(n.blocksStart), n.fragIndex, n.fragOffset, uint32(len(n.data))})
+		b.inodes.write(n.blockSizes)
+	case inodeExtFile:
+		b.inodes.write([]uint64{n.blocksStart, uint64(len(n.data)), 0})
+		b.inodes.write([]uint32{1, n.fragIndex, n.fragOffset, noFragment})
+		b.inodes.write(n.blockSizes)
+	case inodeBasicSymlink, inodeExtSymlink:
+		b.inodes.write([]uint32{1, uint32(len(n.target))})
+		b.inodes.write([]byte(n.target))
+		if t == inodeExtSymlink {
+			b.inodes.write(uint32(noFragment))
+		}
+	case inodeBasicBlockDev, inodeBasicCharDev:
+		b.inodes.write([]uint32{1, 0x0103})
+	case inodeExtBlockDev, inodeExtCharDev:
+		b.inodes.write([]uint32{1, 0x0103, noFragment})
+	case inodeBasicFifo, inodeBasicSocket:
+		b.inodes.write(uint32(1))
+	case inodeExtFifo, inodeExtSocket:
+		b.inodes.write([]uint32{1, noFragment})
+	}
+
+	return ref
+}
+
+// writeTree writes the inodes and directory listings of the tree rooted at n, returning a
+// reference to the inode of n.
+func (b *testBuilder) writeTree(n *testNode, parent uint32) uint64 {
+	if !n.mode.IsDir() {
+		return b.writeInode(n, 0, 0, 0, 0)
+	}
+
+	refs := make([]uint64, len(n.children))
+	for i, c := range n.children {
+		refs[i] = b.writeTree(c, n.number)
+	}
+
+	dirBlock, dirOffset := b.dirs.pos()
+	start := b.dirs.n
+
+	limit := b.opts.dirEntries
+	if limit == 0 {
+		limit = 256
+	}
+
+	for i := 0; i < len(n.children); {
+		// Group entries that reference the same inode metadata block.
+		j := i + 1
+		for j < len(n.children) && j-i < limit && refs[j]>>16 == refs[i]>>16 {
+			j++
+		}
+
+		b.dirs.write([]uint32{uint32(j - i - 1), uint32(refs[i] >> 16), n.children[i].number})
+
+		for k := i; k < j; k++ {
+			c := n.children[k]
+			b.dirs.write([]uint16{uint16(refs[k] & 0xffff), uint16(c.number - n.children[i].number)})
+			b.dirs.write([]uint16{uint16(b.inodeType(c).basic()), uint16(len(c.name) - 1)})
+			b.dirs.write([]byte(c.name))
+		}
+
+		i = j
+	}
+
+	// The directory size includes three bytes for the implicit "." and ".." entries.
+	size := uint32(b.dirs.n-start) + 3
+
+	return b.writeInode(n, dirBlock, dirOffset, size, parent)
+}
+
+// buildTestImage returns a SquashFS image containing entries.
+func buildTestImage(t *testing.T, opts buildOpts, entries ...testEntry) []byte {
+	t.Helper()
+
+	if opts.blockLog == 0 {
+		opts.blockLog = 12
+	}
+
+	// Construct tree.
+	root := &testNode{testEntry: testEntry{mode: fs.ModeDir | 0o755}}
+	nodes := map[string]*testNode{".": root}
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].path < entries[j].path })
+
+	for _, e := range entries {
+		parent, ok := nodes[path.Dir(e.path)]
+		if !ok {
+			t.Fatalf("parent of %v not found", e.path)
+		}
+
+		n := &testNode{testEntry: e, name: path.Base(e.path)}
+		parent.children = append(parent.children, n)
+		nodes[e.path] = n
+	}
+
+	// Sort children by name, and number inodes.
+	var number uint32
+	var walk func(n *testNode)
+	walk = func(n *testNode) {
+		sort.Slice(n.children, func(i, j int) bool { return n.children[i].name < n.children[j].name })
+		for _, c := range n.children {
+			walk(c)
+		}
+		number++
+		n.number = number
+	}
+	walk(root)
+
+	b := &testBuilder{
+		t:      t,
+		opts:   opts,
+		inodes: &metadataWriter{t: t, compress: opts.compress},
+		dirs:   &metadataWriter{t: t, compress: opts.compress},
+	}
+
+	// Superblock placeholder.
+	b.out.Write(make([]byte, binary.Size(superblock{})))
+
+	// Data and fragment blocks.
+	for _, e := range entries {
+		if n := nodes[e.path]; n.mode.IsRegular() {
+			b.writeData(n)
+		}
+	}
+	b.flushFragment()
+
+	// Inode and directory tables.
+	rootRef := b.writeTree(root, number+1)
+
+	sb := superblock{
+		Magic:              magic,
+		InodeCount:         number,
+		ModTime:            opts.modTime,
+		BlockSize:          1 << opts.blockLog,
+		FragmentEntryCount: uint32(len(b.fragments)),
+		Compression:        compressionGZIP,
+		BlockLog:           opts.blockLog,
+		Flags:              0x0200, // No xattrs.
+		IDCount:            1,
+		VersionMajor:       4,
+		RootInodeRef:       rootRef,
+		XattrIDTableStart:  ^uint64(0),
+		ExportTableStart:   ^uint64(0),
+	}
+
+	sb.InodeTableStart = uint64(b.out.Len())
+	b.out.Write(b.inodes.bytes())
+
+	sb.DirectoryTableStart = uint64(b.out.Len())
+	b.out.Write(b.dirs.bytes())
+
+	// Fragment table.
+	frags := &metadataWriter{t: t, compress: opts.compress}
+	var fragLocs []uint64
+	for i, e := range b.fragments {
+		if i%fragmentEntriesPerBlock == 0 {
+			fragLocs = append(fragLocs, uint64(frags.out.Len()))
+		}
+		frags.write(e)
+	}
+	fragStart := uint64(b.out.Len())
+	b.out.Write(frags.bytes())
+
+	sb.FragmentTableStart = uint64(b.out.Len())
+	for _, loc := range fragLocs {
+		_ = binary.Write(&b.out, binary.LittleEndian, fragStart+loc)
+	}
+
+	// ID table.
+	ids := &metadataWriter{t: t, compress: opts.compress}
+	ids.write(uint32(0))
+	idStart := uint64(b.out.Len())
+	b.out.Write(ids.bytes())
+
+	sb.IDTableStart = uint64(b.out.Len())
+	_ = binary.Write(&b.out, binary.LittleEndian, idStart)
+
+	sb.BytesUsed = uint64(b.out.Len())
+
+	// Pad to 4KiB, and write superblock.
+	if r := b.out.Len() % 4096; r != 0 {
+		b.out.Write(make([]byte, 4096-r))
+	}
+
+	img := b.out.Bytes()
+
+	var sbb bytes.Buffer
+	if err := binary.Write(&sbb, binary.LittleEndian, sb); err != nil {
+		t.Fatal(err)
+	}
+	copy(img, sbb.Bytes())
+
+	return img
+}
@@ -0,0 +1,160 @@
+// Copyright (c) 2023, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package squashfs
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+)
+
+// dirEntry is an entry read from a directory. It implements the fs.DirEntry interface.
+type dirEntry struct {
+	f    *FS
+	name string
+	typ  inodeType
+	ref  uint64 // Reference to inode.
+}
+
+func (e *dirEntry) Name() string      { return e.name }
+func (e *dirEntry) IsDir() bool       { return e.typ.basic() == inodeBasicDir }
+func (e *dirEntry) Type() fs.FileMode { return e.typ.typeMode() }
+
+// Info returns the fs.FileInfo for the file or subdirectory described by e.
+func (e *dirEntry) Info() (fs.FileInfo, error) {
+	in, err := e.f.readInode(e.ref)
+	if err != nil {
+		return nil, err
+	}
+	return &fileInfo{e.name, in}, nil
+}
+
+// readDir reads the entries of the directory in, sorted by name.
+func (f *FS) readDir(in *inode) ([]*dirEntry, error) {
+	// The directory size includes three bytes for the implicit "." and ".." entries.
+	remaining := in.size - 3
+	if remaining <= 0 {
+		return nil, nil
+	}
+
+	mr := f.newMetadataReader(int64(f.sb.DirectoryTableStart)+int64(in.dirBlock), int(in.dirOffset))
+
+	var entries []*dirEntry
+
+	for remaining > 0 {
+		var h struct {
+			Count       uint32
+			Start       uint32
+			InodeNumber uint32
+		}
+		if err := binary.Read(mr, binary.LittleEndian, &h); err != nil {
+			return nil, fmt.Errorf("reading directory header: %w", err)
+		}
+		remaining -= int64(binary.Size(h))
+
+		// A directory header is followed by up to 256 entries.
+		if h.Count >= 256 {
+			return nil, fmt.Errorf("%w: too many directory entries", errCorrupt)
+		}
+
+		for i := uint32(0); i <= h.Count; i++ {
+			var e struct {
+				Offset      uint16
+				InodeOffset int16
+				Type        inodeType
+				NameSize    uint16
+			}
+			if err := binary.Read(mr, binary.LittleEndian, &e); err != nil {
+				return nil, fmt.Errorf("reading directory entry: %w", err)
+			}
+
+			name, err := mr.readString(int(e.NameSize) + 1)
+			if err != nil {
+				return nil, fmt.Errorf("reading directory entry: %w", err)
+			}
+			remaining -= int64(binary.Size(e)) + int64(len(name))
+
+			if !fs.ValidPath(name) || name == "." || strings.Contains(name, "/") {
+				return nil, fmt.Errorf("%w: invalid directory entry name %q", errCorrupt, name)
+			}
+
+			entries = append(entries, &dirEntry{
+				f:    f,
+				name: name,
+				typ:  e.Type,
+				ref:  uint64(h.Start)<<16 | uint64(e.Offset),
+			})
+		}
+	}
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].name < entries[j].name })
+
+	return entries, nil
+}
+
+// lookup returns the entry with the specified name in directory in.
+func (f *FS) lookup(in *inode, name string) (*dirEntry, error) {
+	entries, err := f.readDir(in)
+	if err != nil {
+		return nil, err
+	}
+
+	i := sort.Search(len(entries), func(i int) bool { return entries[i].name >= name })
+	if i < len(entries) && entries[i].name == name {
+		return entries[i], nil
+	}
+
+	return nil, fs.ErrNotExist
+}
+
+// dir is an open directory. It implements the fs.ReadDirFile interface.
+type dir struct {
+	f       *FS
+	name    string
+	in      *inode
+	entries []*dirEntry // Remaining entries, populated on first call to ReadDir.
+	read    bool        // Set when entries populated.
+}
+
+func (d *dir) Stat() (fs.FileInfo, error) { return &fileInfo{path.Base(d.name), d.in}, nil }
+
+func (d *dir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.name, Err: errIsDir}
+}
+
+func (d *dir) Close() error { return nil }
+
+// ReadDir reads the contents of the directory and returns a slice of up to n DirEntry values in
+// directory order. It implements the fs.ReadDirFile interface.
+func (d *dir) ReadDir(n int) ([]fs.DirEntry, error) {
+	if !d.read {
+		entries, err := d.f.readDir(d.in)
+		if err != nil {
+			return nil, &fs.PathError{Op: "readdir", Path: d.name, Err: err}
+		}
+		d.entries, d.read = entries, true
+	}
+
+	if n <= 0 {
+		n = len(d.entries)
+	} else if len(d.entries) == 0 {
+		return nil, io.EOF
+	} else if n > len(d.entries) {
+		n = len(d.entries)
+	}
+
+	des := make([]fs.DirEntry, n)
+	for i := range des {
+		des[i] = d.entries[i]
+	}
+	d.entries = d.entries[n:]
+
+	return des, nil
+}
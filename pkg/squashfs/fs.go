@@ -0,0 +1,306 @@
+// Copyright (c) 2023, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package squashfs
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"path"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxSymlinks is the maximum number of symbolic links followed when resolving a path.
+const maxSymlinks = 40
+
+// fileInfo describes a file. It implements the fs.FileInfo interface.
+type fileInfo struct {
+	name string
+	in   *inode
+}
+
+func (fi *fileInfo) Name() string       { return fi.name }
+func (fi *fileInfo) Size() int64        { return fi.in.size }
+func (fi *fileInfo) Mode() fs.FileMode  { return fi.in.mode() }
+func (fi *fileInfo) ModTime() time.Time { return fi.in.modTime() }
+func (fi *fileInfo) IsDir() bool        { return fi.in.isDir() }
+func (fi *fileInfo) Sys() any           { return nil }
+
+// resolve returns the inode at name. Symbolic links encountered in the directory portion of name
+// are always followed. If follow is true, a symbolic link in the final element of name is also
+// followed.
+func (f *FS) resolve(op, name string, follow bool) (*inode, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+
+	in := f.root
+	dir := "" // Path of in, relative to root.
+	rest := name
+	if rest == "." {
+		rest = ""
+	}
+
+	for links := 0; rest != ""; {
+		var elem string
+		elem, rest, _ = strings.Cut(rest, "/")
+
+		if !in.isDir() {
+			return nil, &fs.PathError{Op: op, Path: name, Err: errNotDir}
+		}
+
+		e, err := f.lookup(in, elem)
+		if err != nil {
+			return nil, &fs.PathError{Op: op, Path: name, Err: err}
+		}
+
+		child, err := f.readInode(e.ref)
+		if err != nil {
+			return nil, &fs.PathError{Op: op, Path: name, Err: err}
+		}
+
+		if child.isSymlink() && (rest != "" || follow) {
+			if links++; links > maxSymlinks {
+				return nil, &fs.PathError{Op: op, Path: name, Err: errTooManyLinks}
+			}
+
+			// Restart resolution from the root, using the link target in place of elem. Targets
+			// that refer to locations above the root are confined to the root.
+			target := child.target
+			if !path.IsAbs(target) {
+				target = path.Join("/", dir, target)
+			}
+
+			in, dir = f.root, ""
+			rest = strings.TrimPrefix(path.Join(path.Clean("/"+target), rest), "/")
+			continue
+		}
+
+		in, dir = child, path.Join(dir, elem)
+	}
+
+	return in, nil
+}
+
+// Open opens the named file. It implements the fs.FS interface.
+//
+// Symbolic links are followed. The returned file implements io.ReaderAt and io.Seeker if it is a
+// regular file, or fs.ReadDirFile if it is a directory.
+func (f *FS) Open(name string) (fs.File, error) {
+	in, err := f.resolve("open", name, true)
+	if err != nil {
+		return nil, err
+	}
+
+	if in.isDir() {
+		return &dir{f: f, name: name, in: in}, nil
+	}
+
+	return &file{f: f, name: name, in: in, cached: -1}, nil
+}
+
+// ReadDir reads the named directory and returns a list of directory entries sorted by filename.
+// It implements the fs.ReadDirFS interface.
+func (f *FS) ReadDir(name string) ([]fs.DirEntry, error) {
+	in, err := f.resolve("readdir", name, true)
+	if err != nil {
+		return nil, err
+	}
+
+	if !in.isDir() {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: errNotDir}
+	}
+
+	entries, err := f.readDir(in)
+	if err != nil {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: err}
+	}
+
+	des := make([]fs.DirEntry, 0, len(entries))
+	for _, e := range entries {
+		des = append(des, e)
+	}
+
+	return des, nil
+}
+
+// Stat returns a fs.FileInfo describing the named file. Symbolic links are followed. It
+// implements the fs.StatFS interface.
+func (f *FS) Stat(name string) (fs.FileInfo, error) {
+	in, err := f.resolve("stat", name, true)
+	if err != nil {
+		return nil, err
+	}
+	return &fileInfo{path.Base(name), in}, nil
+}
+
+// Lstat returns a fs.FileInfo describing the named file. If the file is a symbolic link, the
+// returned fs.FileInfo describes the link, and the link is not followed.
+func (f *FS) Lstat(name string) (fs.FileInfo, error) {
+	in, err := f.resolve("lstat", name, false)
+	if err != nil {
+		return nil, err
+	}
+	return &fileInfo{path.Base(name), in}, nil
+}
+
+// ReadLink returns the destination of the named symbolic link.
+func (f *FS) ReadLink(name string) (string, error) {
+	in, err := f.resolve("readlink", name, false)
+	if err != nil {
+		return "", err
+	}
+
+	if !in.isSymlink() {
+		return "", &fs.PathError{Op: "readlink", Path: name, Err: errNotSymlink}
+	}
+
+	return in.target, nil
+}
+
+var errNegativeOffset = errors.New("negative offset")
+
+// file is an open file. It implements the fs.File, io.ReaderAt and io.Seeker interfaces.
+type file struct {
+	f    *FS
+	name string
+	in   *inode
+	off  int64 // Offset for next call to Read.
+
+	mu     sync.Mutex // Protects cached and block.
+	cached int64      // Index of cached block, or -1 if none.
+	block  []byte     // Cached block.
+}
+
+func (fl *file) Stat() (fs.FileInfo, error) { return &fileInfo{path.Base(fl.name), fl.in}, nil }
+
+func (fl *file) Close() error { return nil }
+
+// readBlock returns the decompressed block of fl with index i. The returned slice must not be
+// modified, since it may be shared with concurrent callers.
+func (fl *file) readBlock(i int64) ([]byte, error) {
+	fl.mu.Lock()
+	if i == fl.cached {
+		b := fl.block
+		fl.mu.Unlock()
+		return b, nil
+	}
+	fl.mu.Unlock()
+
+	in := fl.in
+	bs := int64(fl.f.sb.BlockSize)
+
+	// Determine the number of bytes expected in the block.
+	n := in.size - i*bs
+	if n > bs {
+		n = bs
+	}
+
+	var b []byte
+
+	if i < int64(len(in.blockSizes)) {
+		if s := in.blockSizes[i]; s == 0 {
+			// Sparse block.
+			b = make([]byte, n)
+		} else {
+			var err error
+			if b, err = fl.f.readBlock(in.blockOffsets[i], s); err != nil {
+				return nil, err
+			}
+		}
+	} else {
+		// Tail of file is stored in a fragment.
+		frag, err := fl.f.fragment(in.fragIndex)
+		if err != nil {
+			return nil, err
+		}
+
+		if int64(in.fragOffset)+n > int64(len(frag)) {
+			return nil, errCorrupt
+		}
+		b = frag[in.fragOffset : int64(in.fragOffset)+n]
+	}
+
+	if int64(len(b)) < n {
+		return nil, errCorrupt
+	}
+
+	b = b[:n]
+
+	fl.mu.Lock()
+	fl.cached, fl.block = i, b
+	fl.mu.Unlock()
+
+	return b, nil
+}
+
+// ReadAt reads len(p) bytes into p starting at offset off. It implements the io.ReaderAt
+// interface. Files other than regular files have no content. ReadAt may be called concurrently.
+func (fl *file) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, &fs.PathError{Op: "read", Path: fl.name, Err: errNegativeOffset}
+	}
+
+	bs := int64(fl.f.sb.BlockSize)
+
+	size := int64(0)
+	if fl.in.isRegular() {
+		size = fl.in.size
+	}
+
+	var n int
+	for n < len(p) && off < size {
+		b, err := fl.readBlock(off / bs)
+		if err != nil {
+			return n, &fs.PathError{Op: "read", Path: fl.name, Err: err}
+		}
+
+		c := copy(p[n:], b[off%bs:])
+		n += c
+		off += int64(c)
+	}
+
+	if n < len(p) {
+		return n, io.EOF
+	}
+
+	return n, nil
+}
+
+// Read reads up to len(p) bytes into p. It implements the io.Reader interface.
+func (fl *file) Read(p []byte) (int, error) {
+	n, err := fl.ReadAt(p, fl.off)
+	fl.off += int64(n)
+
+	if errors.Is(err, io.EOF) && n > 0 {
+		err = nil
+	}
+
+	return n, err
+}
+
+// Seek sets the offset for the next Read. It implements the io.Seeker interface.
+func (fl *file) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += fl.off
+	case io.SeekEnd:
+		offset += fl.in.size
+	default:
+		return 0, &fs.PathError{Op: "seek", Path: fl.name, Err: fs.ErrInvalid}
+	}
+
+	if offset < 0 {
+		return 0, &fs.PathError{Op: "seek", Path: fl.name, Err: errNegativeOffset}
+	}
+
+	fl.off = offset
+
+	return offset, nil
+}
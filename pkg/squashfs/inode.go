@@ -0,0 +1,258 @@
+// Copyright (c) 2023, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package squashfs
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io/fs"
+	"time"
+)
+
+// inodeType is the type of an inode.
+type inodeType uint16
+
+// List of inode types.
+const (
+	inodeBasicDir inodeType = iota + 1
+	inodeBasicFile
+	inodeBasicSymlink
+	inodeBasicBlockDev
+	inodeBasicCharDev
+	inodeBasicFifo
+	inodeBasicSocket
+	inodeExtDir
+	inodeExtFile
+	inodeExtSymlink
+	inodeExtBlockDev
+	inodeExtCharDev
+	inodeExtFifo
+	inodeExtSocket
+)
+
+// basic returns the basic inode type corresponding to t.
+func (t inodeType) basic() inodeType {
+	if t >= inodeExtDir {
+		return t - (inodeExtDir - inodeBasicDir)
+	}
+	return t
+}
+
+// inodeHeader is the on-disk representation of the header common to all inodes.
+type inodeHeader struct {
+	Type     inodeType
+	Perm     uint16
+	UIDIndex uint16
+	GIDIndex uint16
+	ModTime  uint32
+	Number   uint32
+}
+
+// inode describes a file, directory, or other filesystem object.
+type inode struct {
+	inodeHeader
+
+	size int64 // Size of file or directory listing, or length of symlink target.
+
+	// Directory fields.
+	dirBlock  uint32 // Location of directory listing, relative to start of directory table.
+	dirOffset uint16 // Offset of directory listing within the metadata block.
+
+	// Regular file fields.
+	blocksStart  int64    // On-disk offset of first data block.
+	blockSizes   []uint32 // On-disk size information of each data block.
+	fragIndex    uint32   // Index of fragment block containing tail of file.
+	fragOffset   uint32   // Offset of tail of file within fragment block.
+	blockOffsets []int64  // On-disk offset of each data block.
+
+	// Symbolic link fields.
+	target string
+}
+
+func (in *inode) isDir() bool     { return in.Type.basic() == inodeBasicDir }
+func (in *inode) isRegular() bool { return in.Type.basic() == inodeBasicFile }
+func (in *inode) isSymlink() bool { return in.Type.basic() == inodeBasicSymlink }
+
+// typeMode returns the type bits of the file mode corresponding to t.
+func (t inodeType) typeMode() fs.FileMode {
+	switch t.basic() {
+	case inodeBasicDir:
+		return fs.ModeDir
+	case inodeBasicSymlink:
+		return fs.ModeSymlink
+	case inodeBasicBlockDev:
+		return fs.ModeDevice
+	case inodeBasicCharDev:
+		return fs.ModeDevice | fs.ModeCharDevice
+	case inodeBasicFifo:
+		return fs.ModeNamedPipe
+	case inodeBasicSocket:
+		return fs.ModeSocket
+	}
+	return 0
+}
+
+// mode returns the file mode of in.
+func (in *inode) mode() fs.FileMode {
+	m := fs.FileMode(in.Perm&0o777) | in.Type.typeMode()
+
+	if in.Perm&0o4000 != 0 {
+		m |= fs.ModeSetuid
+	}
+	if in.Perm&0o2000 != 0 {
+		m |= fs.ModeSetgid
+	}
+	if in.Perm&0o1000 != 0 {
+		m |= fs.ModeSticky
+	}
+
+	return m
+}
+
+// modTime returns the modification time of in.
+func (in *inode) modTime() time.Time {
+	return time.Unix(int64(in.ModTime), 0)
+}
+
+// readInode reads the inode referenced by ref.
+func (f *FS) readInode(ref uint64) (*inode, error) {
+	mr := f.newMetadataReader(int64(f.sb.InodeTableStart+ref>>16), int(ref&0xffff))
+
+	in := inode{fragIndex: noFragment}
+
+	if err := binary.Read(mr, binary.LittleEndian, &in.inodeHeader); err != nil {
+		return nil, fmt.Errorf("reading inode: %w", err)
+	}
+
+	var err error
+
+	switch in.Type {
+	case inodeBasicDir:
+		var d struct {
+			BlockIndex  uint32
+			LinkCount   uint32
+			FileSize    uint16
+			BlockOffset uint16
+			ParentInode uint32
+		}
+		if err = binary.Read(mr, binary.LittleEndian, &d); err == nil {
+			in.size = int64(d.FileSize)
+			in.dirBlock = d.BlockIndex
+			in.dirOffset = d.BlockOffset
+		}
+
+	case inodeExtDir:
+		var d struct {
+			LinkCount   uint32
+			FileSize    uint32
+			BlockIndex  uint32
+			ParentInode uint32
+			IndexCount  uint16
+			BlockOffset uint16
+			XattrIndex  uint32
+		}
+		if err = binary.Read(mr, binary.LittleEndian, &d); err == nil {
+			in.size = int64(d.FileSize)
+			in.dirBlock = d.BlockIndex
+			in.dirOffset = d.BlockOffset
+		}
+
+	case inodeBasicFile:
+		var d struct {
+			BlocksStart uint32
+			FragIndex   uint32
+			BlockOffset uint32
+			FileSize    uint32
+		}
+		if err = binary.Read(mr, binary.LittleEndian, &d); err == nil {
+			in.size = int64(d.FileSize)
+			in.blocksStart = int64(d.BlocksStart)
+			in.fragIndex = d.FragIndex
+			in.fragOffset = d.BlockOffset
+			err = f.readBlockSizes(mr, &in)
+		}
+
+	case inodeExtFile:
+		var d struct {
+			BlocksStart uint64
+			FileSize    uint64
+			Sparse      uint64
+			LinkCount   uint32
+			FragIndex   uint32
+			BlockOffset uint32
+			XattrIndex  uint32
+		}
+		if err = binary.Read(mr, binary.LittleEndian, &d); err == nil {
+			in.size = int64(d.FileSize)
+			in.blocksStart = int64(d.BlocksStart)
+			in.fragIndex = d.FragIndex
+			in.fragOffset = d.BlockOffset
+			err = f.readBlockSizes(mr, &in)
+		}
+
+	case inodeBasicSymlink, inodeExtSymlink:
+		var d struct {
+			LinkCount  uint32
+			TargetSize uint32
+		}
+		if err = binary.Read(mr, binary.LittleEndian, &d); err == nil {
+			if d.TargetSize > 4096 {
+				return nil, fmt.Errorf("%w: symlink target too long", errCorrupt)
+			}
+			in.size = int64(d.TargetSize)
+			in.target, err = mr.readString(int(d.TargetSize))
+		}
+
+	case inodeBasicBlockDev, inodeBasicCharDev, inodeBasicFifo, inodeBasicSocket,
+		inodeExtBlockDev, inodeExtCharDev, inodeExtFifo, inodeExtSocket:
+		// No type-specific information required.
+
+	default:
+		return nil, fmt.Errorf("%w: unknown inode type %v", errCorrupt, in.Type)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("reading inode: %w", err)
+	}
+
+	return &in, nil
+}
+
+// readBlockSizes reads the data block size information of the regular file in from mr, and
+// populates the on-disk offset of each block.
+func (f *FS) readBlockSizes(mr *metadataReader, in *inode) error {
+	if in.size < 0 {
+		return fmt.Errorf("%w: negative file size", errCorrupt)
+	}
+
+	bs := int64(f.sb.BlockSize)
+
+	// If the tail of the file is stored in a fragment, there is no block for it.
+	n := in.size / bs
+	if in.fragIndex == noFragment && in.size%bs != 0 {
+		n++
+	}
+
+	// Each block requires four bytes of size information, so this bounds the number of blocks.
+	if n*4 > int64(f.sb.BytesUsed) {
+		return fmt.Errorf("%w: too many data blocks", errCorrupt)
+	}
+
+	in.blockSizes = make([]uint32, n)
+	if err := binary.Read(mr, binary.LittleEndian, in.blockSizes); err != nil {
+		return err
+	}
+
+	in.blockOffsets = make([]int64, n)
+
+	off := in.blocksStart
+	for i, s := range in.blockSizes {
+		in.blockOffsets[i] = off
+		off += int64(s &^ dataUncompressed)
+	}
+
+	return nil
+}
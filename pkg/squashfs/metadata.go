@@ -0,0 +1,135 @@
+// Copyright (c) 2023, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package squashfs
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// metadataCacheSize is the maximum number of decompressed metadata blocks to cache.
+const metadataCacheSize = 1024
+
+// metadataBlock is a decompressed metadata block.
+type metadataBlock struct {
+	data []byte // Decompressed data.
+	next int64  // On-disk offset of the following metadata block.
+}
+
+// readMetadataBlock reads the metadata block at on-disk offset off.
+func (f *FS) readMetadataBlock(off int64) (metadataBlock, error) {
+	f.mu.Lock()
+	mb, ok := f.metadata[off]
+	f.mu.Unlock()
+
+	if ok {
+		return mb, nil
+	}
+
+	var h [2]byte
+	if _, err := f.r.ReadAt(h[:], off); err != nil {
+		return metadataBlock{}, fmt.Errorf("reading metadata block header: %w", err)
+	}
+	s := binary.LittleEndian.Uint16(h[:])
+
+	b := make([]byte, s&^metadataUncompressed)
+	if _, err := f.r.ReadAt(b, off+2); err != nil {
+		return metadataBlock{}, fmt.Errorf("reading metadata block: %w", err)
+	}
+
+	if s&metadataUncompressed == 0 {
+		var err error
+		if b, err = f.decompress(b, metadataBlockSize); err != nil {
+			return metadataBlock{}, err
+		}
+	}
+
+	mb = metadataBlock{
+		data: b,
+		next: off + int64(len(h)) + int64(s&^metadataUncompressed),
+	}
+
+	f.mu.Lock()
+	if len(f.metadata) >= metadataCacheSize {
+		f.metadata = make(map[int64]metadataBlock)
+	}
+	f.metadata[off] = mb
+	f.mu.Unlock()
+
+	return mb, nil
+}
+
+// metadataReader reads a stream of data that spans one or more consecutive metadata blocks.
+type metadataReader struct {
+	f    *FS
+	next int64  // On-disk offset of the next metadata block.
+	pos  int    // Offset of unread data within b.
+	b    []byte // Current decompressed metadata block.
+	err  error  // Deferred error.
+}
+
+// newMetadataReader returns a reader that reads metadata starting at offset pos within the
+// metadata block at on-disk offset off.
+func (f *FS) newMetadataReader(off int64, pos int) *metadataReader {
+	mr := &metadataReader{f: f, next: off}
+
+	if mr.err = mr.advance(); mr.err == nil {
+		if pos > len(mr.b) {
+			mr.err = fmt.Errorf("%w: metadata offset %v out of range", errCorrupt, pos)
+		}
+		mr.pos = pos
+	}
+
+	return mr
+}
+
+// advance reads the next metadata block.
+func (mr *metadataReader) advance() error {
+	mb, err := mr.f.readMetadataBlock(mr.next)
+	if err != nil {
+		return err
+	}
+
+	if len(mb.data) == 0 {
+		return fmt.Errorf("%w: empty metadata block", errCorrupt)
+	}
+
+	mr.b, mr.pos, mr.next = mb.data, 0, mb.next
+
+	return nil
+}
+
+// Read reads up to len(p) bytes into p. It implements the io.Reader interface.
+func (mr *metadataReader) Read(p []byte) (int, error) {
+	var n int
+
+	for n < len(p) && mr.err == nil {
+		if mr.pos == len(mr.b) {
+			mr.err = mr.advance()
+			continue
+		}
+
+		c := copy(p[n:], mr.b[mr.pos:])
+		mr.pos += c
+		n += c
+	}
+
+	if n == len(p) {
+		return n, nil
+	}
+
+	return n, mr.err
+}
+
+// readString reads a string of length n from mr.
+func (mr *metadataReader) readString(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := io.ReadFull(mr, b); err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
@@ -0,0 +1,253 @@
+// Copyright (c) 2023, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+// Package squashfs implements read-only access to SquashFS filesystems, such as those found in
+// the partitions of SIF images, without the need for FUSE or other external tooling.
+package squashfs
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/sylabs/sif/v2/pkg/sif"
+)
+
+const (
+	magic = 0x73717368 // "hsqs"
+
+	metadataBlockSize       = 8192    // Maximum uncompressed size of a metadata block.
+	metadataUncompressed    = 1 << 15 // Metadata block header flag for uncompressed data.
+	dataUncompressed        = 1 << 24 // Data block size flag for uncompressed data.
+	fragmentEntriesPerBlock = metadataBlockSize / 16
+	noFragment              = 0xffffffff
+)
+
+// compression is the compression algorithm used by a SquashFS filesystem.
+type compression uint16
+
+// List of compression algorithms.
+const (
+	compressionGZIP compression = iota + 1
+	compressionLZMA
+	compressionLZO
+	compressionXZ
+	compressionLZ4
+	compressionZSTD
+)
+
+// String returns a human-readable representation of c.
+func (c compression) String() string {
+	switch c {
+	case compressionGZIP:
+		return "gzip"
+	case compressionLZMA:
+		return "lzma"
+	case compressionLZO:
+		return "lzo"
+	case compressionXZ:
+		return "xz"
+	case compressionLZ4:
+		return "lz4"
+	case compressionZSTD:
+		return "zstd"
+	}
+	return "unknown"
+}
+
+// superblock is the on-disk representation of a SquashFS superblock.
+type superblock struct {
+	Magic               uint32
+	InodeCount          uint32
+	ModTime             uint32
+	BlockSize           uint32
+	FragmentEntryCount  uint32
+	Compression         compression
+	BlockLog            uint16
+	Flags               uint16
+	IDCount             uint16
+	VersionMajor        uint16
+	VersionMinor        uint16
+	RootInodeRef        uint64
+	BytesUsed           uint64
+	IDTableStart        uint64
+	XattrIDTableStart   uint64
+	InodeTableStart     uint64
+	DirectoryTableStart uint64
+	FragmentTableStart  uint64
+	ExportTableStart    uint64
+}
+
+var (
+	errInvalidMagic   = errors.New("invalid SquashFS magic")
+	errInvalidBlock   = errors.New("invalid block size")
+	errCorrupt        = errors.New("corrupt SquashFS filesystem")
+	errNotReaderAt    = errors.New("data object reader does not implement io.ReaderAt")
+	errTooManyLinks   = errors.New("too many levels of symbolic links")
+	errNotDir         = errors.New("not a directory")
+	errIsDir          = errors.New("is a directory")
+	errNotSymlink     = errors.New("not a symbolic link")
+	errInvalidVersion = errors.New("unsupported SquashFS version")
+)
+
+// ErrNotSquashFS is the error returned when a data object is not a SquashFS partition.
+var ErrNotSquashFS = errors.New("data object is not a SquashFS partition")
+
+// unsupportedCompressionError records an unsupported compression algorithm.
+type unsupportedCompressionError struct {
+	c compression
+}
+
+func (e *unsupportedCompressionError) Error() string {
+	return fmt.Sprintf("unsupported compression algorithm: %v", e.c)
+}
+
+func (e *unsupportedCompressionError) Is(target error) bool {
+	t, ok := target.(*unsupportedCompressionError)
+	if !ok {
+		return false
+	}
+	return e.c == t.c || t.c == 0
+}
+
+// FS provides read-only access to a SquashFS filesystem. It implements the fs.FS, fs.ReadDirFS
+// and fs.StatFS interfaces, and is safe for concurrent use.
+type FS struct {
+	r  io.ReaderAt
+	sb superblock
+
+	root *inode
+
+	mu       sync.Mutex
+	metadata map[int64]metadataBlock // Decompressed metadata blocks, indexed by on-disk offset.
+}
+
+// New returns a FS that reads the SquashFS filesystem from r.
+//
+// Only filesystems that use gzip compression are supported.
+func New(r io.ReaderAt) (*FS, error) {
+	f := &FS{
+		r:        r,
+		metadata: make(map[int64]metadataBlock),
+	}
+
+	if err := binary.Read(io.NewSectionReader(r, 0, int64(binary.Size(f.sb))), binary.LittleEndian, &f.sb); err != nil { //nolint:lll
+		return nil, fmt.Errorf("reading superblock: %w", err)
+	}
+
+	if f.sb.Magic != magic {
+		return nil, errInvalidMagic
+	}
+
+	if f.sb.VersionMajor != 4 || f.sb.VersionMinor != 0 {
+		return nil, fmt.Errorf("%w: %v.%v", errInvalidVersion, f.sb.VersionMajor, f.sb.VersionMinor)
+	}
+
+	if f.sb.BlockLog > 20 || f.sb.BlockSize != 1<<f.sb.BlockLog {
+		return nil, fmt.Errorf("%w: %v", errInvalidBlock, f.sb.BlockSize)
+	}
+
+	if f.sb.Compression != compressionGZIP {
+		return nil, &unsupportedCompressionError{f.sb.Compression}
+	}
+
+	root, err := f.readInode(f.sb.RootInodeRef)
+	if err != nil {
+		return nil, fmt.Errorf("reading root inode: %w", err)
+	}
+
+	if !root.isDir() {
+		return nil, fmt.Errorf("%w: root inode is not a directory", errCorrupt)
+	}
+	f.root = root
+
+	return f, nil
+}
+
+// FromDescriptor returns a FS that reads the SquashFS partition described by d.
+func FromDescriptor(d sif.Descriptor) (*FS, error) {
+	if d.DataType() != sif.DataPartition {
+		return nil, ErrNotSquashFS
+	}
+
+	if fs, _, _, err := d.PartitionMetadata(); err != nil {
+		return nil, fmt.Errorf("%w", err)
+	} else if fs != sif.FsSquash {
+		return nil, ErrNotSquashFS
+	}
+
+	r, ok := d.GetReader().(io.ReaderAt)
+	if !ok {
+		return nil, errNotReaderAt
+	}
+
+	return New(r)
+}
+
+// decompress decompresses b, which is expected to decompress to at most limit bytes.
+func (f *FS) decompress(b []byte, limit int64) ([]byte, error) {
+	zr, err := zlib.NewReader(bytes.NewReader(b))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errCorrupt, err)
+	}
+	defer zr.Close()
+
+	out, err := io.ReadAll(io.LimitReader(zr, limit+1))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errCorrupt, err)
+	}
+
+	if int64(len(out)) > limit {
+		return nil, fmt.Errorf("%w: decompressed block too large", errCorrupt)
+	}
+
+	return out, nil
+}
+
+// readBlock reads the data block at offset off, with on-disk size information s. The
+// decompressed block is returned.
+func (f *FS) readBlock(off int64, s uint32) ([]byte, error) {
+	b := make([]byte, s&^dataUncompressed)
+	if _, err := f.r.ReadAt(b, off); err != nil {
+		return nil, fmt.Errorf("reading data block: %w", err)
+	}
+
+	if s&dataUncompressed != 0 {
+		return b, nil
+	}
+
+	return f.decompress(b, int64(f.sb.BlockSize))
+}
+
+// fragment reads the fragment block with the specified index, returning the decompressed block.
+func (f *FS) fragment(index uint32) ([]byte, error) {
+	if index >= f.sb.FragmentEntryCount {
+		return nil, fmt.Errorf("%w: fragment index %v out of range", errCorrupt, index)
+	}
+
+	// The fragment table is referenced by an array of metadata block locations.
+	var loc [8]byte
+	off := int64(f.sb.FragmentTableStart) + int64(index/fragmentEntriesPerBlock)*8
+	if _, err := f.r.ReadAt(loc[:], off); err != nil {
+		return nil, fmt.Errorf("reading fragment table: %w", err)
+	}
+
+	mr := f.newMetadataReader(int64(binary.LittleEndian.Uint64(loc[:])), int(index%fragmentEntriesPerBlock)*16)
+
+	var e struct {
+		Start  uint64
+		Size   uint32
+		Unused uint32
+	}
+	if err := binary.Read(mr, binary.LittleEndian, &e); err != nil {
+		return nil, fmt.Errorf("reading fragment table: %w", err)
+	}
+
+	return f.readBlock(int64(e.Start), e.Size)
+}
@@ -0,0 +1,621 @@
+// Copyright (c) 2023, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package squashfs
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"testing/fstest"
+
+	"github.com/sylabs/sif/v2/pkg/sif"
+)
+
+var corpus = filepath.Join("..", "..", "test", "images")
+
+// testEntries returns a set of entries that exercise a range of SquashFS features.
+func testEntries() []testEntry {
+	return []testEntry{
+		{path: "bin", mode: fs.ModeDir | 0o755},
+		{path: "bin/sh", mode: 0o755, data: []byte("#!/bin/sh\n")},
+		{path: "bin/su", mode: fs.ModeSetuid | 0o755, data: []byte{0x7f, 'E', 'L', 'F'}},
+		{path: "dev", mode: fs.ModeDir | 0o755},
+		{path: "dev/null", mode: fs.ModeDevice | fs.ModeCharDevice | 0o666},
+		{path: "dev/sda", mode: fs.ModeDevice | 0o660},
+		{path: "empty", mode: 0o644},
+		{path: "etc", mode: fs.ModeDir | 0o755},
+		{path: "etc/os-release", mode: 0o644, data: []byte("NAME=\"Test\"\nID=test\n")},
+		{path: "etc/passwd", mode: 0o644, data: []byte("root:x:0:0:root:/root:/bin/sh\n")},
+		{path: "large", mode: 0o644, data: bytes.Repeat([]byte("0123456789abcdef"), 1000)},
+		{path: "lib", mode: fs.ModeSymlink | 0o777, target: "usr/lib"},
+		{path: "run", mode: fs.ModeDir | 0o755},
+		{path: "run/fifo", mode: fs.ModeNamedPipe | 0o600},
+		{path: "run/sock", mode: fs.ModeSocket | 0o600},
+		{path: "sparse", mode: 0o644, data: append(make([]byte, 10000), 'x')},
+		{path: "tmp", mode: fs.ModeDir | fs.ModeSticky | 0o777},
+		{path: "usr", mode: fs.ModeDir | 0o755},
+		{path: "usr/lib", mode: fs.ModeDir | 0o755},
+		{path: "usr/lib/os-release", mode: fs.ModeSymlink | 0o777, target: "../../etc/os-release"},
+		{path: "usr/lib/escape", mode: fs.ModeSymlink | 0o777, target: "../../../../etc/passwd"},
+		{path: "usr/lib/abs", mode: fs.ModeSymlink | 0o777, target: "/etc/passwd"},
+	}
+}
+
+// testFiles lists the regular files in testEntries.
+var testFiles = []string{
+	"bin/sh",
+	"bin/su",
+	"empty",
+	"etc/os-release",
+	"etc/passwd",
+	"large",
+	"sparse",
+}
+
+// testLinks lists paths in testEntries that resolve to regular files via symbolic links.
+var testLinks = []string{
+	"lib/os-release",
+	"lib/escape",
+	"lib/abs",
+	"usr/lib/os-release",
+}
+
+// wantContents returns the expected contents of the regular file at name, following symlinks.
+func wantContents(t *testing.T, name string) []byte {
+	t.Helper()
+
+	switch name {
+	case "lib/os-release", "usr/lib/os-release":
+		name = "etc/os-release"
+	case "lib/escape", "lib/abs", "usr/lib/escape", "usr/lib/abs":
+		name = "etc/passwd"
+	}
+
+	for _, e := range testEntries() {
+		if e.path == name {
+			return e.data
+		}
+	}
+
+	t.Fatalf("no entry %v", name)
+	return nil
+}
+
+func TestNew(t *testing.T) {
+	b, err := os.ReadFile(filepath.Join("..", "..", "test", "input", "root.squashfs"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// corrupt returns a copy of b with the bytes at off replaced by c.
+	corrupt := func(off int, c ...byte) []byte {
+		b := bytes.Clone(b)
+		copy(b[off:], c)
+		return b
+	}
+
+	tests := []struct {
+		name    string
+		b       []byte
+		wantErr error
+	}{
+		{
+			name: "OK",
+			b:    b,
+		},
+		{
+			name:    "Empty",
+			b:       nil,
+			wantErr: io.EOF,
+		},
+		{
+			name:    "InvalidMagic",
+			b:       corrupt(0, 'h', 's', 'q', 't'),
+			wantErr: errInvalidMagic,
+		},
+		{
+			name:    "InvalidVersion",
+			b:       corrupt(28, 3),
+			wantErr: errInvalidVersion,
+		},
+		{
+			name:    "InvalidBlockSize",
+			b:       corrupt(12, 0, 0, 1, 0),
+			wantErr: errInvalidBlock,
+		},
+		{
+			name:    "UnsupportedCompression",
+			b:       corrupt(20, byte(compressionXZ)),
+			wantErr: &unsupportedCompressionError{compressionXZ},
+		},
+		{
+			name:    "CorruptInodeTable",
+			b:       corrupt(0x75, 0xff, 0xff),
+			wantErr: errCorrupt,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := New(bytes.NewReader(tt.b))
+			if got, want := err, tt.wantErr; !errors.Is(got, want) {
+				t.Fatalf("got error %v, want %v", got, want)
+			}
+
+			if err == nil {
+				b, err := fs.ReadFile(f, "hello.txt")
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				if got, want := string(b), "Hello from Sylabs!\n"; got != want {
+					t.Errorf("got contents %q, want %q", got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestFromDescriptor(t *testing.T) {
+	f, err := sif.LoadContainerFromPath(filepath.Join(corpus, "two-groups.sif"), sif.OptLoadWithFlag(os.O_RDONLY))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := f.UnloadContainer(); err != nil {
+			t.Error(err)
+		}
+	})
+
+	tests := []struct {
+		name    string
+		id      uint32
+		wantErr error
+	}{
+		{
+			name:    "Raw",
+			id:      1,
+			wantErr: ErrNotSquashFS,
+		},
+		{
+			name: "SquashFS",
+			id:   2,
+		},
+		{
+			name:    "EXT3",
+			id:      3,
+			wantErr: ErrNotSquashFS,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, err := f.GetDescriptor(sif.WithID(tt.id))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			fsys, err := FromDescriptor(d)
+			if got, want := err, tt.wantErr; !errors.Is(got, want) {
+				t.Fatalf("got error %v, want %v", got, want)
+			}
+
+			if err == nil {
+				if err := fstest.TestFS(fsys, "hello.txt"); err != nil {
+					t.Error(err)
+				}
+			}
+		})
+	}
+}
+
+// TestFS_Mksquashfs reads an image generated by mksquashfs, rather than by buildTestImage, so that
+// the reader is checked against an independent implementation of the format.
+func TestFS_Mksquashfs(t *testing.T) {
+	b, err := os.ReadFile(filepath.Join("..", "..", "test", "input", "root.squashfs"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := New(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := fstest.TestFS(f, "hello.txt"); err != nil {
+		t.Error(err)
+	}
+
+	des, err := f.ReadDir(".")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := len(des), 1; got != want {
+		t.Fatalf("got %v entries, want %v", got, want)
+	}
+
+	if got, want := des[0].Name(), "hello.txt"; got != want {
+		t.Errorf("got name %q, want %q", got, want)
+	}
+
+	fi, err := f.Stat(".")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := fi.Mode(), fs.ModeDir|0o775; got != want {
+		t.Errorf("got mode %v, want %v", got, want)
+	}
+
+	fi, err = f.Stat("hello.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := fi.Mode(), fs.FileMode(0o664); got != want {
+		t.Errorf("got mode %v, want %v", got, want)
+	}
+
+	if got, want := fi.Size(), int64(19); got != want {
+		t.Errorf("got size %v, want %v", got, want)
+	}
+
+	fl, err := f.Open("hello.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fl.Close()
+
+	ra, ok := fl.(io.ReaderAt)
+	if !ok {
+		t.Fatal("file does not implement io.ReaderAt")
+	}
+
+	// The file is stored entirely within a fragment.
+	tests := []struct {
+		name    string
+		off     int64
+		n       int
+		want    string
+		wantErr error
+	}{
+		{name: "Start", off: 0, n: 5, want: "Hello"},
+		{name: "Middle", off: 6, n: 4, want: "from"},
+		{name: "PastEnd", off: 15, n: 10, want: "bs!\n", wantErr: io.EOF},
+		{name: "EOF", off: 19, n: 1, wantErr: io.EOF},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := make([]byte, tt.n)
+
+			n, err := ra.ReadAt(b, tt.off)
+			if got, want := err, tt.wantErr; !errors.Is(got, want) {
+				t.Fatalf("got error %v, want %v", got, want)
+			}
+
+			if got, want := string(b[:n]), tt.want; got != want {
+				t.Errorf("got %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestFS(t *testing.T) {
+	tests := []struct {
+		name string
+		opts buildOpts
+	}{
+		{
+			name: "Uncompressed",
+		},
+		{
+			name: "Compressed",
+			opts: buildOpts{compress: true},
+		},
+		{
+			name: "Fragments",
+			opts: buildOpts{fragments: true},
+		},
+		{
+			name: "CompressedFragments",
+			opts: buildOpts{compress: true, fragments: true},
+		},
+		{
+			name: "Sparse",
+			opts: buildOpts{sparse: true},
+		},
+		{
+			name: "Extended",
+			opts: buildOpts{compress: true, fragments: true, extended: true},
+		},
+		{
+			name: "SmallDirectoryHeaders",
+			opts: buildOpts{dirEntries: 1},
+		},
+		{
+			name: "LargeBlocks",
+			opts: buildOpts{blockLog: 17, compress: true, fragments: true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := New(bytes.NewReader(buildTestImage(t, tt.opts, testEntries()...)))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if err := fstest.TestFS(f, testFiles...); err != nil {
+				t.Error(err)
+			}
+
+			for _, name := range append(testFiles, testLinks...) {
+				b, err := fs.ReadFile(f, name)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				if got, want := b, wantContents(t, name); !bytes.Equal(got, want) {
+					t.Errorf("%v: got contents %q, want %q", name, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestFS_ManyEntries(t *testing.T) {
+	// Enough entries to span multiple metadata blocks, and multiple directory headers.
+	entries := []testEntry{{path: "dir", mode: fs.ModeDir | 0o755}}
+	for i := 0; i < 1000; i++ {
+		name := "dir/" + strings.Repeat("x", i%50) + string(rune('a'+i%26)) + string(rune('0'+i/26%10)) +
+			string(rune('0'+i/260))
+		entries = append(entries, testEntry{path: name, mode: 0o644, data: []byte(name)})
+	}
+
+	for _, compress := range []bool{false, true} {
+		f, err := New(bytes.NewReader(buildTestImage(t, buildOpts{compress: compress, fragments: true}, entries...)))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		des, err := f.ReadDir("dir")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if got, want := len(des), len(entries)-1; got != want {
+			t.Fatalf("got %v entries, want %v", got, want)
+		}
+
+		for _, de := range des {
+			name := "dir/" + de.Name()
+
+			b, err := fs.ReadFile(f, name)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got, want := string(b), name; got != want {
+				t.Errorf("got contents %q, want %q", got, want)
+			}
+		}
+	}
+}
+
+func TestFS_Stat(t *testing.T) {
+	entries := append(testEntries(), testEntry{path: "loop", mode: fs.ModeSymlink | 0o777, target: "loop"})
+
+	f, err := New(bytes.NewReader(buildTestImage(t, buildOpts{mtime: 1234}, entries...)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		path     string
+		lstat    bool
+		wantMode fs.FileMode
+		wantSize int64
+		wantErr  error
+	}{
+		{name: "Root", path: ".", wantMode: fs.ModeDir | 0o755},
+		{name: "Dir", path: "etc", wantMode: fs.ModeDir | 0o755},
+		{name: "File", path: "etc/passwd", wantMode: 0o644, wantSize: 30},
+		{name: "Setuid", path: "bin/su", wantMode: fs.ModeSetuid | 0o755, wantSize: 4},
+		{name: "Sticky", path: "tmp", wantMode: fs.ModeDir | fs.ModeSticky | 0o777},
+		{name: "CharDevice", path: "dev/null", wantMode: fs.ModeDevice | fs.ModeCharDevice | 0o666},
+		{name: "BlockDevice", path: "dev/sda", wantMode: fs.ModeDevice | 0o660},
+		{name: "Fifo", path: "run/fifo", wantMode: fs.ModeNamedPipe | 0o600},
+		{name: "Socket", path: "run/sock", wantMode: fs.ModeSocket | 0o600},
+		{name: "Symlink", path: "lib", wantMode: fs.ModeDir | 0o755},
+		{name: "SymlinkLstat", path: "lib", lstat: true, wantMode: fs.ModeSymlink | 0o777, wantSize: 7},
+		{name: "SymlinkDir", path: "lib/abs", lstat: true, wantMode: fs.ModeSymlink | 0o777, wantSize: 11},
+		{name: "NotExist", path: "etc/shadow", wantErr: fs.ErrNotExist},
+		{name: "NotDir", path: "etc/passwd/x", wantErr: errNotDir},
+		{name: "Invalid", path: "/etc", wantErr: fs.ErrInvalid},
+		{name: "Loop", path: "loop", wantErr: errTooManyLinks},
+		{name: "LoopLstat", path: "loop", lstat: true, wantMode: fs.ModeSymlink | 0o777, wantSize: 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stat := f.Stat
+			if tt.lstat {
+				stat = f.Lstat
+			}
+
+			fi, err := stat(tt.path)
+			if got, want := err, tt.wantErr; !errors.Is(got, want) {
+				t.Fatalf("got error %v, want %v", got, want)
+			}
+
+			if err == nil {
+				if got, want := fi.Mode(), tt.wantMode; got != want {
+					t.Errorf("got mode %v, want %v", got, want)
+				}
+
+				if !tt.wantMode.IsDir() {
+					if got, want := fi.Size(), tt.wantSize; got != want {
+						t.Errorf("got size %v, want %v", got, want)
+					}
+				}
+
+				if got, want := fi.ModTime().Unix(), int64(1234); got != want {
+					t.Errorf("got mod time %v, want %v", got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestFS_ReadLink(t *testing.T) {
+	f, err := New(bytes.NewReader(buildTestImage(t, buildOpts{}, testEntries()...)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		path       string
+		wantTarget string
+		wantErr    error
+	}{
+		{name: "Relative", path: "lib", wantTarget: "usr/lib"},
+		{name: "ThroughLink", path: "lib/os-release", wantTarget: "../../etc/os-release"},
+		{name: "Absolute", path: "usr/lib/abs", wantTarget: "/etc/passwd"},
+		{name: "NotSymlink", path: "etc/passwd", wantErr: errNotSymlink},
+		{name: "NotExist", path: "etc/shadow", wantErr: fs.ErrNotExist},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target, err := f.ReadLink(tt.path)
+			if got, want := err, tt.wantErr; !errors.Is(got, want) {
+				t.Fatalf("got error %v, want %v", got, want)
+			}
+
+			if got, want := target, tt.wantTarget; got != want {
+				t.Errorf("got target %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestFile_ReadAt(t *testing.T) {
+	f, err := New(bytes.NewReader(buildTestImage(t, buildOpts{compress: true, fragments: true}, testEntries()...)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fl, err := f.Open("large")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fl.Close()
+
+	ra, ok := fl.(io.ReaderAt)
+	if !ok {
+		t.Fatal("file does not implement io.ReaderAt")
+	}
+
+	want := wantContents(t, "large")
+
+	tests := []struct {
+		name    string
+		off     int64
+		n       int
+		wantN   int
+		wantErr error
+	}{
+		{name: "Start", off: 0, n: 16, wantN: 16},
+		{name: "SpanBlocks", off: 4090, n: 5000, wantN: 5000},
+		{name: "Tail", off: 15990, n: 10, wantN: 10},
+		{name: "PastEnd", off: 15990, n: 20, wantN: 10, wantErr: io.EOF},
+		{name: "EOF", off: 16000, n: 1, wantErr: io.EOF},
+		{name: "NegativeOffset", off: -1, n: 1, wantErr: errNegativeOffset},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := make([]byte, tt.n)
+
+			n, err := ra.ReadAt(b, tt.off)
+			if got, want := err, tt.wantErr; !errors.Is(got, want) {
+				t.Fatalf("got error %v, want %v", got, want)
+			}
+
+			if got, want := n, tt.wantN; got != want {
+				t.Fatalf("got %v bytes, want %v", got, want)
+			}
+
+			if n > 0 {
+				if got, want := b[:n], want[tt.off:tt.off+int64(n)]; !bytes.Equal(got, want) {
+					t.Errorf("got %q, want %q", got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestFile_ReadAtConcurrent(t *testing.T) {
+	f, err := New(bytes.NewReader(buildTestImage(t, buildOpts{compress: true, fragments: true}, testEntries()...)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fl, err := f.Open("large")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fl.Close()
+
+	ra, ok := fl.(io.ReaderAt)
+	if !ok {
+		t.Fatal("file does not implement io.ReaderAt")
+	}
+
+	want := wantContents(t, "large")
+
+	// Each goroutine reads from a different block, so that the cached block is replaced
+	// repeatedly.
+	offs := []int64{0, 4090, 8192, 15990}
+
+	var wg sync.WaitGroup
+
+	for _, off := range offs {
+		off := off
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			b := make([]byte, 10)
+
+			for i := 0; i < 100; i++ {
+				n, err := ra.ReadAt(b, off)
+				if err != nil {
+					t.Error(err)
+					return
+				}
+
+				if got, want := b[:n], want[off:off+int64(n)]; !bytes.Equal(got, want) {
+					t.Errorf("got %q, want %q", got, want)
+					return
+				}
+			}
+		}()
+	}
+
+	wg.Wait()
+}
@@ -15,11 +15,13 @@ import (
 	"os/exec"
 	"path/filepath"
 
+	"github.com/sylabs/sif/v2/pkg/integrity"
 	"github.com/sylabs/sif/v2/pkg/sif"
 )
 
-// mountSquashFS mounts the SquashFS filesystem from path at offset into mountPath.
-func mountSquashFS(ctx context.Context, offset int64, path, mountPath string, mo mountOpts) error {
+// mountSquashFS mounts the SquashFS filesystem from path at offset into mountPath. Any extraFiles are
+// inherited by the mount helper, as described by exec.Cmd.
+func mountSquashFS(ctx context.Context, offset int64, path, mountPath string, extraFiles []*os.File, mo mountOpts) error { //nolint:lll
 	args := []string{
 		"-o", fmt.Sprintf("ro,offset=%d", offset),
 		filepath.Clean(path),
@@ -29,6 +31,33 @@ func mountSquashFS(ctx context.Context, offset int64, path, mountPath string, mo
 	cmd := exec.CommandContext(ctx, mo.squashfusePath, args...)
 	cmd.Stdout = mo.stdout
 	cmd.Stderr = mo.stderr
+	cmd.ExtraFiles = extraFiles
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("failed to mount: %w", err)
+	}
+
+	return nil
+}
+
+// mountExt3 mounts the EXT3 filesystem from path at offset into mountPath. Any extraFiles are
+// inherited by the mount helper, as described by exec.Cmd.
+func mountExt3(ctx context.Context, offset int64, path, mountPath string, extraFiles []*os.File, mo mountOpts) error { //nolint:lll
+	mode := "ro"
+	if mo.writable {
+		mode = "rw"
+	}
+
+	args := []string{
+		"-o", fmt.Sprintf("%s,offset=%d", mode, offset),
+		filepath.Clean(path),
+		filepath.Clean(mountPath),
+	}
+	//nolint:gosec // note (gosec exclusion) - we require callers to be able to specify fuse2fs not on PATH
+	cmd := exec.CommandContext(ctx, mo.fuse2fsPath, args...)
+	cmd.Stdout = mo.stdout
+	cmd.Stderr = mo.stderr
+	cmd.ExtraFiles = extraFiles
 
 	if err := cmd.Run(); err != nil {
 		return fmt.Errorf("failed to mount: %w", err)
@@ -42,6 +71,11 @@ type mountOpts struct {
 	stdout         io.Writer
 	stderr         io.Writer
 	squashfusePath string
+	fuse2fsPath    string
+	writable       bool
+	selectors      []sif.DescriptorSelectorFunc
+	verify         bool
+	verifierOpts   []integrity.VerifierOpt
 }
 
 // MountOpt are used to specify mount options.
@@ -77,19 +111,109 @@ func OptMountSquashfusePath(path string) MountOpt {
 	}
 }
 
-var errUnsupportedFSType = errors.New("unrecognized filesystem type")
+var errFuse2fsPathInvalid = errors.New("fuse2fs path must be relative or absolute")
 
-// Mount mounts the primary system partition of the SIF file at path into mountPath.
+// OptMountFuse2fsPath sets an explicit path to the fuse2fs binary. The path must be an absolute
+// or relative path.
+func OptMountFuse2fsPath(path string) MountOpt {
+	return func(mo *mountOpts) error {
+		if filepath.Base(path) == path {
+			return errFuse2fsPathInvalid
+		}
+		mo.fuse2fsPath = path
+		return nil
+	}
+}
+
+// OptMountWritable specifies whether the partition should be mounted read-write. This is
+// typically used with overlay partitions, and is only supported for EXT3 partitions.
+func OptMountWritable(b bool) MountOpt {
+	return func(mo *mountOpts) error {
+		mo.writable = b
+		return nil
+	}
+}
+
+// OptMountPartitionID specifies that the partition with the specified ID should be mounted,
+// rather than the primary system partition.
+func OptMountPartitionID(id uint32) MountOpt {
+	return OptMountSelector(sif.WithID(id))
+}
+
+// OptMountSelector specifies that the partition selected by fns should be mounted, rather than
+// the primary system partition. Exactly one data object must be selected by fns.
+func OptMountSelector(fns ...sif.DescriptorSelectorFunc) MountOpt {
+	return func(mo *mountOpts) error {
+		mo.selectors = fns
+		return nil
+	}
+}
+
+// OptMountVerify specifies that the object group containing the partition should be verified
+// before it is mounted, using a Verifier configured with opts. If the partition is not part of an
+// object group, the partition itself is verified. Key material must be supplied via opts, for
+// example using integrity.OptVerifyWithVerifier and/or integrity.OptVerifyWithKeyRing.
+//
+// When verification is enabled, the image is opened once, and the mount helper is passed the
+// verified file via an inherited file descriptor (/dev/fd/3) rather than the image path, so
+// replacing the file at the image path after verification has no effect on the mount. Verification
+// does not protect against in-place modification of the image by another process once Mount
+// returns.
+func OptMountVerify(opts ...integrity.VerifierOpt) MountOpt {
+	return func(mo *mountOpts) error {
+		mo.verify = true
+		mo.verifierOpts = opts
+		return nil
+	}
+}
+
+// verifyPartition verifies the object group containing the partition described by d in f, or
+// the partition itself if it is not part of an object group.
+func verifyPartition(ctx context.Context, f *sif.FileImage, d sif.Descriptor, mo mountOpts) error {
+	opts := []integrity.VerifierOpt{integrity.OptVerifyWithContext(ctx)}
+
+	if id := d.GroupID(); id != 0 {
+		opts = append(opts, integrity.OptVerifyGroup(id))
+	} else {
+		opts = append(opts, integrity.OptVerifyObject(d.ID()))
+	}
+
+	v, err := integrity.NewVerifier(f, append(opts, mo.verifierOpts...)...)
+	if err != nil {
+		return err
+	}
+
+	return v.Verify()
+}
+
+var (
+	errNotPartition      = errors.New("data object is not a partition")
+	errUnsupportedFSType = errors.New("unrecognized filesystem type")
+	errReadOnlyFSType    = errors.New("filesystem type does not support writable mounts")
+)
+
+// Mount mounts the primary system partition of the SIF file at path into mountPath. SquashFS
+// and EXT3 partitions are supported. To mount a different partition, consider using
+// OptMountPartitionID or OptMountSelector.
 //
 // Mount may start one or more underlying processes. By default, stdout and stderr of these
 // processes is discarded. To modify this behavior, consider using OptMountStdout and/or
 // OptMountStderr.
 //
-// By default, Mount searches for a squashfuse binary in the directories named by the PATH
-// environment variable. To override this behavior, consider using OptMountSquashfusePath().
+// By default, Mount searches for squashfuse and fuse2fs binaries in the directories named by the
+// PATH environment variable. To override this behavior, consider using OptMountSquashfusePath()
+// and/or OptMountFuse2fsPath().
+//
+// By default, the partition is not verified before it is mounted. To verify the partition,
+// consider using OptMountVerify.
+//
+// By default, the partition is mounted read-only. To mount an EXT3 partition read-write, consider
+// using OptMountWritable.
 func Mount(ctx context.Context, path, mountPath string, opts ...MountOpt) error {
 	mo := mountOpts{
 		squashfusePath: "squashfuse",
+		fuse2fsPath:    "fuse2fs",
+		selectors:      []sif.DescriptorSelectorFunc{sif.WithPartitionType(sif.PartPrimSys)},
 	}
 
 	for _, opt := range opts {
@@ -98,17 +222,46 @@ func Mount(ctx context.Context, path, mountPath string, opts ...MountOpt) error
 		}
 	}
 
+	src := path
+
+	var extraFiles []*os.File
+
+	if mo.verify {
+		fp, err := os.Open(path)
+		if err != nil {
+			return fmt.Errorf("failed to open image: %w", err)
+		}
+		defer fp.Close()
+
+		// Load, verify and mount the open file rather than path, so that the file cannot be
+		// replaced between verification and mounting. The mount helper inherits fp as file
+		// descriptor 3.
+		path = fmt.Sprintf("/dev/fd/%d", fp.Fd())
+		src = "/dev/fd/3"
+		extraFiles = []*os.File{fp}
+	}
+
 	f, err := sif.LoadContainerFromPath(path, sif.OptLoadWithFlag(os.O_RDONLY))
 	if err != nil {
 		return fmt.Errorf("failed to load image: %w", err)
 	}
 	defer func() { _ = f.UnloadContainer() }()
 
-	d, err := f.GetDescriptor(sif.WithPartitionType(sif.PartPrimSys))
+	d, err := f.GetDescriptor(mo.selectors...)
 	if err != nil {
 		return fmt.Errorf("failed to get partition descriptor: %w", err)
 	}
 
+	if dt := d.DataType(); dt != sif.DataPartition {
+		return fmt.Errorf("%w: object %v has data type %v", errNotPartition, d.ID(), dt)
+	}
+
+	if mo.verify {
+		if err := verifyPartition(ctx, f, d, mo); err != nil {
+			return fmt.Errorf("failed to verify partition: %w", err)
+		}
+	}
+
 	fs, _, _, err := d.PartitionMetadata()
 	if err != nil {
 		return fmt.Errorf("failed to get partition metadata: %w", err)
@@ -116,7 +269,12 @@ func Mount(ctx context.Context, path, mountPath string, opts ...MountOpt) error
 
 	switch fs {
 	case sif.FsSquash:
-		return mountSquashFS(ctx, d.Offset(), path, mountPath, mo)
+		if mo.writable {
+			return errReadOnlyFSType
+		}
+		return mountSquashFS(ctx, d.Offset(), src, mountPath, extraFiles, mo)
+	case sif.FsExt3:
+		return mountExt3(ctx, d.Offset(), src, mountPath, extraFiles, mo)
 	default:
 		return errUnsupportedFSType
 	}
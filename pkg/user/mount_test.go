@@ -0,0 +1,168 @@
+// Copyright (c) 2023, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package user
+
+import (
+	"context"
+	"crypto"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/sigstore/sigstore/pkg/cryptoutils"
+	"github.com/sigstore/sigstore/pkg/signature"
+	"github.com/sylabs/sif/v2/pkg/integrity"
+	"github.com/sylabs/sif/v2/pkg/sif"
+)
+
+// loadVerifier returns a verifier using the PEM-encoded public key with the specified name.
+func loadVerifier(t *testing.T, name string) signature.Verifier { //nolint:ireturn
+	t.Helper()
+
+	b, err := os.ReadFile(filepath.Join("..", "..", "test", "keys", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pub, err := cryptoutils.UnmarshalPEMToPublicKey(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sv, err := signature.LoadVerifier(pub, crypto.SHA256)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return sv
+}
+
+func Test_Mount(t *testing.T) {
+	// Substitute a binary that always succeeds for squashfuse, so that the tests do not depend on
+	// FUSE.
+	truePath, err := exec.LookPath("true")
+	if err != nil {
+		t.Skip("true not found, skipping mount tests")
+	}
+
+	// Substitute a script for squashfuse that succeeds only if the image is passed as an
+	// inherited file descriptor, and matches the verified image.
+	signed := filepath.Join(corpus, "one-group-signed-dsse.sif")
+	fdSquashfusePath := filepath.Join(t.TempDir(), "squashfuse")
+	script := fmt.Sprintf("#!/bin/sh\n[ \"$3\" = /dev/fd/3 ] && cmp -s \"$3\" %q\n", signed)
+	if err := os.WriteFile(fdSquashfusePath, []byte(script), 0o700); err != nil { //nolint:gosec // Script must be executable.
+		t.Fatal(err)
+	}
+
+	ed25519 := loadVerifier(t, "ed25519-public.pem")
+	ecdsa := loadVerifier(t, "ecdsa-public.pem")
+
+	tests := []struct {
+		name    string
+		path    string
+		opts    []MountOpt
+		wantErr error
+	}{
+		{
+			name:    "NotExist",
+			path:    "not-exist.sif",
+			wantErr: os.ErrNotExist,
+		},
+		{
+			name:    "SquashfuseBare",
+			path:    filepath.Join(corpus, "one-group.sif"),
+			opts:    []MountOpt{OptMountSquashfusePath("squashfuse")},
+			wantErr: errSquashfusePathInvalid,
+		},
+		{
+			name:    "Fuse2fsBare",
+			path:    filepath.Join(corpus, "one-group.sif"),
+			opts:    []MountOpt{OptMountFuse2fsPath("fuse2fs")},
+			wantErr: errFuse2fsPathInvalid,
+		},
+		{
+			name:    "WritableSquashFS",
+			path:    filepath.Join(corpus, "one-group.sif"),
+			opts:    []MountOpt{OptMountWritable(true)},
+			wantErr: errReadOnlyFSType,
+		},
+		{
+			name:    "PartitionIDNotFound",
+			path:    filepath.Join(corpus, "one-group.sif"),
+			opts:    []MountOpt{OptMountPartitionID(10)},
+			wantErr: sif.ErrObjectNotFound,
+		},
+		{
+			name:    "PartitionIDNotPartition",
+			path:    filepath.Join(corpus, "one-object-sbom.sif"),
+			opts:    []MountOpt{OptMountPartitionID(1)},
+			wantErr: errNotPartition,
+		},
+		{
+			name:    "PartitionIDUnsupported",
+			path:    filepath.Join(corpus, "two-groups.sif"),
+			opts:    []MountOpt{OptMountPartitionID(1)},
+			wantErr: errUnsupportedFSType,
+		},
+		{
+			name:    "SelectorMultiple",
+			path:    filepath.Join(corpus, "two-groups.sif"),
+			opts:    []MountOpt{OptMountSelector(sif.WithDataType(sif.DataPartition))},
+			wantErr: sif.ErrMultipleObjectsFound,
+		},
+		{
+			name:    "SelectorNotPartition",
+			path:    filepath.Join(corpus, "one-group-signed-pgp.sif"),
+			opts:    []MountOpt{OptMountSelector(sif.WithDataType(sif.DataSignature))},
+			wantErr: errNotPartition,
+		},
+		{
+			name: "VerifyNotSigned",
+			path: filepath.Join(corpus, "one-group.sif"),
+			opts: []MountOpt{
+				OptMountSquashfusePath(truePath),
+				OptMountVerify(integrity.OptVerifyWithVerifier(ed25519)),
+			},
+			wantErr: &integrity.SignatureNotFoundError{},
+		},
+		{
+			name: "VerifyWrongKey",
+			path: filepath.Join(corpus, "one-group-signed-dsse.sif"),
+			opts: []MountOpt{
+				OptMountSquashfusePath(truePath),
+				OptMountVerify(integrity.OptVerifyWithVerifier(ecdsa)),
+			},
+			wantErr: &integrity.SignatureNotValidError{},
+		},
+		{
+			name: "VerifyOK",
+			path: filepath.Join(corpus, "one-group-signed-dsse.sif"),
+			opts: []MountOpt{
+				OptMountSquashfusePath(truePath),
+				OptMountVerify(integrity.OptVerifyWithVerifier(ed25519)),
+			},
+		},
+		{
+			name: "VerifyFileDescriptor",
+			path: signed,
+			opts: []MountOpt{
+				OptMountSquashfusePath(fdSquashfusePath),
+				OptMountVerify(integrity.OptVerifyWithVerifier(ed25519)),
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Mount(context.Background(), tt.path, t.TempDir(), tt.opts...)
+			if got, want := err, tt.wantErr; !errors.Is(got, want) {
+				t.Fatalf("got error %v, want %v", got, want)
+			}
+		})
+	}
+}